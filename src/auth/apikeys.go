@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"CarServiceCenter/src/db"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// APIKeyHeader - the request header API keys are presented in
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyPrefix - every key starts with this, so leaked keys are easy to search for
+const apiKeyPrefix = "csc_"
+
+// lastUsedResolution - how stale a key's recorded last use may get before a request updates it, so busy keys
+// don't cost a write on every request
+const lastUsedResolution = time.Minute
+
+// NewAPIKey - returns a new random key, the prefix it is listed under and the hash it is stored as
+func NewAPIKey() (key, prefix, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("generating api key: %v", err)
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:len(apiKeyPrefix)+6], HashAPIKey(key), nil
+}
+
+// HashAPIKey - the SHA-256 of key. Keys are long and random, so unlike passwords they don't need a slow hash,
+// and hashing them the same way every time lets a key be looked up by its hash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeys - authenticates requests made with an API key
+type APIKeys struct {
+	DB db.APIKeyStore
+	// Now - the clock last uses are recorded with
+	Now func() time.Time
+}
+
+// Authenticate - chi middleware that accepts requests with an API key in their X-API-Key header, rejecting
+// unknown and revoked keys with 401, and passes the key's claims on through the request context. Requests
+// without the header are passed on untouched, to be authenticated by Tokens.Authenticate.
+func (a *APIKeys) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := r.Header.Get(APIKeyHeader)
+		if presented == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, err := a.DB.GetAPIKeyByHash(r.Context(), HashAPIKey(presented))
+		if errors.Is(err, db.ErrNotFound) || (err == nil && key.RevokedAt != nil) {
+			Reject(w, http.StatusUnauthorized, "invalid api key")
+			return
+		} else if err != nil {
+			log.Println("error looking up api key", err)
+			Reject(w, http.StatusServiceUnavailable, "service temporarily unavailable")
+			return
+		}
+
+		now := a.Now()
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+			if err := a.DB.TouchAPIKey(r.Context(), key.ID.Hex(), now.UTC()); err != nil {
+				log.Println("error recording api key use", err)
+			}
+		}
+		claims := &Claims{Subject: key.ID.Hex(), Username: key.Name, Use: UseAPIKey, Scopes: key.Scopes}
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+// Scope - chi middleware that rejects requests made with an API key with 403 unless the key has the scope
+// for resource: "<resource>:read" for GET and HEAD requests and "<resource>:write" for every other method.
+// Signed in users are passed on, since their roles are checked by Require.
+func Scope(resource string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFrom(r.Context())
+			if ok && claims.Use == UseAPIKey {
+				scope := resource + ":write"
+				if r.Method == http.MethodGet || r.Method == http.MethodHead {
+					scope = resource + ":read"
+				}
+				if !contains(scope, claims.Scopes) {
+					Reject(w, http.StatusForbidden, fmt.Sprintf("api key %s does not have the %s scope", claims.Username, scope))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyLastUsed(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	key, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := store.CreateAPIKey(ctx, models.APIKey{Name: "kiosk", Prefix: prefix, KeyHash: hash, Scopes: []string{"appointments:read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+	now := start
+	apiKeys := &APIKeys{DB: store, Now: func() time.Time { return now }}
+	handler := apiKeys.Authenticate(Scope("appointments")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	lastUsed := func(at time.Time) time.Time {
+		now = at
+		req := httptest.NewRequest("GET", "/appointments", nil)
+		req.Header.Set(APIKeyHeader, key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+		}
+		stored, err := store.GetAPIKey(ctx, created.ID.Hex())
+		if err != nil || stored.LastUsedAt == nil {
+			t.Fatalf("got %+v, %v want the last use recorded", stored, err)
+		}
+		return *stored.LastUsedAt
+	}
+
+	if used := lastUsed(start); !used.Equal(start) {
+		t.Errorf("first use got %v want %v", used, start)
+	}
+	if used := lastUsed(start.Add(30 * time.Second)); !used.Equal(start) {
+		t.Errorf("use within a minute got %v want %v kept", used, start)
+	}
+	if used := lastUsed(start.Add(time.Minute)); !used.Equal(start.Add(time.Minute)) {
+		t.Errorf("use a minute later got %v want %v", used, start.Add(time.Minute))
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// contextKey - type of the keys auth stores in request contexts
+type contextKey string
+
+// claimsKey - the context key of the authenticated caller's claims
+const claimsKey contextKey = "claims"
+
+// errorResponse - JSON body of a rejected request, matching the controller package's error responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// WithClaims - returns a copy of ctx carrying claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// ClaimsFrom - returns the claims of the authenticated caller, if the request was authenticated
+func ClaimsFrom(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	return claims, ok
+}
+
+// Authenticate - chi middleware that rejects requests without a valid access token in their Authorization
+// header with 401, and passes the token's claims on to the next handler through the request context.
+// Requests already authenticated by APIKeys.Authenticate are passed on as they are.
+func (t *Tokens) Authenticate(next http.Handler) http.Handler {
+	return t.authenticate(UseAccess, next)
+}
+
+// AuthenticateCustomer - chi middleware like Authenticate, for the customer portal: it accepts only portal tokens,
+// whose subject is the customer's id
+func (t *Tokens) AuthenticateCustomer(next http.Handler) http.Handler {
+	return t.authenticate(UsePortal, next)
+}
+
+// authenticate - the middleware behind Authenticate and AuthenticateCustomer, accepting bearer tokens meant for use
+func (t *Tokens) authenticate(use string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ClaimsFrom(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := bearerToken(r)
+		if token == "" {
+			Reject(w, http.StatusUnauthorized, "request must have an Authorization: Bearer token")
+			return
+		}
+		claims, err := t.Verify(token, use)
+		if err != nil {
+			Reject(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+// bearerToken - the token in r's Authorization header, or "" if it has none
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Reject - writes a JSON error response with status; 401 responses also tell the client to authenticate with a bearer token
+func Reject(w http.ResponseWriter, status int, message string) {
+	response, err := json.Marshal(errorResponse{Error: message})
+	if err != nil {
+		log.Println("error marshaling error response", err)
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="CarServiceCenter"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// technicianStatuses - the statuses technicians may move appointments to. Every transition into them starts from
+// another work state, so technicians can only move appointments along the work in the bay.
+var technicianStatuses = map[string]bool{
+	models.StatusInProgress:   true,
+	models.StatusWaitingParts: true,
+	models.StatusCompleted:    true,
+}
+
+// Require - chi middleware that rejects requests with 403 unless the authenticated caller has one of roles.
+// It must run after Authenticate. Requests made with an API key are passed on, since their scopes are checked by Scope.
+func Require(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFrom(r.Context())
+			if !ok {
+				Reject(w, http.StatusUnauthorized, "request must have an Authorization: Bearer token")
+				return
+			}
+			if claims.Use != UseAPIKey && !contains(claims.Role, roles) {
+				Reject(w, http.StatusForbidden, fmt.Sprintf("role %q may not %s %s", claims.Role, r.Method, r.URL.Path))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Permitted - reports whether the caller of ctx has one of roles. API keys, whose scopes were checked by Scope, are
+// permitted; contexts without claims are not, so a handler mounted without Authenticate refuses everyone.
+func Permitted(ctx context.Context, roles ...string) bool {
+	claims, ok := ClaimsFrom(ctx)
+	return ok && (claims.Use == UseAPIKey || contains(claims.Role, roles))
+}
+
+// CanSetStatus - reports whether the caller of ctx may move an appointment to status; technicians may only move
+// appointments between work states. API keys are permitted and contexts without claims are not, as with Permitted.
+func CanSetStatus(ctx context.Context, status string) bool {
+	claims, ok := ClaimsFrom(ctx)
+	switch {
+	case !ok:
+		return false
+	case claims.Use == UseAPIKey:
+		return true
+	case claims.Role == models.RoleTechnician:
+		return technicianStatuses[status]
+	default:
+		return contains(claims.Role, []string{models.RoleAdmin, models.RoleAdvisor})
+	}
+}
+
+// contains - reports whether value is one of values
+func contains(value string, values []string) bool {
+	for _, allowed := range values {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"testing"
+)
+
+func TestRoleChecks(t *testing.T) {
+	technician := WithClaims(context.Background(), &Claims{Role: models.RoleTechnician, Use: UseAccess})
+	advisor := WithClaims(context.Background(), &Claims{Role: models.RoleAdvisor, Use: UseAccess})
+	apiKey := WithClaims(context.Background(), &Claims{Use: UseAPIKey})
+	customer := WithClaims(context.Background(), &Claims{Use: UsePortal})
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		permitted bool
+		cancel    bool
+		complete  bool
+	}{
+		{"no claims", context.Background(), false, false, false},
+		{"technician", technician, false, false, true},
+		{"advisor", advisor, true, true, true},
+		{"api key", apiKey, true, true, true},
+		{"portal customer", customer, false, false, false},
+	}
+	for _, test := range tests {
+		if permitted := Permitted(test.ctx, models.RoleAdmin, models.RoleAdvisor); permitted != test.permitted {
+			t.Errorf("%v: got permitted %v want %v", test.name, permitted, test.permitted)
+		}
+		if cancel := CanSetStatus(test.ctx, models.StatusCancelled); cancel != test.cancel {
+			t.Errorf("%v: got may cancel %v want %v", test.name, cancel, test.cancel)
+		}
+		if complete := CanSetStatus(test.ctx, models.StatusCompleted); complete != test.complete {
+			t.Errorf("%v: got may complete %v want %v", test.name, complete, test.complete)
+		}
+	}
+}
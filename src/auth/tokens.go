@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Token uses, so a refresh token can't be presented as an access token or the other way round, and a customer's
+// portal token is only accepted by the portal. UseAPIKey marks the claims of requests made with an API key,
+// which are never issued as tokens.
+const (
+	UseAccess  = "access"
+	UseRefresh = "refresh"
+	UsePortal  = "portal"
+	UseAPIKey  = "api_key"
+)
+
+// ErrInvalidToken - the token is malformed, wrongly signed, expired or of the wrong use
+var ErrInvalidToken = errors.New("invalid token")
+
+// jwtHeader - the only header Tokens issues or accepts
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims - what a session token says about the user it was issued to
+type Claims struct {
+	Subject  string `json:"sub"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Use      string `json:"use"`
+	// Version - the user's token version when the token was issued; refresh tokens from an older one are refused
+	Version int `json:"ver,omitempty"`
+	// Scopes - what an API key may access; signed in users have a Role instead
+	Scopes    []string `json:"scopes,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// TokenPair - the body returned when signing in or refreshing a session
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// ExpiresIn - seconds until the access token expires
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// Tokens - issues and verifies JWT session tokens signed with HMAC-SHA256
+type Tokens struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	// Now - the clock tokens are issued and checked against
+	Now func() time.Time
+}
+
+// NewTokens - returns Tokens signing with cfg's secret, or with a random one when it is empty
+func NewTokens(cfg config.AuthConfig) (*Tokens, error) {
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("generating token secret: %v", err)
+		}
+	}
+	return &Tokens{
+		secret:     secret,
+		accessTTL:  time.Duration(cfg.AccessTTL),
+		refreshTTL: time.Duration(cfg.RefreshTTL),
+		Now:        time.Now,
+	}, nil
+}
+
+// Issue - returns a new access and refresh token for user
+func (t *Tokens) Issue(user models.User) (*TokenPair, error) {
+	now := t.Now()
+	access, err := t.sign(Claims{Subject: user.ID.Hex(), Username: user.Username, Role: user.Role, Use: UseAccess, Version: user.TokenVersion,
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(t.accessTTL).Unix()})
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := t.sign(Claims{Subject: user.ID.Hex(), Username: user.Username, Role: user.Role, Use: UseRefresh, Version: user.TokenVersion,
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(t.refreshTTL).Unix()})
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, TokenType: "Bearer", ExpiresIn: int64(t.accessTTL / time.Second)}, nil
+}
+
+// IssuePortal - returns a token that lets customer use the portal for ttl, and when it expires
+func (t *Tokens) IssuePortal(customer models.Customer, ttl time.Duration) (string, time.Time, error) {
+	now := t.Now()
+	expires := now.Add(ttl)
+	token, err := t.sign(Claims{Subject: customer.ID.Hex(), Username: customer.Name, Use: UsePortal,
+		IssuedAt: now.Unix(), ExpiresAt: expires.Unix()})
+	return token, time.Unix(expires.Unix(), 0).UTC(), err
+}
+
+// Verify - returns the claims of token if it is validly signed, unexpired and meant for use; ErrInvalidToken otherwise
+func (t *Tokens) Verify(token, use string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, fmt.Errorf("malformed token: %w", ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, t.signature(parts[0]+"."+parts[1])) {
+		return nil, fmt.Errorf("bad token signature: %w", ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", ErrInvalidToken)
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed token: %w", ErrInvalidToken)
+	}
+	switch {
+	case claims.Use != use:
+		return nil, fmt.Errorf("token is not for %s use: %w", use, ErrInvalidToken)
+	case t.Now().Unix() >= claims.ExpiresAt:
+		return nil, fmt.Errorf("token expired: %w", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+// sign - encodes claims as a signed JWT
+func (t *Tokens) sign(claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(t.signature(unsigned)), nil
+}
+
+// signature - the HMAC-SHA256 of the header and payload
+func (t *Tokens) signature(unsigned string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestTokens(t *testing.T, now time.Time) *Tokens {
+	tokens, err := NewTokens(config.Default().Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens.Now = func() time.Time { return now }
+	return tokens
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	issued := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+	tokens := newTestTokens(t, issued)
+	user := models.User{ID: primitive.NewObjectID(), Username: "advisor"}
+
+	pair, err := tokens.Issue(user)
+	if err != nil || pair.ExpiresIn != 15*60 {
+		t.Fatalf("got %+v, %v want a pair expiring in 15 minutes", pair, err)
+	}
+	claims, err := tokens.Verify(pair.AccessToken, UseAccess)
+	if err != nil || claims.Subject != user.ID.Hex() || claims.Username != "advisor" {
+		t.Fatalf("got %+v, %v want the claims of %+v", claims, err, user)
+	}
+
+	other := newTestTokens(t, issued)
+	tampered := pair.AccessToken + "A"
+	tests := []struct {
+		name   string
+		tokens *Tokens
+		token  string
+		use    string
+	}{
+		{"refresh token used for access", tokens, pair.RefreshToken, UseAccess},
+		{"access token used for refresh", tokens, pair.AccessToken, UseRefresh},
+		{"tampered signature", tokens, tampered, UseAccess},
+		{"signed with another secret", other, pair.AccessToken, UseAccess},
+		{"malformed", tokens, "not.a-token", UseAccess},
+	}
+	for _, test := range tests {
+		if _, err := test.tokens.Verify(test.token, test.use); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%v: got %v want ErrInvalidToken", test.name, err)
+		}
+	}
+
+	tokens.Now = func() time.Time { return issued.Add(15 * time.Minute) }
+	if _, err := tokens.Verify(pair.AccessToken, UseAccess); err == nil || err.Error() != "token expired: invalid token" {
+		t.Errorf("got %v want the access token expired", err)
+	}
+	if _, err := tokens.Verify(pair.RefreshToken, UseRefresh); err != nil {
+		t.Errorf("got %v want the refresh token still valid", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tokens := newTestTokens(t, time.Now())
+	pair, err := tokens.Issue(models.User{ID: primitive.NewObjectID(), Username: "advisor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := tokens.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFrom(r.Context())
+		if !ok {
+			t.Error("authenticated request has no claims")
+		} else {
+			w.Write([]byte(claims.Username))
+		}
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		expected      string
+	}{
+		{"no header", "", http.StatusUnauthorized, `{"error":"request must have an Authorization: Bearer token"}`},
+		{"basic auth", "Basic YWR2aXNvcjpwYXNzd29yZA==", http.StatusUnauthorized, `{"error":"request must have an Authorization: Bearer token"}`},
+		{"refresh token", "Bearer " + pair.RefreshToken, http.StatusUnauthorized, `{"error":"token is not for access use: invalid token"}`},
+		{"access token", "bearer " + pair.AccessToken, http.StatusOK, "advisor"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/appointments", nil)
+		if test.authorization != "" {
+			req.Header.Set("Authorization", test.authorization)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", test.name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package billing
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/money"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Errors returned by Billing.Invoice
+var (
+	// ErrUnknownCoupon - the coupon code isn't configured
+	ErrUnknownCoupon = errors.New("unknown coupon")
+	// ErrInvalidDiscount - a discount asks for neither or both of a percent and an amount, or for more than 100%
+	ErrInvalidDiscount = errors.New("invalid discount")
+)
+
+// Discount - a discount given by hand when an invoice is issued, of either Percent or Amount off the
+// labor or part subtotal named by LineType, or off both when it is empty
+type Discount struct {
+	Description string       `json:"description"`
+	Amount      money.Amount `json:"amount"`
+	Percent     money.Rate   `json:"percent"`
+	LineType    string       `json:"line_type"`
+}
+
+// Billing - works out invoices from work orders using the shop's tax rates, fees and coupons
+type Billing struct {
+	shopName string
+	prefix   string
+	taxRates map[string]money.Rate
+	fees     []config.ShopFee
+	coupons  map[string]config.Coupon
+
+	// Now - current time, replaceable in tests; used as the issue date of new invoices
+	Now func() time.Time
+}
+
+// New - builds a Billing from cfg
+func New(cfg config.BillingConfig) *Billing {
+	billing := &Billing{
+		shopName: cfg.ShopName,
+		prefix:   cfg.InvoicePrefix,
+		taxRates: cfg.TaxRates,
+		fees:     cfg.ShopFees,
+		coupons:  make(map[string]config.Coupon),
+		Now:      time.Now,
+	}
+	for code, coupon := range cfg.Coupons {
+		billing.coupons[strings.ToUpper(code)] = coupon
+	}
+	return billing
+}
+
+// Number - the invoice number for the given position in the invoice sequence, such as "INV-000042"
+func (b *Billing) Number(sequence int64) string {
+	return fmt.Sprintf("%s%06d", b.prefix, sequence)
+}
+
+// subtotals - amounts still to be charged for labor and for parts
+type subtotals map[string]money.Amount
+
+// of - the subtotal of lineType, or of labor and parts together when lineType is empty
+func (s subtotals) of(lineType string) money.Amount {
+	if lineType == "" {
+		return s[models.LineLabor] + s[models.LinePart]
+	}
+	return s[lineType]
+}
+
+// take - removes amount from the subtotal of lineType, or from labor and parts in proportion to their subtotals when lineType is empty
+func (s subtotals) take(lineType string, amount money.Amount) {
+	if lineType != "" {
+		s[lineType] -= amount
+		return
+	}
+	base := s.of("")
+	if base == 0 {
+		return
+	}
+	labor := amount.MulRatio(int64(s[models.LineLabor]), int64(base))
+	s[models.LineLabor] -= labor
+	s[models.LinePart] -= amount - labor
+}
+
+// Invoice - prices order: the given coupon, if any, and discounts come off the labor and part subtotals in that order,
+// shop fees are added on the subtotals before discounts, and each line type is taxed on what is left of it
+func (b *Billing) Invoice(order models.WorkOrder, coupon string, discounts []Discount) (models.Invoice, error) {
+	invoice := models.Invoice{
+		WorkOrderID:   order.ID,
+		AppointmentID: order.AppointmentID,
+		CustomerID:    order.CustomerID,
+		VehicleID:     order.VehicleID,
+		IssuedAt:      b.Now().UTC(),
+		Lines:         order.Lines,
+		Discounts:     []models.Adjustment{},
+		Fees:          []models.Adjustment{},
+		Taxes:         []models.Tax{},
+		LaborTotal:    order.Total(models.LineLabor),
+		PartsTotal:    order.Total(models.LinePart),
+	}
+	if invoice.Lines == nil {
+		invoice.Lines = []models.WorkOrderLine{}
+	}
+	before := subtotals{models.LineLabor: invoice.LaborTotal, models.LinePart: invoice.PartsTotal}
+	remaining := subtotals{models.LineLabor: invoice.LaborTotal, models.LinePart: invoice.PartsTotal}
+
+	if coupon != "" {
+		configured, ok := b.coupons[strings.ToUpper(coupon)]
+		if !ok {
+			return models.Invoice{}, fmt.Errorf("%w %q", ErrUnknownCoupon, coupon)
+		}
+		description := configured.Description
+		if description == "" {
+			description = "Coupon " + strings.ToUpper(coupon)
+		}
+		discounts = append([]Discount{{Description: description, Amount: configured.Amount, Percent: configured.Percent, LineType: configured.LineType}}, discounts...)
+	}
+	for i, discount := range discounts {
+		if err := validateDiscount(discount); err != nil {
+			return models.Invoice{}, err
+		}
+		amount := discount.Amount
+		if discount.Percent > 0 {
+			amount = discount.Percent.Of(remaining.of(discount.LineType))
+		}
+		if amount > remaining.of(discount.LineType) {
+			amount = remaining.of(discount.LineType)
+		}
+		remaining.take(discount.LineType, amount)
+
+		adjustment := models.Adjustment{Description: discount.Description, LineType: discount.LineType, Amount: amount}
+		if coupon != "" && i == 0 {
+			adjustment.Code = strings.ToUpper(coupon)
+		}
+		invoice.Discounts = append(invoice.Discounts, adjustment)
+		invoice.DiscountTotal += amount
+	}
+
+	for _, fee := range b.fees {
+		amount := fee.Amount + fee.Percent.Of(before.of(fee.LineType))
+		if fee.Max > 0 && amount > fee.Max {
+			amount = fee.Max
+		}
+		if amount == 0 {
+			continue
+		}
+		invoice.Fees = append(invoice.Fees, models.Adjustment{Description: fee.Name, LineType: fee.LineType, Amount: amount})
+		invoice.FeeTotal += amount
+	}
+
+	taxable := map[string]money.Amount{
+		models.LineLabor: remaining[models.LineLabor],
+		models.LinePart:  remaining[models.LinePart],
+		models.LineFee:   invoice.FeeTotal,
+	}
+	for _, lineType := range []string{models.LineLabor, models.LinePart, models.LineFee} {
+		rate := b.taxRates[lineType]
+		if rate == 0 || taxable[lineType] == 0 {
+			continue
+		}
+		tax := models.Tax{LineType: lineType, Rate: rate, Taxable: taxable[lineType], Amount: rate.Of(taxable[lineType])}
+		invoice.Taxes = append(invoice.Taxes, tax)
+		invoice.TaxTotal += tax.Amount
+	}
+
+	invoice.Total = invoice.LaborTotal + invoice.PartsTotal - invoice.DiscountTotal + invoice.FeeTotal + invoice.TaxTotal
+	return invoice, nil
+}
+
+// validateDiscount - returns ErrInvalidDiscount describing the first problem with discount, or nil if it can be applied
+func validateDiscount(discount Discount) error {
+	switch {
+	case strings.TrimSpace(discount.Description) == "":
+		return fmt.Errorf("%w: discounts must have a description", ErrInvalidDiscount)
+	case (discount.Amount > 0) == (discount.Percent > 0) || discount.Amount < 0 || discount.Percent < 0:
+		return fmt.Errorf("%w: %v must have either a positive amount or a positive percent", ErrInvalidDiscount, discount.Description)
+	case discount.Percent > money.Percent(100):
+		return fmt.Errorf("%w: %v must not be over 100 percent", ErrInvalidDiscount, discount.Description)
+	case discount.LineType != "" && discount.LineType != models.LineLabor && discount.LineType != models.LinePart:
+		return fmt.Errorf("%w: %v line_type must be %v or %v", ErrInvalidDiscount, discount.Description, models.LineLabor, models.LinePart)
+	}
+	return nil
+}
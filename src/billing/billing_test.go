@@ -0,0 +1,107 @@
+package billing
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/money"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBilling - 8.875% tax on parts and fees, a 5% shop supplies fee on labor capped at 35.00, and a 10% coupon
+func testBilling() *Billing {
+	billing := New(config.BillingConfig{
+		ShopName:      "Main Street Garage",
+		InvoicePrefix: "INV-",
+		TaxRates:      map[string]money.Rate{models.LinePart: 88750, models.LineFee: 88750},
+		ShopFees:      []config.ShopFee{{Name: "Shop supplies", Percent: money.Percent(5), Max: 3500, LineType: models.LineLabor}},
+		Coupons:       map[string]config.Coupon{"save10": {Percent: money.Percent(10)}},
+	})
+	billing.Now = func() time.Time { return time.Date(2019, 8, 28, 17, 0, 0, 0, time.UTC) }
+	return billing
+}
+
+// testOrder - 90 minutes of labor at 95.00 an hour and two 45.50 parts
+func testOrder() models.WorkOrder {
+	return models.WorkOrder{
+		Status: models.StatusCompleted,
+		Lines: []models.WorkOrderLine{
+			{Type: models.LineLabor, Description: "Brake job", Minutes: 90, Rate: 9500},
+			{Type: models.LinePart, SKU: "BP-100", Description: "brake pads", Quantity: 2, UnitPrice: 4550},
+		},
+	}
+}
+
+func TestInvoice(t *testing.T) {
+	billing := testBilling()
+
+	invoice, err := billing.Invoice(testOrder(), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// labor 142.50, parts 91.00, shop supplies 5% of labor 7.13, tax 8.875% of 91.00 + 7.13
+	if invoice.LaborTotal != 14250 || invoice.PartsTotal != 9100 || invoice.FeeTotal != 713 {
+		t.Errorf("got labor %v parts %v fees %v", invoice.LaborTotal, invoice.PartsTotal, invoice.FeeTotal)
+	}
+	if len(invoice.Taxes) != 2 || invoice.Taxes[0].Amount != 808 || invoice.Taxes[1].Amount != 63 || invoice.Total != 24934 {
+		t.Errorf("got taxes %+v total %v want 8.08 and 0.63 making 249.34", invoice.Taxes, invoice.Total)
+	}
+
+	// 10% of 233.50 is 23.35, split 14.25 labor and 9.10 parts, so parts are taxed on 81.90
+	invoice, err = billing.Invoice(testOrder(), "SAVE10", []Discount{{Description: "Loyalty", Amount: 500, LineType: models.LineLabor}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invoice.Discounts) != 2 || invoice.Discounts[0].Code != "SAVE10" || invoice.Discounts[0].Amount != 2335 || invoice.Discounts[1].Amount != 500 {
+		t.Errorf("got discounts %+v", invoice.Discounts)
+	}
+	if invoice.Taxes[0].Taxable != 8190 || invoice.Taxes[0].Amount != 727 || invoice.Total != 22018 {
+		t.Errorf("got taxes %+v total %v want 7.27 on 81.90 making 220.18", invoice.Taxes, invoice.Total)
+	}
+
+	discounts := []Discount{{Description: "Goodwill", Amount: 100000}}
+	if invoice, _ := billing.Invoice(testOrder(), "", discounts); invoice.DiscountTotal != 23350 || invoice.Total != 776 {
+		t.Errorf("got discount %v total %v want the discount limited to the subtotal", invoice.DiscountTotal, invoice.Total)
+	}
+}
+
+func TestInvoiceErrors(t *testing.T) {
+	billing := testBilling()
+
+	if _, err := billing.Invoice(testOrder(), "FREE", nil); !errors.Is(err, ErrUnknownCoupon) {
+		t.Errorf("got %v want %v", err, ErrUnknownCoupon)
+	}
+	invalid := map[string]Discount{
+		"no description":       {Amount: 500},
+		"percent and amount":   {Description: "Both", Amount: 500, Percent: money.Percent(5)},
+		"over 100 percent":     {Description: "Too much", Percent: money.Percent(101)},
+		"discounted fee lines": {Description: "Fees", Amount: 500, LineType: models.LineFee},
+	}
+	for name, discount := range invalid {
+		if _, err := billing.Invoice(testOrder(), "", []Discount{discount}); !errors.Is(err, ErrInvalidDiscount) {
+			t.Errorf("%v: got %v want %v", name, err, ErrInvalidDiscount)
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	billing := testBilling()
+	invoice, err := billing.Invoice(testOrder(), "SAVE10", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoice.Number = billing.Number(42)
+
+	var page bytes.Buffer
+	if err := billing.RenderHTML(&page, invoice); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Main Street Garage", "Invoice INV-000042", "August 28, 2019", "BP-100 brake pads", "(SAVE10)", "Part tax (8.875% of 81.90)"} {
+		if !strings.Contains(page.String(), expected) {
+			t.Errorf("page is missing %q:\n%s", expected, page.String())
+		}
+	}
+}
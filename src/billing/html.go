@@ -0,0 +1,77 @@
+package billing
+
+import (
+	"CarServiceCenter/src/models"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// invoicePage - the data the invoice template is executed with
+type invoicePage struct {
+	ShopName string
+	models.Invoice
+}
+
+// invoiceTemplate - a self-contained printable page for one invoice
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"date":  func(t time.Time) string { return t.Format("January 2, 2006") },
+	"title": func(s string) string { return strings.ToUpper(s[:1]) + s[1:] },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.3em 0.6em; border-bottom: 1px solid #ccc; text-align: left; }
+td.amount, th.amount { text-align: right; }
+tfoot td { border-bottom: none; }
+tr.total td { font-weight: bold; border-top: 2px solid #000; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.ShopName}}</h1>
+<h2>Invoice {{.Number}}</h2>
+<p>Issued {{date .IssuedAt}}</p>
+<table>
+<thead><tr><th>Type</th><th>Description</th><th>Quantity</th><th class="amount">Price</th><th class="amount">Amount</th></tr></thead>
+<tbody>
+{{- range .Lines}}
+<tr>
+<td>{{title .Type}}</td>
+{{- if eq .Type "labor"}}
+<td>{{.Description}}</td><td>{{.Minutes}} min</td><td class="amount">{{.Rate}}/h</td>
+{{- else}}
+<td>{{.SKU}}{{if .Description}} {{.Description}}{{end}}</td><td>{{.Quantity}}</td><td class="amount">{{.UnitPrice}}</td>
+{{- end}}
+<td class="amount">{{.Total}}</td>
+</tr>
+{{- end}}
+</tbody>
+<tfoot>
+<tr><td colspan="4">Labor</td><td class="amount">{{.LaborTotal}}</td></tr>
+<tr><td colspan="4">Parts</td><td class="amount">{{.PartsTotal}}</td></tr>
+{{- range .Discounts}}
+<tr><td colspan="4">{{.Description}}{{if .Code}} ({{.Code}}){{end}}</td><td class="amount">-{{.Amount}}</td></tr>
+{{- end}}
+{{- range .Fees}}
+<tr><td colspan="4">{{.Description}}</td><td class="amount">{{.Amount}}</td></tr>
+{{- end}}
+{{- range .Taxes}}
+<tr><td colspan="4">{{title .LineType}} tax ({{.Rate}}% of {{.Taxable}})</td><td class="amount">{{.Amount}}</td></tr>
+{{- end}}
+<tr class="total"><td colspan="4">Total</td><td class="amount">{{.Total}}</td></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
+
+// RenderHTML - writes invoice to w as a printable HTML page
+func (b *Billing) RenderHTML(w io.Writer, invoice models.Invoice) error {
+	return invoiceTemplate.Execute(w, invoicePage{ShopName: b.shopName, Invoice: invoice})
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// minSecretLength - the shortest signing secret accepted; HS256 keys shorter than the hash add no strength
+const minSecretLength = 32
+
+// knownPasswords - passwords that ship in examples or are guessed first, refused for the bootstrap account
+var knownPasswords = []string{"change-me-now", "changeme", "change-me", "password", "admin", "administrator", "secret", "12345678", "letmein"}
+
+// AuthConfig - how staff sessions are signed and how long they last
+type AuthConfig struct {
+	// Secret - key the session tokens are signed with. When empty a random key is generated at startup,
+	// so sessions end whenever the server restarts.
+	Secret     string   `json:"secret" yaml:"secret"`
+	AccessTTL  Duration `json:"access_ttl" yaml:"access_ttl"`
+	RefreshTTL Duration `json:"refresh_ttl" yaml:"refresh_ttl"`
+	// BootstrapUsername and BootstrapPassword - the first staff account, created at startup while there are none
+	BootstrapUsername string `json:"bootstrap_username" yaml:"bootstrap_username"`
+	BootstrapPassword string `json:"bootstrap_password" yaml:"bootstrap_password"`
+}
+
+// defaultAuth - fifteen minute access tokens refreshed for up to a week
+func defaultAuth() AuthConfig {
+	return AuthConfig{
+		AccessTTL:  Duration(15 * time.Minute),
+		RefreshTTL: Duration(7 * 24 * time.Hour),
+	}
+}
+
+func (a *AuthConfig) validate() error {
+	switch {
+	case a.Secret != "" && len(a.Secret) < minSecretLength:
+		return errors.New("auth secret must be at least 32 characters")
+	case a.AccessTTL <= 0:
+		return errors.New("auth access_ttl must be positive")
+	case a.RefreshTTL < a.AccessTTL:
+		return errors.New("auth refresh_ttl must be at least access_ttl")
+	case (a.BootstrapUsername == "") != (a.BootstrapPassword == ""):
+		return errors.New("auth bootstrap_username and bootstrap_password must be set together")
+	case a.BootstrapPassword != "" && isKnownPassword(a.BootstrapUsername, a.BootstrapPassword):
+		return errors.New("auth bootstrap_password must not be a default or well-known password")
+	}
+	return nil
+}
+
+// isKnownPassword - reports whether password is the username or one of knownPasswords, ignoring case
+func isKnownPassword(username, password string) bool {
+	if strings.EqualFold(password, username) {
+		return true
+	}
+	for _, known := range knownPasswords {
+		if strings.EqualFold(password, known) {
+			return true
+		}
+	}
+	return false
+}
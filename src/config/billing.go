@@ -0,0 +1,77 @@
+package config
+
+import (
+	"CarServiceCenter/src/money"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BillingConfig - how invoices are numbered, taxed and adjusted
+type BillingConfig struct {
+	ShopName      string `json:"shop_name" yaml:"shop_name"`
+	InvoicePrefix string `json:"invoice_prefix" yaml:"invoice_prefix"`
+	// TaxRates - percentage charged on each line type: labor, part, or fee for shop fees; missing types aren't taxed
+	TaxRates map[string]money.Rate `json:"tax_rates" yaml:"tax_rates"`
+	ShopFees []ShopFee             `json:"shop_fees" yaml:"shop_fees"`
+	// Coupons - discounts customers can redeem by code, matched case-insensitively
+	Coupons map[string]Coupon `json:"coupons" yaml:"coupons"`
+}
+
+// ShopFee - a charge added to every invoice, such as shop supplies. The fee is Amount plus Percent of the
+// labor or part subtotal named by LineType (or of both when it is empty), capped at Max when Max is set.
+type ShopFee struct {
+	Name     string       `json:"name" yaml:"name"`
+	Amount   money.Amount `json:"amount" yaml:"amount"`
+	Percent  money.Rate   `json:"percent" yaml:"percent"`
+	Max      money.Amount `json:"max" yaml:"max"`
+	LineType string       `json:"line_type" yaml:"line_type"`
+}
+
+// Coupon - a discount of either Percent or Amount off the labor or part subtotal named by LineType, or off both when it is empty
+type Coupon struct {
+	Description string       `json:"description" yaml:"description"`
+	Amount      money.Amount `json:"amount" yaml:"amount"`
+	Percent     money.Rate   `json:"percent" yaml:"percent"`
+	LineType    string       `json:"line_type" yaml:"line_type"`
+}
+
+// taxedLineTypes - the keys tax_rates accepts
+var taxedLineTypes = []string{"labor", "part", "fee"}
+
+// discountedLineTypes - the line types fees and discounts may be limited to
+var discountedLineTypes = []string{"labor", "part"}
+
+func (b *BillingConfig) validate() error {
+	for lineType, rate := range b.TaxRates {
+		if !contains(taxedLineTypes, lineType) {
+			return fmt.Errorf("billing tax_rates key %q must be one of %v", lineType, strings.Join(taxedLineTypes, ", "))
+		}
+		if rate < 0 || rate > money.Percent(100) {
+			return fmt.Errorf("billing tax rate for %s must be between 0 and 100", lineType)
+		}
+	}
+	for _, fee := range b.ShopFees {
+		switch {
+		case strings.TrimSpace(fee.Name) == "":
+			return errors.New("billing shop_fees must have a name")
+		case fee.Amount < 0 || fee.Percent < 0 || fee.Max < 0:
+			return fmt.Errorf("billing shop fee %q must not be negative", fee.Name)
+		case fee.LineType != "" && !contains(discountedLineTypes, fee.LineType):
+			return fmt.Errorf("billing shop fee %q line_type must be one of %v", fee.Name, strings.Join(discountedLineTypes, ", "))
+		}
+	}
+	for code, coupon := range b.Coupons {
+		switch {
+		case strings.TrimSpace(code) == "":
+			return errors.New("billing coupon codes must not be empty")
+		case (coupon.Amount > 0) == (coupon.Percent > 0) || coupon.Amount < 0 || coupon.Percent < 0:
+			return fmt.Errorf("billing coupon %q must have either a positive amount or a positive percent", code)
+		case coupon.Percent > money.Percent(100):
+			return fmt.Errorf("billing coupon %q percent must not be over 100", code)
+		case coupon.LineType != "" && !contains(discountedLineTypes, coupon.LineType):
+			return fmt.Errorf("billing coupon %q line_type must be one of %v", code, strings.Join(discountedLineTypes, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,381 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Storage backends supported by the db package
+const (
+	BackendMongo  = "mongo"
+	BackendMemory = "memory"
+	BackendBolt   = "bolt"
+)
+
+// Config - runtime configuration for the server and the database it talks to
+type Config struct {
+	Server     ServerConfig     `json:"server" yaml:"server"`
+	Storage    StorageConfig    `json:"storage" yaml:"storage"`
+	Mongo      MongoConfig      `json:"mongo" yaml:"mongo"`
+	Bolt       BoltConfig       `json:"bolt" yaml:"bolt"`
+	Scheduling SchedulingConfig `json:"scheduling" yaml:"scheduling"`
+	Billing    BillingConfig    `json:"billing" yaml:"billing"`
+	Auth       AuthConfig       `json:"auth" yaml:"auth"`
+	Portal     PortalConfig     `json:"portal" yaml:"portal"`
+}
+
+// ServerConfig - settings for the http server
+type ServerConfig struct {
+	Port            string   `json:"port" yaml:"port"`
+	RequestTimeout  Duration `json:"request_timeout" yaml:"request_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	// AllowedOrigins - origins browsers may call the API from; "*" allows any. Cookies are never sent cross-origin,
+	// since sessions travel in the Authorization header.
+	AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
+}
+
+// StorageConfig - selects where appointments are stored
+type StorageConfig struct {
+	Backend string `json:"backend" yaml:"backend"`
+}
+
+// MongoConfig - connection settings for MongoDB
+type MongoConfig struct {
+	URI            string           `json:"uri" yaml:"uri"`
+	Database       string           `json:"database" yaml:"database"`
+	Collections    MongoCollections `json:"collections" yaml:"collections"`
+	ConnectTimeout Duration         `json:"connect_timeout" yaml:"connect_timeout"`
+	PoolSize       uint64           `json:"pool_size" yaml:"pool_size"`
+	Username       string           `json:"username" yaml:"username"`
+	Password       string           `json:"password" yaml:"password"`
+	AuthSource     string           `json:"auth_source" yaml:"auth_source"`
+}
+
+// MongoCollections - names of the collections used by the db package
+type MongoCollections struct {
+	Appointments string `json:"appointments" yaml:"appointments"`
+	Customers    string `json:"customers" yaml:"customers"`
+	Vehicles     string `json:"vehicles" yaml:"vehicles"`
+	Catalog      string `json:"catalog" yaml:"catalog"`
+	Technicians  string `json:"technicians" yaml:"technicians"`
+	WorkOrders   string `json:"workorders" yaml:"workorders"`
+	Invoices     string `json:"invoices" yaml:"invoices"`
+	Parts        string `json:"parts" yaml:"parts"`
+	Estimates    string `json:"estimates" yaml:"estimates"`
+	Users        string `json:"users" yaml:"users"`
+	APIKeys      string `json:"apikeys" yaml:"apikeys"`
+	Audit        string `json:"audit" yaml:"audit"`
+	// Counters - sequences such as the next invoice number
+	Counters string `json:"counters" yaml:"counters"`
+}
+
+// BoltConfig - settings for the embedded bbolt file store
+type BoltConfig struct {
+	Path        string   `json:"path" yaml:"path"`
+	OpenTimeout Duration `json:"open_timeout" yaml:"open_timeout"`
+}
+
+// Duration - time.Duration that can be read from strings such as "20s" in config files
+type Duration time.Duration
+
+// UnmarshalJSON - parses a duration string such as "1m30s"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"20s\": %v", err)
+	}
+	return d.set(value)
+}
+
+// MarshalJSON - writes the duration as a string such as "1m30s"
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalYAML - parses a duration string such as "1m30s"
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+func (d *Duration) set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Default - returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            "8080",
+			RequestTimeout:  Duration(200 * time.Second),
+			ShutdownTimeout: Duration(30 * time.Second),
+			AllowedOrigins:  []string{"*"},
+		},
+		Storage: StorageConfig{
+			Backend: BackendMongo,
+		},
+		Mongo: MongoConfig{
+			URI:      "mongodb://localhost:27017",
+			Database: "test",
+			Collections: MongoCollections{
+				Appointments: "appointments",
+				Customers:    "customers",
+				Vehicles:     "vehicles",
+				Catalog:      "catalog",
+				Technicians:  "technicians",
+				WorkOrders:   "workorders",
+				Invoices:     "invoices",
+				Parts:        "parts",
+				Estimates:    "estimates",
+				Users:        "users",
+				APIKeys:      "apikeys",
+				Audit:        "audit",
+				Counters:     "counters",
+			},
+			ConnectTimeout: Duration(20 * time.Second),
+			PoolSize:       100,
+		},
+		Bolt: BoltConfig{
+			Path:        "carservicecenter.db",
+			OpenTimeout: Duration(5 * time.Second),
+		},
+		Scheduling: SchedulingConfig{
+			Timezone:        "UTC",
+			BusinessHours:   defaultBusinessHours(),
+			SlotDuration:    Duration(30 * time.Minute),
+			DefaultDuration: Duration(time.Hour),
+			Suggestions:     3,
+			SearchDays:      7,
+		},
+		Billing: BillingConfig{
+			ShopName:      "Car Service Center",
+			InvoicePrefix: "INV-",
+		},
+		Auth:   defaultAuth(),
+		Portal: defaultPortal(),
+	}
+}
+
+// Load - builds the configuration from the defaults, the optional file at path and
+// finally environment variables, each taking precedence over the previous one
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		// business hours from a file replace the defaults instead of merging with them
+		cfg.Scheduling.BusinessHours = nil
+		if err := cfg.readFile(path); err != nil {
+			return nil, fmt.Errorf("reading config file %s: %v", path, err)
+		}
+	}
+	if cfg.Scheduling.BusinessHours == nil {
+		cfg.Scheduling.BusinessHours = defaultBusinessHours()
+	}
+	if err := cfg.readEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readFile - decodes a JSON or YAML file, chosen by its extension, over cfg
+func (cfg *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.UnmarshalStrict(data, cfg)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(cfg)
+	default:
+		return fmt.Errorf("unsupported config file extension %q", filepath.Ext(path))
+	}
+}
+
+// readEnv - overrides cfg with any of the supported environment variables that are set
+func (cfg *Config) readEnv() error {
+	lookupString("PORT", &cfg.Server.Port)
+	lookupString("STORAGE_BACKEND", &cfg.Storage.Backend)
+	lookupString("MONGO_URI", &cfg.Mongo.URI)
+	lookupString("MONGO_DATABASE", &cfg.Mongo.Database)
+	lookupString("MONGO_APPOINTMENTS_COLLECTION", &cfg.Mongo.Collections.Appointments)
+	lookupString("MONGO_CUSTOMERS_COLLECTION", &cfg.Mongo.Collections.Customers)
+	lookupString("MONGO_VEHICLES_COLLECTION", &cfg.Mongo.Collections.Vehicles)
+	lookupString("MONGO_CATALOG_COLLECTION", &cfg.Mongo.Collections.Catalog)
+	lookupString("MONGO_TECHNICIANS_COLLECTION", &cfg.Mongo.Collections.Technicians)
+	lookupString("MONGO_WORKORDERS_COLLECTION", &cfg.Mongo.Collections.WorkOrders)
+	lookupString("MONGO_INVOICES_COLLECTION", &cfg.Mongo.Collections.Invoices)
+	lookupString("MONGO_PARTS_COLLECTION", &cfg.Mongo.Collections.Parts)
+	lookupString("MONGO_ESTIMATES_COLLECTION", &cfg.Mongo.Collections.Estimates)
+	lookupString("MONGO_USERS_COLLECTION", &cfg.Mongo.Collections.Users)
+	lookupString("MONGO_APIKEYS_COLLECTION", &cfg.Mongo.Collections.APIKeys)
+	lookupString("MONGO_AUDIT_COLLECTION", &cfg.Mongo.Collections.Audit)
+	lookupString("MONGO_COUNTERS_COLLECTION", &cfg.Mongo.Collections.Counters)
+	lookupString("MONGO_USERNAME", &cfg.Mongo.Username)
+	lookupString("MONGO_PASSWORD", &cfg.Mongo.Password)
+	lookupString("MONGO_AUTH_SOURCE", &cfg.Mongo.AuthSource)
+	lookupString("BOLT_PATH", &cfg.Bolt.Path)
+	lookupString("SCHEDULING_TIMEZONE", &cfg.Scheduling.Timezone)
+	lookupString("BILLING_SHOP_NAME", &cfg.Billing.ShopName)
+	lookupString("BILLING_INVOICE_PREFIX", &cfg.Billing.InvoicePrefix)
+	lookupString("AUTH_SECRET", &cfg.Auth.Secret)
+	lookupString("AUTH_BOOTSTRAP_USERNAME", &cfg.Auth.BootstrapUsername)
+	lookupString("AUTH_BOOTSTRAP_PASSWORD", &cfg.Auth.BootstrapPassword)
+
+	if value := os.Getenv("CORS_ALLOWED_ORIGINS"); value != "" {
+		cfg.Server.AllowedOrigins = nil
+		for _, origin := range strings.Split(value, ",") {
+			cfg.Server.AllowedOrigins = append(cfg.Server.AllowedOrigins, strings.TrimSpace(origin))
+		}
+	}
+
+	durations := map[string]*Duration{
+		"REQUEST_TIMEOUT":            &cfg.Server.RequestTimeout,
+		"SHUTDOWN_TIMEOUT":           &cfg.Server.ShutdownTimeout,
+		"MONGO_CONNECT_TIMEOUT":      &cfg.Mongo.ConnectTimeout,
+		"BOLT_OPEN_TIMEOUT":          &cfg.Bolt.OpenTimeout,
+		"AUTH_ACCESS_TTL":            &cfg.Auth.AccessTTL,
+		"AUTH_REFRESH_TTL":           &cfg.Auth.RefreshTTL,
+		"PORTAL_LINK_TTL":            &cfg.Portal.LinkTTL,
+		"PORTAL_CANCELLATION_CUTOFF": &cfg.Portal.CancellationCutoff,
+	}
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			if err := target.set(value); err != nil {
+				return fmt.Errorf("invalid %s %q: %v", name, value, err)
+			}
+		}
+	}
+
+	if value := os.Getenv("MONGO_POOL_SIZE"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid MONGO_POOL_SIZE %q: %v", value, err)
+		}
+		cfg.Mongo.PoolSize = parsed
+	}
+
+	if value := os.Getenv("SCHEDULING_BAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid SCHEDULING_BAYS %q: %v", value, err)
+		}
+		cfg.Scheduling.Bays = parsed
+	}
+	return nil
+}
+
+func lookupString(name string, target *string) {
+	if value := os.Getenv(name); value != "" {
+		*target = value
+	}
+}
+
+// Validate - checks that every required setting has a usable value
+func (cfg *Config) Validate() error {
+	switch {
+	case cfg.Server.Port == "":
+		return errors.New("server port must be set")
+	case cfg.Server.RequestTimeout <= 0:
+		return errors.New("server request_timeout must be positive")
+	case cfg.Server.ShutdownTimeout <= 0:
+		return errors.New("server shutdown_timeout must be positive")
+	}
+	if err := cfg.Scheduling.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Billing.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Auth.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Portal.validate(); err != nil {
+		return err
+	}
+
+	switch cfg.Storage.Backend {
+	case BackendMongo:
+		return cfg.Mongo.validate()
+	case BackendMemory:
+		return nil
+	case BackendBolt:
+		return cfg.Bolt.validate()
+	default:
+		return fmt.Errorf("storage backend must be one of %q, %q or %q", BackendMongo, BackendMemory, BackendBolt)
+	}
+}
+
+func (b *BoltConfig) validate() error {
+	switch {
+	case b.Path == "":
+		return errors.New("bolt path must be set")
+	case b.OpenTimeout <= 0:
+		return errors.New("bolt open_timeout must be positive")
+	}
+	return nil
+}
+
+func (m *MongoConfig) validate() error {
+	switch {
+	case m.URI == "":
+		return errors.New("mongo uri must be set")
+	case m.Database == "":
+		return errors.New("mongo database must be set")
+	case m.Collections.Appointments == "":
+		return errors.New("mongo appointments collection must be set")
+	case m.Collections.Customers == "":
+		return errors.New("mongo customers collection must be set")
+	case m.Collections.Vehicles == "":
+		return errors.New("mongo vehicles collection must be set")
+	case m.Collections.Catalog == "":
+		return errors.New("mongo catalog collection must be set")
+	case m.Collections.Technicians == "":
+		return errors.New("mongo technicians collection must be set")
+	case m.Collections.WorkOrders == "":
+		return errors.New("mongo workorders collection must be set")
+	case m.Collections.Invoices == "":
+		return errors.New("mongo invoices collection must be set")
+	case m.Collections.Parts == "":
+		return errors.New("mongo parts collection must be set")
+	case m.Collections.Estimates == "":
+		return errors.New("mongo estimates collection must be set")
+	case m.Collections.Users == "":
+		return errors.New("mongo users collection must be set")
+	case m.Collections.APIKeys == "":
+		return errors.New("mongo apikeys collection must be set")
+	case m.Collections.Audit == "":
+		return errors.New("mongo audit collection must be set")
+	case m.Collections.Counters == "":
+		return errors.New("mongo counters collection must be set")
+	case m.ConnectTimeout <= 0:
+		return errors.New("mongo connect_timeout must be positive")
+	case m.PoolSize == 0:
+		return errors.New("mongo pool_size must be positive")
+	case m.Password != "" && m.Username == "":
+		return errors.New("mongo username must be set when a password is given")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mongo.URI != "mongodb://localhost:27017" || cfg.Mongo.Database != "test" {
+		t.Errorf("unexpected default mongo config: %+v", cfg.Mongo)
+	}
+}
+
+func TestLoadYAMLFileWithEnvOverride(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: "9090"
+mongo:
+  uri: mongodb://staging:27017
+  database: staging
+  collections:
+    appointments: staging_appointments
+  connect_timeout: 5s
+`)
+	t.Setenv("MONGO_DATABASE", "prod")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != "9090" {
+		t.Errorf("got port %v want 9090", cfg.Server.Port)
+	}
+	if cfg.Mongo.URI != "mongodb://staging:27017" {
+		t.Errorf("got uri %v want mongodb://staging:27017", cfg.Mongo.URI)
+	}
+	if cfg.Mongo.Database != "prod" {
+		t.Errorf("got database %v want prod", cfg.Mongo.Database)
+	}
+	if cfg.Mongo.Collections.Appointments != "staging_appointments" {
+		t.Errorf("got collection %v want staging_appointments", cfg.Mongo.Collections.Appointments)
+	}
+	if time.Duration(cfg.Mongo.ConnectTimeout) != 5*time.Second {
+		t.Errorf("got connect timeout %v want 5s", time.Duration(cfg.Mongo.ConnectTimeout))
+	}
+}
+
+func TestLoadJSONFileRejectsUnknownFields(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{"mongo": {"url": "mongodb://typo:27017"}}`)
+
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestLoadInvalidPoolSize(t *testing.T) {
+	t.Setenv("MONGO_POOL_SIZE", "0")
+
+	if _, err := Load(""); err == nil {
+		t.Error("expected an error for a zero pool size")
+	}
+}
+
+func TestLoadExampleFile(t *testing.T) {
+	if _, err := Load("../../config.example.yaml"); err != nil {
+		t.Errorf("got %v want the example configuration to load as it is", err)
+	}
+}
+
+func TestLoadBootstrapPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"example password", "change-me-now", false},
+		{"well-known password in another case", "Password", false},
+		{"same as username", "ADMIN", false},
+		{"own password", "correct horse battery", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("AUTH_BOOTSTRAP_USERNAME", "admin")
+			t.Setenv("AUTH_BOOTSTRAP_PASSWORD", test.password)
+			if _, err := Load(""); (err == nil) != test.valid {
+				t.Errorf("got %v for %q want valid %v", err, test.password, test.valid)
+			}
+		})
+	}
+}
+
+func TestLoadBilling(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+billing:
+  tax_rates:
+    part: "8.875"
+    fee: 5
+  shop_fees:
+    - {name: Shop supplies, percent: 5, max: "35.00", line_type: labor}
+  coupons:
+    SAVE10: {percent: 10}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Billing.TaxRates["part"] != 88750 || cfg.Billing.ShopFees[0].Max != 3500 || cfg.Billing.Coupons["SAVE10"].Percent != 100000 {
+		t.Errorf("got %+v", cfg.Billing)
+	}
+	if cfg.Billing.InvoicePrefix != "INV-" {
+		t.Errorf("got invoice prefix %q want the default", cfg.Billing.InvoicePrefix)
+	}
+
+	path = writeConfigFile(t, "config.yaml", `
+billing:
+  coupons:
+    SAVE10: {percent: 10, amount: 5}
+`)
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for a coupon with both a percent and an amount")
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// PortalConfig - settings for the customer self-service portal
+type PortalConfig struct {
+	// LinkTTL - how long a portal link handed to a customer keeps working
+	LinkTTL Duration `json:"link_ttl" yaml:"link_ttl"`
+	// CancellationCutoff - customers can't cancel online once an appointment is closer than this
+	CancellationCutoff Duration `json:"cancellation_cutoff" yaml:"cancellation_cutoff"`
+}
+
+// defaultPortal - links that work for three days, and online cancellation until a day before the appointment
+func defaultPortal() PortalConfig {
+	return PortalConfig{
+		LinkTTL:            Duration(72 * time.Hour),
+		CancellationCutoff: Duration(24 * time.Hour),
+	}
+}
+
+func (p *PortalConfig) validate() error {
+	switch {
+	case p.LinkTTL <= 0:
+		return errors.New("portal link_ttl must be positive")
+	case p.CancellationCutoff < 0:
+		return errors.New("portal cancellation_cutoff must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SchedulingConfig - shop capacity used to reject double-booked appointments.
+// Scheduling checks are disabled while Bays is 0.
+type SchedulingConfig struct {
+	Bays             int                 `json:"bays" yaml:"bays"`
+	Timezone         string              `json:"timezone" yaml:"timezone"`
+	BusinessHours    map[string]DayHours `json:"business_hours" yaml:"business_hours"`
+	SlotDuration     Duration            `json:"slot_duration" yaml:"slot_duration"`
+	DefaultDuration  Duration            `json:"default_duration" yaml:"default_duration"`
+	ServiceDurations map[string]Duration `json:"service_durations" yaml:"service_durations"`
+	Suggestions      int                 `json:"suggestions" yaml:"suggestions"`
+	SearchDays       int                 `json:"search_days" yaml:"search_days"`
+}
+
+// DayHours - opening and closing time of a business day in 24-hour "15:04" form
+type DayHours struct {
+	Open  string `json:"open" yaml:"open"`
+	Close string `json:"close" yaml:"close"`
+}
+
+// weekdays - lower-case names accepted as business_hours keys
+var weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// defaultBusinessHours - used when no business hours are configured
+func defaultBusinessHours() map[string]DayHours {
+	return map[string]DayHours{
+		"monday":    {Open: "08:00", Close: "18:00"},
+		"tuesday":   {Open: "08:00", Close: "18:00"},
+		"wednesday": {Open: "08:00", Close: "18:00"},
+		"thursday":  {Open: "08:00", Close: "18:00"},
+		"friday":    {Open: "08:00", Close: "18:00"},
+		"saturday":  {Open: "09:00", Close: "13:00"},
+	}
+}
+
+func (s *SchedulingConfig) validate() error {
+	switch {
+	case s.Bays < 0:
+		return errors.New("scheduling bays must not be negative")
+	case s.Bays == 0:
+		return nil
+	case s.SlotDuration <= 0:
+		return errors.New("scheduling slot_duration must be positive")
+	case s.DefaultDuration <= 0:
+		return errors.New("scheduling default_duration must be positive")
+	case s.Suggestions < 0:
+		return errors.New("scheduling suggestions must not be negative")
+	case s.SearchDays < 1:
+		return errors.New("scheduling search_days must be at least 1")
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("scheduling timezone %q: %v", s.Timezone, err)
+	}
+	for day, hours := range s.BusinessHours {
+		if !contains(weekdays, day) {
+			return fmt.Errorf("scheduling business_hours key %q must be one of %v", day, strings.Join(weekdays, ", "))
+		}
+		open, openErr := time.Parse("15:04", hours.Open)
+		close, closeErr := time.Parse("15:04", hours.Close)
+		if openErr != nil || closeErr != nil || !open.Before(close) {
+			return fmt.Errorf("scheduling business_hours for %s must open before closing, as \"15:04\" times", day)
+		}
+	}
+	for service, duration := range s.ServiceDurations {
+		if duration <= 0 {
+			return fmt.Errorf("scheduling duration for service %q must be positive", service)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
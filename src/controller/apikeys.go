@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// APIKeysController - struct that has a reference to the API key store
+type APIKeysController struct {
+	DB db.APIKeyStore
+}
+
+// newAPIKeyRequest - body of a request to create an API key
+type newAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// newAPIKeyResponse - a created API key along with the key itself, which is never shown again
+type newAPIKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// validateScopes - returns scopes sorted without duplicates, or a description of the first one that isn't valid
+func validateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("api key must have at least one scope")
+	}
+	unique := map[string]bool{}
+	for _, scope := range scopes {
+		if !models.ValidScope(scope) {
+			return nil, fmt.Errorf("scope %v must be <resource>:read or <resource>:write, where resource is one of %v",
+				scope, strings.Join(models.ScopeResources(), ", "))
+		}
+		unique[scope] = true
+	}
+	result := make([]string, 0, len(unique))
+	for scope := range unique {
+		result = append(result, scope)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// CreateAPIKey - accepts a name and scopes and returns the new API key, including the key itself
+func (a *APIKeysController) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request newAPIKeyRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid api key")
+	} else if strings.TrimSpace(request.Name) == "" {
+		status = http.StatusBadRequest
+		response = errorJSON("api key must have a name")
+	} else if scopes, err := validateScopes(request.Scopes); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if key, prefix, hash, err := auth.NewAPIKey(); err != nil {
+		log.Println("error creating api key", err)
+		status = http.StatusInternalServerError
+		response = errorJSON("internal server error")
+	} else if created, err := a.DB.CreateAPIKey(r.Context(), models.APIKey{Name: strings.TrimSpace(request.Name), Prefix: prefix, KeyHash: hash,
+		Scopes: scopes, CreatedBy: callerName(r), CreatedAt: time.Now().UTC()}); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(newAPIKeyResponse{APIKey: *created, Key: key})
+		if err != nil {
+			log.Println("error marshaling api key", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// callerName - the username of the authenticated caller of r, or "" if it wasn't authenticated
+func callerName(r *http.Request) string {
+	if claims, ok := auth.ClaimsFrom(r.Context()); ok {
+		return claims.Username
+	}
+	return ""
+}
+
+// GetAPIKey - accepts API key id and returns the specified key, without the key itself
+func (a *APIKeysController) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	key, err := a.DB.GetAPIKey(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(key)
+		if err != nil {
+			log.Println("error marshaling api key", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListAPIKeys - returns every API key, including revoked ones, from the oldest to the newest
+func (a *APIKeysController) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	keys, err := a.DB.ListAPIKeys(r.Context())
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(keys)
+		if err != nil {
+			log.Println("error marshaling api keys", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// RevokeAPIKey - accepts API key id and revokes the key, which is refused from then on; the key stays listed
+func (a *APIKeysController) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	key, err := a.DB.RevokeAPIKey(r.Context(), id, time.Now().UTC())
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(key)
+		if err != nil {
+			log.Println("error marshaling api key", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createAPIKey - creates an API key with the given body through apiKeysController and returns the response
+func createAPIKey(t *testing.T, apiKeysController APIKeysController, body string) newAPIKeyResponse {
+	rr := serveWithID(apiKeysController.CreateAPIKey, "POST", "", body)
+	var created newAPIKeyResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); rr.Code != http.StatusOK || err != nil {
+		t.Fatalf("got %v %v want the api key created", rr.Code, rr.Body.String())
+	}
+	return created
+}
+
+// serveWithAPIKey - sends a request made with key to handler and returns the recorded response
+func serveWithAPIKey(handler http.Handler, method, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(auth.APIKeyHeader, key)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	apiKeysController := APIKeysController{DB: db.NewMemoryStore()}
+
+	created := createAPIKey(t, apiKeysController, `{"name":" Kiosk ","scopes":["parts:write","appointments:read","parts:write"]}`)
+	if created.Key == "" || !strings.HasPrefix(created.Key, created.Prefix) || created.Name != "Kiosk" || created.CreatedBy != "advisor" {
+		t.Errorf("got %+v want the key returned with its prefix, trimmed name and creator", created)
+	}
+	if strings.Join(created.Scopes, ",") != "appointments:read,parts:write" {
+		t.Errorf("got scopes %v want them sorted without duplicates", created.Scopes)
+	}
+	rr := serveWithID(apiKeysController.GetAPIKey, "GET", created.ID.Hex(), "")
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), created.Key) || strings.Contains(rr.Body.String(), `"key"`) {
+		t.Errorf("got %v %v want the key itself only returned when it is created", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		expected string
+	}{
+		{"no name", `{"scopes":["parts:read"]}`, http.StatusBadRequest, `{"error":"api key must have a name"}`},
+		{"no scopes", `{"name":"Kiosk"}`, http.StatusBadRequest, `{"error":"api key must have at least one scope"}`},
+		{"unknown scope", `{"name":"Kiosk","scopes":["parts:delete"]}`, http.StatusBadRequest,
+			`{"error":"scope parts:delete must be \u003cresource\u003e:read or \u003cresource\u003e:write, where resource is one of ` + strings.Join(models.ScopeResources(), ", ") + `"}`},
+		{"not json", `kiosk`, http.StatusBadRequest, `{"error":"request body must be a valid api key"}`},
+	}
+	for _, test := range tests {
+		rr := serveWithID(apiKeysController.CreateAPIKey, "POST", "", test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", test.name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
+
+func TestListAndRevokeAPIKeys(t *testing.T) {
+	apiKeysController := APIKeysController{DB: db.NewMemoryStore()}
+	kiosk := createAPIKey(t, apiKeysController, `{"name":"Kiosk","scopes":["appointments:read"]}`)
+	supplier := createAPIKey(t, apiKeysController, `{"name":"Supplier","scopes":["parts:write"]}`)
+
+	rr := serveWithID(apiKeysController.RevokeAPIKey, "POST", kiosk.ID.Hex(), "")
+	var revoked models.APIKey
+	if err := json.Unmarshal(rr.Body.Bytes(), &revoked); rr.Code != http.StatusOK || err != nil || revoked.RevokedAt == nil {
+		t.Errorf("got %v %v want the key revoked", rr.Code, rr.Body.String())
+	}
+	if rr := serveWithID(apiKeysController.RevokeAPIKey, "POST", "000000000000000000000000", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("revoking an unknown key got %v %v want %v", rr.Code, rr.Body.String(), http.StatusNotFound)
+	}
+
+	rr = serveWithID(apiKeysController.ListAPIKeys, "GET", "", "")
+	var keys []models.APIKey
+	if err := json.Unmarshal(rr.Body.Bytes(), &keys); rr.Code != http.StatusOK || err != nil || len(keys) != 2 ||
+		keys[0].Name != "Kiosk" || keys[0].RevokedAt == nil || keys[1].Name != "Supplier" || keys[1].RevokedAt != nil {
+		t.Fatalf("got %v %v want both keys, oldest first, with only the kiosk revoked", rr.Code, rr.Body.String())
+	}
+	for _, created := range []newAPIKeyResponse{kiosk, supplier} {
+		if body := rr.Body.String(); strings.Contains(body, created.Key) || strings.Contains(body, auth.HashAPIKey(created.Key)) || strings.Contains(body, "hash") {
+			t.Errorf("list %v exposes the key or its hash", body)
+		}
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	store := db.NewMemoryStore()
+	apiKeysController := APIKeysController{DB: store}
+	partsController := PartsController{DB: store}
+	apiKeys := &auth.APIKeys{DB: store, Now: time.Now}
+	scoped := func(handler http.HandlerFunc) http.Handler {
+		return apiKeys.Authenticate(auth.Scope("parts")(handler))
+	}
+	reader := createAPIKey(t, apiKeysController, `{"name":"Kiosk","scopes":["parts:read"]}`)
+	writer := createAPIKey(t, apiKeysController, `{"name":"Supplier","scopes":["parts:read","parts:write"]}`)
+	other := createAPIKey(t, apiKeysController, `{"name":"Calendar","scopes":["appointments:read"]}`)
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		key      string
+		body     string
+		status   int
+		expected string
+	}{
+		{"read with read scope", partsController.ListParts, "GET", reader.Key, "", http.StatusOK, `[]`},
+		{"write with read scope", partsController.CreatePart, "POST", reader.Key, `{"sku":"BP-100","name":"Brake pads"}`,
+			http.StatusForbidden, `{"error":"api key Kiosk does not have the parts:write scope"}`},
+		{"read with another resource's scope", partsController.ListParts, "GET", other.Key, "",
+			http.StatusForbidden, `{"error":"api key Calendar does not have the parts:read scope"}`},
+		{"unknown key", partsController.ListParts, "GET", "not-a-key", "", http.StatusUnauthorized, `{"error":"invalid api key"}`},
+	}
+	for _, test := range tests {
+		rr := serveWithAPIKey(scoped(test.handler), test.method, test.key, test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", test.name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+	if rr := serveWithAPIKey(scoped(partsController.CreatePart), "POST", writer.Key, `{"sku":"BP-100","name":"Brake pads"}`); rr.Code != http.StatusOK {
+		t.Errorf("write with write scope got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+
+	serveWithID(apiKeysController.RevokeAPIKey, "POST", writer.ID.Hex(), "")
+	if rr := serveWithAPIKey(scoped(partsController.ListParts), "GET", writer.Key, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key got %v %v want %v", rr.Code, rr.Body.String(), http.StatusUnauthorized)
+	}
+}
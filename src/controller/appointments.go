@@ -1,84 +1,182 @@
 package controller
 
 import (
+	"CarServiceCenter/src/auth"
 	"CarServiceCenter/src/db"
 	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/scheduling"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // AppointmentsController - struct that has reference to db client
 type AppointmentsController struct {
 	DB db.ClientInterface
+	// Scheduler - optional; when set, new and rescheduled appointments must fit the shop's bays and business hours
+	Scheduler *scheduling.Scheduler
+	// Customers and Vehicles - optional; when set, appointments may only be created for customers and vehicles that exist
+	Customers db.CustomerStore
+	Vehicles  db.VehicleStore
+	// Catalog - optional; when set, the catalog items an appointment is booked for decide how long it takes
+	Catalog db.CatalogStore
+	// Technicians - technicians appointments are assigned to; with a Scheduler they must be working and free for the whole appointment
+	Technicians db.TechnicianStore
+	// WorkOrders - optional; when set, an appointment's work order follows it through status changes
+	WorkOrders db.WorkOrderStore
+	// Parts - optional; when set, parts reserved for a work order are released or consumed as its appointment is cancelled or completed
+	Parts db.PartStore
+	// Estimates - optional; when set, work on an appointment can't start until one of its estimates is approved
+	Estimates db.EstimateStore
+	// Audit - optional; when set, every change to an appointment is recorded in its history
+	Audit db.AuditStore
+
+	// scheduleMu - serializes schedule checks with the write that follows them so this process can't double-book a bay.
+	// The store doesn't enforce bays itself, so with scheduling on only one instance may run against a database.
+	scheduleMu sync.Mutex
+}
+
+// validAppointment - reports whether appointment has the fields every stored appointment needs
+func validAppointment(appointment models.Appointment) bool {
+	return len(appointment.Name) != 0 && !appointment.Date.IsZero() && len(appointment.Description) != 0
 }
 
 // CreateAppointment - accepts appointment name, description, and returns created appointment
 func (a *AppointmentsController) CreateAppointment(w http.ResponseWriter, r *http.Request) {
 	var appointment models.Appointment
-	err := json.NewDecoder(r.Body).Decode(&appointment)
-	if err != nil {
-		log.Println("error decoding json", err.Error())
-	}
 	status := http.StatusOK
 	response := []byte{}
 
-	if len(appointment.Name) == 0 || appointment.Date.IsZero() || len(appointment.Description) == 0 {
+	err := json.NewDecoder(r.Body).Decode(&appointment)
+	if err != nil {
+		log.Println("error decoding json", err.Error())
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid appointment")
+	} else if !validAppointment(appointment) {
 		status = http.StatusBadRequest
-		response = []byte(fmt.Sprintf("appointment must have valid name, description and date values"))
+		response = errorJSON("appointment must have valid name, description and date values")
 	} else {
-		appointment.Status = "open"
-		newAppointment := a.DB.CreateAppointment(appointment)
-		response, err = json.Marshal(newAppointment)
-		if err != nil {
-			log.Println("error:", err)
-		}
-
+		appointment.Status = models.StatusOpen
+		status, response = a.createAppointment(r.Context(), appointment)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	w.Write(response)
 }
 
-// DeleteAppointment - accepts appointmentID to be deleted
+// DeleteAppointment - accepts appointmentID to be deleted; appointments with a work order still open can't be deleted
 func (a *AppointmentsController) DeleteAppointment(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	status := http.StatusOK
-	response := fmt.Sprintf("appointment %v successfully deleted", id)
+	response := []byte(fmt.Sprintf("appointment %v successfully deleted", id))
 
-	deleted := a.DB.DeleteAppointment(id)
-	if deleted == false {
-		status = http.StatusBadRequest
-		response = fmt.Sprintf("unable to find resource with id %v", id)
+	if err := a.checkNoOpenWorkOrder(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if deleted, err := a.DB.DeleteAppointment(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		a.audit(r.Context(), models.AuditDeleted, deleted, nil)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	w.Write([]byte(response))
+	w.Write(response)
 }
 
-// UpdateAppointmentStatus - accepts id and status to update appointment status
+// UpdateAppointmentStatus - accepts id and status to update appointment status, rejecting transitions the lifecycle does not allow
 func (a *AppointmentsController) UpdateAppointmentStatus(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	var updatedStatus models.Status
-	json.NewDecoder(r.Body).Decode(&updatedStatus)
 	status := http.StatusOK
-	response := fmt.Sprintf("appointment status successfully updated to %v", updatedStatus)
+	response := []byte{}
 
-	updated := a.DB.UpdateAppointmentStatus(id, updatedStatus.Status)
-	if updated == false {
+	err := json.NewDecoder(r.Body).Decode(&updatedStatus)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must contain a valid status")
+	} else if !models.ValidStatus(updatedStatus.Status) {
 		status = http.StatusBadRequest
-		response = fmt.Sprintf("unable to update appointment status at id %v", id)
+		response = errorJSON(fmt.Sprintf("status must be one of %v", strings.Join(models.Statuses(), ", ")))
+	} else if !auth.CanSetStatus(r.Context(), updatedStatus.Status) {
+		status = http.StatusForbidden
+		response = errorJSON(fmt.Sprintf("your role may not move appointments to %v", updatedStatus.Status))
+	} else if err = a.setStatus(r.Context(), id, updatedStatus.Status); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response = []byte(fmt.Sprintf("appointment status successfully updated to %v", updatedStatus))
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	w.Write([]byte(response))
+	w.Write(response)
+}
+
+// setStatus - moves the appointment with the given id to status once any estimate it needs is approved,
+// records the change and brings its work order along
+func (a *AppointmentsController) setStatus(ctx context.Context, id, status string) error {
+	if err := a.checkEstimate(ctx, id, status); err != nil {
+		return err
+	}
+	before, err := a.DB.UpdateAppointmentStatus(ctx, id, status)
+	if err != nil {
+		return err
+	}
+	after := *before
+	after.Status = status
+	a.audit(ctx, models.AuditStatusChanged, before, &after)
+	a.syncWorkOrder(ctx, id, status)
+	return nil
+}
+
+// UpdateAppointment - accepts id and a JSON Merge Patch of the appointment's name, description, date, service and catalog items and returns the updated appointment.
+// PUT requests replace all of those fields, so each must be given.
+func (a *AppointmentsController) UpdateAppointment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	appointment, err := a.DB.GetAppointment(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if patched, err := applyAppointmentPatch(*appointment, r.Body, r.Method == http.MethodPut); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if !validAppointment(patched) {
+		status = http.StatusBadRequest
+		response = errorJSON("appointment must have valid name, description and date values")
+	} else {
+		status, response = a.updateAppointment(r.Context(), patched, *appointment)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// PatchAppointment - sends JSON Merge Patch requests to UpdateAppointment and every other PATCH to UpdateAppointmentStatus.
+// Only admins and advisors may edit appointments this way; technicians may only change their status.
+func (a *AppointmentsController) PatchAppointment(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == mergePatchContentType {
+		if !auth.Permitted(r.Context(), models.RoleAdmin, models.RoleAdvisor) {
+			auth.Reject(w, http.StatusForbidden, "your role may not edit appointments")
+			return
+		}
+		a.UpdateAppointment(w, r)
+		return
+	}
+	a.UpdateAppointmentStatus(w, r)
 }
 
 // GetAppointment - accepts appointment id and returns specified appointment
@@ -87,10 +185,9 @@ func (a *AppointmentsController) GetAppointment(w http.ResponseWriter, r *http.R
 	status := http.StatusOK
 	response := []byte{}
 
-	appointment, err := a.DB.GetAppointment(id)
+	appointment, err := a.DB.GetAppointment(r.Context(), id)
 	if err != nil {
-		status = http.StatusBadRequest
-		response = []byte(fmt.Sprintf("Unable to retrieve appointment with ID %v", id))
+		status, response = dbErrorResponse(err)
 	} else {
 		response, err = json.Marshal(appointment)
 		if err != nil {
@@ -130,9 +227,10 @@ func (a *AppointmentsController) GetAppointmentsWithinDateRange(w http.ResponseW
 
 	if start.IsZero() || end.IsZero() || end.Before(start) {
 		status = http.StatusBadRequest
-		response = []byte(fmt.Sprintf("request must have valid start and end date range"))
+		response = errorJSON("request must have valid start and end date range")
+	} else if results, err := a.DB.GetAppointmentsWithinDateRange(r.Context(), start, end); err != nil {
+		status, response = dbErrorResponse(err)
 	} else {
-		results := a.DB.GetAppointmentsWithinDateRange(start, end)
 		response, err = json.Marshal(results)
 		if err != nil {
 			log.Println("error marshaling results")
@@ -142,3 +240,120 @@ func (a *AppointmentsController) GetAppointmentsWithinDateRange(w http.ResponseW
 	w.WriteHeader(status)
 	w.Write(response)
 }
+
+// ListAppointments - returns one page of appointments filtered by the status, name, start, end, customer, vehicle and technician query parameters.
+// Results are ordered by the sort parameter (date, name or status, prefixed with - for descending order) and the next
+// parameter takes the token returned with the previous page.
+func (a *AppointmentsController) ListAppointments(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	query, err := parseAppointmentQuery(r.URL.Query())
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if page, err := a.DB.ListAppointments(r.Context(), *query); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(page)
+		if err != nil {
+			log.Println("error marshaling appointment page")
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// parseAppointmentQuery - builds an AppointmentQuery from the query parameters accepted by ListAppointments
+func parseAppointmentQuery(values url.Values) (*models.AppointmentQuery, error) {
+	query := &models.AppointmentQuery{
+		Filter: models.AppointmentFilter{
+			Status: values.Get("status"),
+			Name:   values.Get("name"),
+		},
+		SortBy: strings.TrimPrefix(values.Get("sort"), "-"),
+		After:  values.Get("next"),
+	}
+	query.Descending = strings.HasPrefix(values.Get("sort"), "-")
+
+	if query.Filter.Status != "" && !models.ValidStatus(query.Filter.Status) {
+		return nil, fmt.Errorf("status must be one of %v", strings.Join(models.Statuses(), ", "))
+	}
+	switch query.SortBy {
+	case "", models.SortByDate, models.SortByName, models.SortByStatus:
+	default:
+		return nil, fmt.Errorf("sort must be one of %v, %v or %v", models.SortByDate, models.SortByName, models.SortByStatus)
+	}
+
+	var err error
+	if query.Filter.Start, err = parseTimeParam(values, "start"); err != nil {
+		return nil, err
+	}
+	if query.Filter.End, err = parseTimeParam(values, "end"); err != nil {
+		return nil, err
+	}
+	if !query.Filter.Start.IsZero() && !query.Filter.End.IsZero() && query.Filter.End.Before(query.Filter.Start) {
+		return nil, errors.New("end must not be before start")
+	}
+	if query.Filter.CustomerID, err = parseIDParam(values, "customer"); err != nil {
+		return nil, err
+	}
+	if query.Filter.VehicleID, err = parseIDParam(values, "vehicle"); err != nil {
+		return nil, err
+	}
+	if query.Filter.TechnicianID, err = parseIDParam(values, "technician"); err != nil {
+		return nil, err
+	}
+
+	if value := values.Get("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil || query.Limit < 1 || query.Limit > models.MaxPageSize {
+			return nil, fmt.Errorf("limit must be a number between 1 and %d", models.MaxPageSize)
+		}
+	}
+	return query, nil
+}
+
+// parseTimeParam - parses the RFC3339 query parameter key, restoring a "+" in the offset that was decoded as a space
+func parseTimeParam(values url.Values, key string) (time.Time, error) {
+	value := values.Get(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, strings.Replace(value, " ", "+", -1))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 date", key)
+	}
+	return parsed, nil
+}
+
+// parseIDParam - parses the object id query parameter key, returning nil when it is absent
+func parseIDParam(values url.Values, key string) (*primitive.ObjectID, error) {
+	value := values.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a valid id", key)
+	}
+	return &id, nil
+}
+
+// parseIDList - reads the comma-separated ids in the query parameter key, returning nil when it isn't given
+func parseIDList(values url.Values, key string) ([]primitive.ObjectID, error) {
+	value := values.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	var ids []primitive.ObjectID
+	for _, part := range strings.Split(value, ",") {
+		id, err := primitive.ObjectIDFromHex(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a comma-separated list of valid ids", key)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -1,7 +1,11 @@
 package controller
 
 import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/db"
 	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/scheduling"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -12,32 +16,37 @@ import (
 	"time"
 
 	"github.com/go-chi/chi"
-	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type DBTestImplementation struct{}
 
-func (d *DBTestImplementation) OpenConnection() *mongo.Client {
-	fmt.Println("being called")
+func (d *DBTestImplementation) Close() error {
 	return nil
 }
 
-func (d *DBTestImplementation) CreateAppointment(appointment models.Appointment) *models.Appointment {
+func (d *DBTestImplementation) CreateAppointment(ctx context.Context, appointment models.Appointment) (*models.Appointment, error) {
+	if appointment.Name == "Unavailable" {
+		return nil, fmt.Errorf("%w: connection refused", db.ErrUnavailable)
+	}
 	date, _ := time.Parse(time.RFC3339, "2019-08-28T09:00:01+00:00")
 	return &models.Appointment{
 		Name:        "Test",
 		Date:        date,
 		Description: "Test Appointment",
 		Status:      "open",
-	}
+	}, nil
 }
-func (d *DBTestImplementation) DeleteAppointment(id string) bool {
+func (d *DBTestImplementation) DeleteAppointment(ctx context.Context, id string) (*models.Appointment, error) {
 	if id != "1" {
-		return false
+		return nil, fmt.Errorf("appointment %v %w", id, db.ErrNotFound)
 	}
-	return true
+	return &models.Appointment{Name: "Test Appointment", Status: "open"}, nil
 }
-func (d *DBTestImplementation) GetAppointment(id string) (*models.Appointment, error) {
+func (d *DBTestImplementation) GetAppointment(ctx context.Context, id string) (*models.Appointment, error) {
+	if id == "2" {
+		return nil, fmt.Errorf("%w %q", db.ErrInvalidID, id)
+	}
 	date, _ := time.Parse(time.RFC3339, "2019-08-28T09:00:01+00:00")
 	return &models.Appointment{
 		Name:        "Test",
@@ -46,8 +55,11 @@ func (d *DBTestImplementation) GetAppointment(id string) (*models.Appointment, e
 		Status:      "open",
 	}, nil
 }
-func (d *DBTestImplementation) GetAppointmentsWithinDateRange(start, end time.Time) *[]models.Appointment {
+func (d *DBTestImplementation) GetAppointmentsWithinDateRange(ctx context.Context, start, end time.Time) (*[]models.Appointment, error) {
 	fmt.Println("times", start, end)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("appointments: %w", err)
+	}
 	date, _ := time.Parse(time.RFC3339, "2019-08-28T09:00:01+00:00")
 	return &[]models.Appointment{
 		models.Appointment{
@@ -62,13 +74,43 @@ func (d *DBTestImplementation) GetAppointmentsWithinDateRange(start, end time.Ti
 			Description: "Test2 Appointment",
 			Status:      "open",
 		},
-	}
+	}, nil
 }
-func (d *DBTestImplementation) UpdateAppointmentStatus(id, status string) bool {
+func (d *DBTestImplementation) UpdateAppointmentStatus(ctx context.Context, id, status string) (*models.Appointment, error) {
 	if id == "2" {
-		return false
+		return nil, fmt.Errorf("appointment %v %w", id, db.ErrNotFound)
 	}
-	return true
+	if id == "3" {
+		return nil, fmt.Errorf("%w: appointment %v cannot move from %q to %q", db.ErrInvalidTransition, id, "completed", status)
+	}
+	return &models.Appointment{Name: "Test Appointment", Status: "open"}, nil
+}
+
+func (d *DBTestImplementation) UpdateAppointment(ctx context.Context, appointment models.Appointment) (*models.Appointment, error) {
+	return &appointment, nil
+}
+
+func (d *DBTestImplementation) AssignAppointmentTechnician(ctx context.Context, id string, technicianID primitive.ObjectID) (*models.Appointment, error) {
+	return &models.Appointment{Name: "Test Appointment", Status: "open"}, nil
+}
+
+func (d *DBTestImplementation) UnassignAppointmentTechnician(ctx context.Context, id string, technicianID primitive.ObjectID) (*models.Appointment, error) {
+	return &models.Appointment{Name: "Test Appointment", Status: "open", TechnicianIDs: []primitive.ObjectID{technicianID}}, nil
+}
+
+func (d *DBTestImplementation) ListAppointments(ctx context.Context, query models.AppointmentQuery) (*models.AppointmentPage, error) {
+	date, _ := time.Parse(time.RFC3339, "2019-08-28T09:00:01+00:00")
+	return &models.AppointmentPage{
+		Appointments: []models.Appointment{
+			models.Appointment{
+				Name:        "Test",
+				Date:        date,
+				Description: "Test Appointment",
+				Status:      query.Filter.Status,
+			},
+		},
+		Next: "token",
+	}, nil
 }
 
 func TestCreateAppointmentSuccess(t *testing.T) {
@@ -126,7 +168,7 @@ func TestBadCreateAppointment(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	expected := "appointment must have valid name, description and date values"
+	expected := `{"error":"appointment must have valid name, description and date values"}`
 	if rr.Body.String() != expected {
 		t.Errorf("handler returned unexpected body: got %v want %v",
 			rr.Body.String(), expected)
@@ -141,7 +183,7 @@ func TestDeleteAppointmentSuccess(t *testing.T) {
 
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "1")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
 
 	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
 	rr := httptest.NewRecorder()
@@ -168,19 +210,19 @@ func TestBadDeleteAppointment(t *testing.T) {
 
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "2")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
 
 	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(appointmentsController.DeleteAppointment)
 	handler.ServeHTTP(rr, req)
 
-	if status := rr.Code; status != http.StatusBadRequest {
+	if status := rr.Code; status != http.StatusNotFound {
 		t.Errorf("handler returned wrong status code: got %v want %v",
-			status, http.StatusOK)
+			status, http.StatusNotFound)
 	}
 
-	expected := "unable to find resource with id 2"
+	expected := `{"error":"appointment 2 not found"}`
 	if rr.Body.String() != expected {
 		t.Errorf("handler returned unexpected body: got %v want %v",
 			rr.Body.String(), expected)
@@ -189,7 +231,7 @@ func TestBadDeleteAppointment(t *testing.T) {
 
 func TestUpdateAppointmentStatusSuccess(t *testing.T) {
 	requestBody := map[string]interface{}{
-		"status": "closed",
+		"status": "confirmed",
 	}
 	body, _ := json.Marshal(requestBody)
 	req, err := http.NewRequest("Patch", "/appointment/", bytes.NewReader(body))
@@ -200,7 +242,7 @@ func TestUpdateAppointmentStatusSuccess(t *testing.T) {
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "1")
 
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
 
 	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
 	rr := httptest.NewRecorder()
@@ -212,7 +254,7 @@ func TestUpdateAppointmentStatusSuccess(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	expected := "appointment status successfully updated to {closed}"
+	expected := "appointment status successfully updated to {confirmed}"
 	if rr.Body.String() != expected {
 		t.Errorf("handler returned unexpected body: got %v want %v",
 			rr.Body.String(), expected)
@@ -221,7 +263,7 @@ func TestUpdateAppointmentStatusSuccess(t *testing.T) {
 
 func TestBadUpdateAppointmentStatus(t *testing.T) {
 	requestBody := map[string]interface{}{
-		"status": "closed",
+		"status": "confirmed",
 	}
 	body, _ := json.Marshal(requestBody)
 	req, err := http.NewRequest("Patch", "/appointment/", bytes.NewReader(body))
@@ -232,19 +274,19 @@ func TestBadUpdateAppointmentStatus(t *testing.T) {
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "2")
 
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
 
 	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(appointmentsController.UpdateAppointmentStatus)
 	handler.ServeHTTP(rr, req)
 
-	if status := rr.Code; status != http.StatusBadRequest {
+	if status := rr.Code; status != http.StatusNotFound {
 		t.Errorf("handler returned wrong status code: got %v want %v",
-			status, http.StatusOK)
+			status, http.StatusNotFound)
 	}
 
-	expected := "unable to update appointment status at id 2"
+	expected := `{"error":"appointment 2 not found"}`
 	if rr.Body.String() != expected {
 		t.Errorf("handler returned unexpected body: got %v want %v",
 			rr.Body.String(), expected)
@@ -261,7 +303,7 @@ func TestBadGetAppointmentsWithinDateRange(t *testing.T) {
 	rctx.URLParams.Add("start", "2019-07-29T09:00:01+00:00")
 	rctx.URLParams.Add("end", "2019-08-29T09:00:01+00:00")
 
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
 	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
 	rr := httptest.NewRecorder()
 
@@ -273,9 +315,408 @@ func TestBadGetAppointmentsWithinDateRange(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	expected := "request must have valid start and end date range"
+	expected := `{"error":"request must have valid start and end date range"}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestCreateAppointmentUnavailable(t *testing.T) {
+	requestBody := map[string]interface{}{
+		"Name":        "Unavailable",
+		"Description": "even newer engine appointment",
+		"Date":        "2019-08-28T09:00:01+00:00",
+	}
+	body, _ := json.Marshal(requestBody)
+
+	req, err := http.NewRequest("POST", "/appointment", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.CreateAppointment)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusServiceUnavailable)
+	}
+
+	expected := `{"error":"service temporarily unavailable"}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestGetAppointmentInvalidID(t *testing.T) {
+	req, err := http.NewRequest("GET", "/appointment/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "2")
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
+
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.GetAppointment)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	expected := `{"error":"invalid id \"2\""}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestGetAppointmentsWithinDateRangeTimeout(t *testing.T) {
+	req, err := http.NewRequest("GET", "/appointments/range/?start=2019-07-29T09:00:01Z&end=2019-08-29T09:00:01Z", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithDeadline(req.Context(), time.Now().Add(-time.Second))
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.GetAppointmentsWithinDateRange)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusGatewayTimeout {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusGatewayTimeout)
+	}
+
+	expected := `{"error":"request timed out"}`
 	if rr.Body.String() != expected {
 		t.Errorf("handler returned unexpected body: got %v want %v",
 			rr.Body.String(), expected)
 	}
 }
+
+func TestUpdateAppointmentStatusUnknownStatus(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"status": "clsoed"})
+	req, err := http.NewRequest("Patch", "/appointment/", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
+
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.UpdateAppointmentStatus)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	expected := `{"error":"status must be one of open, confirmed, checked_in, in_progress, waiting_parts, completed, picked_up, cancelled, no_show, closed"}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestUpdateAppointmentStatusIllegalTransition(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"status": "open"})
+	req, err := http.NewRequest("Patch", "/appointment/", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "3")
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
+
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.UpdateAppointmentStatus)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusConflict)
+	}
+
+	expected := `{"error":"invalid status transition: appointment 3 cannot move from \"completed\" to \"open\""}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestMergePatchAppointment(t *testing.T) {
+	body := []byte(`{"description": "rotate tires as well", "date": "2019-08-29T10:30:00Z"}`)
+	req, err := http.NewRequest("PATCH", "/appointment/", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
+
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.PatchAppointment)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	expected := `{"id":"000000000000000000000000","name":"Test","description":"rotate tires as well","status":"open","date":"2019-08-29T10:30:00Z"}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestTechnicianMergePatchAppointment(t *testing.T) {
+	body := []byte(`{"description": "rotate tires as well"}`)
+	req, err := http.NewRequest("PATCH", "/appointment/", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	ctx := auth.WithClaims(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx), &auth.Claims{Username: "sam", Role: models.RoleTechnician})
+	req = req.WithContext(ctx)
+
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.PatchAppointment)
+	handler.ServeHTTP(rr, req)
+
+	expected := `{"error":"your role may not edit appointments"}`
+	if rr.Code != http.StatusForbidden || rr.Body.String() != expected {
+		t.Errorf("handler returned %v %v want %v %v", rr.Code, rr.Body.String(), http.StatusForbidden, expected)
+	}
+}
+
+func TestBadMergePatchAppointment(t *testing.T) {
+	tests := map[string]struct {
+		method   string
+		body     string
+		expected string
+	}{
+		"null required field": {"PATCH", `{"name": null}`, `{"error":"appointment must have valid name, description and date values"}`},
+		"status":              {"PATCH", `{"status": "completed"}`, `{"error":"status cannot be changed with this request"}`},
+		"unknown field":       {"PATCH", `{"color": "red"}`, `{"error":"unknown appointment field \"color\""}`},
+		"invalid date":        {"PATCH", `{"date": "tomorrow"}`, `{"error":"invalid value for date"}`},
+		"not an object":       {"PATCH", `["name"]`, `{"error":"request body must be a JSON object"}`},
+		"incomplete put":      {"PUT", `{"name": "Test"}`, `{"error":"appointment must have valid name, description and date values"}`},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, "/appointment/", bytes.NewReader([]byte(test.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
+
+			appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(appointmentsController.UpdateAppointment)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+			if rr.Body.String() != test.expected {
+				t.Errorf("handler returned unexpected body: got %v want %v",
+					rr.Body.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestListAppointments(t *testing.T) {
+	req, err := http.NewRequest("GET", "/appointments?status=open&sort=-date&limit=10", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.ListAppointments)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	expected := `{"appointments":[{"id":"000000000000000000000000","name":"Test","description":"Test Appointment","status":"open","date":"2019-08-28T09:00:01Z"}],"next":"token"}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestBadListAppointments(t *testing.T) {
+	tests := map[string]string{
+		"/appointments?status=clsoed":                                       `{"error":"status must be one of open, confirmed, checked_in, in_progress, waiting_parts, completed, picked_up, cancelled, no_show, closed"}`,
+		"/appointments?sort=price":                                          `{"error":"sort must be one of date, name or status"}`,
+		"/appointments?start=yesterday":                                     `{"error":"start must be an RFC3339 date"}`,
+		"/appointments?customer=1":                                          `{"error":"customer must be a valid id"}`,
+		"/appointments?limit=1000":                                          `{"error":"limit must be a number between 1 and 200"}`,
+		"/appointments?start=2019-08-02T00:00:00Z&end=2019-08-01T00:00:00Z": `{"error":"end must not be before start"}`,
+	}
+	for url, expected := range tests {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appointmentsController := AppointmentsController{DB: &DBTestImplementation{}}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(appointmentsController.ListAppointments)
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%v: handler returned wrong status code: got %v want %v",
+				url, status, http.StatusBadRequest)
+		}
+		if rr.Body.String() != expected {
+			t.Errorf("%v: handler returned unexpected body: got %v want %v",
+				url, rr.Body.String(), expected)
+		}
+	}
+}
+
+func TestCreateAppointmentScheduleConflict(t *testing.T) {
+	cfg := config.Default().Scheduling
+	cfg.Bays = 2
+	scheduler, err := scheduling.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler.Now = func() time.Time { return time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC) }
+
+	requestBody := map[string]interface{}{
+		"Name":        "Ultimate Car Appointment",
+		"Description": "even newer engine appointment",
+		"Date":        "2019-08-28T09:00:01+00:00",
+	}
+	body, _ := json.Marshal(requestBody)
+
+	req, err := http.NewRequest("POST", "/appointment", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}, Scheduler: scheduler}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.CreateAppointment)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusConflict)
+	}
+
+	expected := `{"error":"no service bay is available","suggestions":["2019-08-28T10:30:00Z","2019-08-28T11:00:00Z","2019-08-28T11:30:00Z"]}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestGetAvailability(t *testing.T) {
+	cfg := config.Default().Scheduling
+	cfg.Bays = 2
+	scheduler, err := scheduling.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler.Now = func() time.Time { return time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC) }
+	appointmentsController := AppointmentsController{DB: &DBTestImplementation{}, Scheduler: scheduler}
+
+	req, err := http.NewRequest("GET", "/availability?date=2019-08-28", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(appointmentsController.GetAvailability)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	var response struct {
+		Date     string
+		Duration string
+		Slots    []scheduling.Slot
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	// both bays are busy from 09:00:01 to 10:00:01, so one hour slots from 08:30 to 10:00 are taken
+	if response.Date != "2019-08-28" || response.Duration != "1h0m0s" || len(response.Slots) != 15 {
+		t.Fatalf("handler returned unexpected body: %v", rr.Body.String())
+	}
+	if !response.Slots[1].Start.Equal(time.Date(2019, 8, 28, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("got second slot %v want 10:30", response.Slots[1].Start)
+	}
+}
+
+func TestBadGetAvailability(t *testing.T) {
+	cfg := config.Default().Scheduling
+	cfg.Bays = 2
+	scheduler, err := scheduling.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		scheduler *scheduling.Scheduler
+		query     string
+		status    int
+		expected  string
+	}{
+		"disabled":        {nil, "date=2019-08-28", http.StatusNotImplemented, `{"error":"scheduling is not configured"}`},
+		"missing date":    {scheduler, "", http.StatusBadRequest, `{"error":"date must be given as YYYY-MM-DD"}`},
+		"bad date":        {scheduler, "date=28-08-2019", http.StatusBadRequest, `{"error":"date must be given as YYYY-MM-DD"}`},
+		"unknown service": {scheduler, "date=2019-08-28&service=paint", http.StatusBadRequest, `{"error":"unknown service \"paint\""}`},
+		"bad service ids": {scheduler, "date=2019-08-28&service_ids=oil", http.StatusBadRequest, `{"error":"service_ids must be a comma-separated list of valid ids"}`},
+	}
+	for name, test := range tests {
+		req, err := http.NewRequest("GET", "/availability?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appointmentsController := AppointmentsController{DB: &DBTestImplementation{}, Scheduler: test.scheduler}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(appointmentsController.GetAvailability)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
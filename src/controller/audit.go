@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+)
+
+// auditTimeout - how long recording a change may take once the request that made it has gone
+const auditTimeout = 10 * time.Second
+
+// auditActor - who is making the request ctx belongs to, as recorded in the audit trail
+func auditActor(ctx context.Context) models.Actor {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return models.Actor{Type: models.ActorSystem}
+	}
+	actor := models.Actor{ID: claims.Subject, Name: claims.Username}
+	switch claims.Use {
+	case auth.UseAPIKey:
+		actor.Type = models.ActorAPIKey
+	case auth.UsePortal:
+		actor.Type = models.ActorCustomer
+	default:
+		actor.Type = models.ActorUser
+	}
+	return actor
+}
+
+// appointmentFields - the fields of appointment as they appear in its JSON, leaving out its id and unset fields
+func appointmentFields(appointment *models.Appointment) (map[string]string, error) {
+	fields := map[string]string{}
+	if appointment == nil {
+		return fields, nil
+	}
+	encoded, err := json.Marshal(appointment)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		return nil, err
+	}
+	for field, value := range raw {
+		if field == "id" || bytes.Equal(value, []byte("null")) {
+			continue
+		}
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			text = string(value)
+		}
+		fields[field] = text
+	}
+	return fields, nil
+}
+
+// appointmentChanges - the fields that differ between before and after, sorted by name; either may be nil when
+// the appointment is created or deleted
+func appointmentChanges(before, after *models.Appointment) ([]models.FieldChange, error) {
+	previous, err := appointmentFields(before)
+	if err != nil {
+		return nil, err
+	}
+	current, err := appointmentFields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := []models.FieldChange{}
+	for field, value := range previous {
+		if current[field] != value {
+			changes = append(changes, models.FieldChange{Field: field, Before: value, After: current[field]})
+		}
+	}
+	for field, value := range current {
+		if _, found := previous[field]; !found {
+			changes = append(changes, models.FieldChange{Field: field, After: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+// audit - appends an entry for a change to the appointment from before to after to the audit trail, if there is one.
+// The entry is written even if the client has gone away, since the change has already been made. A failure is only
+// logged: the change is saved either way, and reporting it as failed would have clients retry a request that succeeded.
+func (a *AppointmentsController) audit(ctx context.Context, action string, before, after *models.Appointment) {
+	if a.Audit == nil {
+		return
+	}
+	appointment := after
+	if appointment == nil {
+		appointment = before
+	}
+	changes, err := appointmentChanges(before, after)
+	if err != nil {
+		log.Printf("error diffing %v of appointment %v for audit trail: %v\n", action, appointment.ID.Hex(), err)
+		return
+	}
+	entry := models.AuditEntry{AppointmentID: appointment.ID, Action: action, Actor: auditActor(ctx),
+		RequestID: middleware.GetReqID(ctx), At: time.Now().UTC(), Changes: changes}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+	defer cancel()
+	if _, err := a.Audit.AppendAuditEntry(ctx, entry); err != nil {
+		log.Printf("error recording %v of appointment %v by request %v in audit trail: %v\n", action, appointment.ID.Hex(), entry.RequestID, err)
+	}
+}
+
+// GetAppointmentHistory - accepts appointment id and returns the audit trail of the appointment, oldest change first.
+// The history of a deleted appointment is still returned; an id nothing was ever recorded for is not found.
+func (a *AppointmentsController) GetAppointmentHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	entries := &[]models.AuditEntry{}
+	var err error
+	if a.Audit != nil {
+		entries, err = a.Audit.ListAuditEntries(r.Context(), id)
+	}
+	if err == nil && len(*entries) == 0 {
+		_, err = a.DB.GetAppointment(r.Context(), id)
+	}
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(entries)
+		if err != nil {
+			log.Println("error marshaling audit entries")
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// failingAudit - an audit trail that can't be written to, as when the database is unreachable
+type failingAudit struct {
+	db.AuditStore
+}
+
+func (failingAudit) AppendAuditEntry(context.Context, models.AuditEntry) (*models.AuditEntry, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func TestAppointmentAudit(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	appointmentsController := AppointmentsController{DB: store, Audit: store}
+	date := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+
+	rr := serveWithID(appointmentsController.CreateAppointment, "POST", "", `{"name":"Brakes","description":"squeal","date":"2019-08-26T09:00:00Z"}`)
+	var created models.Appointment
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	id := created.ID.Hex()
+	if rr := serveWithID(appointmentsController.UpdateAppointmentStatus, "PATCH", id, `{"status":"confirmed"}`); rr.Code != http.StatusOK {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+
+	rr = serveWithID(appointmentsController.GetAppointmentHistory, "GET", id, "")
+	var history []models.AuditEntry
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if rr.Code != http.StatusOK || len(history) != 2 || history[0].Actor.Type != models.ActorUser || history[1].Actor.Name != "advisor" {
+		t.Fatalf("got %v %v want the create and status change by the advisor", rr.Code, rr.Body.String())
+	}
+	expected := []models.FieldChange{{Field: "date", After: "2019-08-26T09:00:00Z"}, {Field: "description", After: "squeal"},
+		{Field: "name", After: "Brakes"}, {Field: "status", After: models.StatusOpen}}
+	if changes := history[0].Changes; len(changes) != len(expected) || changes[0] != expected[0] || changes[3] != expected[3] {
+		t.Errorf("got changes %+v want %+v", changes, expected)
+	}
+
+	// the change is recorded even when the client has already gone away
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	after := created
+	after.Name = "Brake pads"
+	appointmentsController.audit(cancelled, models.AuditUpdated, &created, &after)
+	if entries, _ := store.ListAuditEntries(ctx, id); len(*entries) != 3 {
+		t.Errorf("got %+v want the entry for the cancelled request", *entries)
+	}
+
+	failing := AppointmentsController{DB: store, Audit: failingAudit{}}
+	other, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Tires", Description: "rotate", Date: date, Status: models.StatusOpen})
+	rr = serveWithID(failing.UpdateAppointmentStatus, "PATCH", other.ID.Hex(), `{"status":"confirmed"}`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got %v %v want the saved change reported as saved when it can't be recorded", rr.Code, rr.Body.String())
+	}
+	if updated, _ := store.GetAppointment(ctx, other.ID.Hex()); updated.Status != models.StatusConfirmed {
+		t.Errorf("got %+v want the status change saved", updated)
+	}
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/scheduling"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// availabilityResponse - free slots for one service, or one set of catalog items, on one day
+type availabilityResponse struct {
+	Date       string               `json:"date"`
+	Service    string               `json:"service,omitempty"`
+	ServiceIDs []primitive.ObjectID `json:"service_ids,omitempty"`
+	Duration   string               `json:"duration"`
+	Slots      []scheduling.Slot    `json:"slots"`
+}
+
+// GetAvailability - accepts a date (YYYY-MM-DD) and optionally a service or comma-separated catalog service_ids
+// and returns the slots still free that day
+func (a *AppointmentsController) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+	query := models.Appointment{Service: r.URL.Query().Get("service")}
+
+	serviceIDs, idsErr := parseIDList(r.URL.Query(), "service_ids")
+	query.ServiceIDs = serviceIDs
+	if a.Scheduler == nil {
+		status = http.StatusNotImplemented
+		response = errorJSON("scheduling is not configured")
+	} else if day, err := a.Scheduler.ParseDay(r.URL.Query().Get("date")); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("date must be given as YYYY-MM-DD")
+	} else if idsErr != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(idsErr.Error())
+	} else if err := a.resolveServices(r.Context(), &query); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if duration, err := a.Scheduler.AppointmentDuration(query); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else {
+		status, response = a.availability(r, day, query, duration)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// availability - looks up the appointments around day and computes its free slots for an appointment like query lasting duration
+func (a *AppointmentsController) availability(r *http.Request, day time.Time, query models.Appointment, duration time.Duration) (int, []byte) {
+	start, end := a.Scheduler.DayWindow(day)
+	existing, err := a.DB.GetAppointmentsWithinDateRange(r.Context(), start, end)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+
+	response, err := json.Marshal(availabilityResponse{
+		Date:       day.Format("2006-01-02"),
+		Service:    query.Service,
+		ServiceIDs: query.ServiceIDs,
+		Duration:   duration.String(),
+		Slots:      a.Scheduler.Availability(day, duration, *existing),
+	})
+	if err != nil {
+		log.Println("error marshaling availability", err)
+	}
+	return http.StatusOK, response
+}
@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// catalogCode - catalog codes are short lower-case identifiers such as "oil_change"
+var catalogCode = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// CatalogController - struct that has reference to the service catalog store
+type CatalogController struct {
+	DB db.CatalogStore
+}
+
+// validateCatalogItem - returns a description of the first problem with item, or nil if it can be stored
+func validateCatalogItem(item models.CatalogItem) error {
+	switch {
+	case !catalogCode.MatchString(item.Code):
+		return errors.New("code must only contain lower-case letters, digits and underscores")
+	case strings.TrimSpace(item.Name) == "":
+		return errors.New("catalog item must have a name")
+	case item.LaborMinutes <= 0:
+		return errors.New("labor_minutes must be positive")
+	case item.LaborRate < 0:
+		return errors.New("labor_rate must not be negative")
+	}
+	for _, part := range item.Parts {
+		switch {
+		case strings.TrimSpace(part.SKU) == "":
+			return errors.New("parts must have a sku")
+		case part.Quantity <= 0:
+			return fmt.Errorf("quantity of part %v must be positive", part.SKU)
+		case part.UnitPrice < 0:
+			return fmt.Errorf("unit_price of part %v must not be negative", part.SKU)
+		}
+	}
+	return nil
+}
+
+// CreateCatalogItem - accepts a catalog item and returns it with its generated id
+func (c *CatalogController) CreateCatalogItem(w http.ResponseWriter, r *http.Request) {
+	var item models.CatalogItem
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid catalog item")
+	} else if err := validateCatalogItem(item); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if created, err := c.DB.CreateCatalogItem(r.Context(), item); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(created)
+		if err != nil {
+			log.Println("error marshaling catalog item", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// GetCatalogItem - accepts catalog item id and returns the specified item
+func (c *CatalogController) GetCatalogItem(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	item, err := c.DB.GetCatalogItem(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(item)
+		if err != nil {
+			log.Println("error marshaling catalog item", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListCatalogItems - returns the whole service catalog ordered by code
+func (c *CatalogController) ListCatalogItems(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	items, err := c.DB.ListCatalogItems(r.Context())
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(items)
+		if err != nil {
+			log.Println("error marshaling catalog items", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// UpdateCatalogItem - accepts id and either a whole catalog item (PUT) or a JSON Merge Patch of one (PATCH) and returns the updated item.
+// Appointments already booked keep the labor time they were booked with.
+func (c *CatalogController) UpdateCatalogItem(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	item, err := c.DB.GetCatalogItem(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := decodeUpdate(r, item, &item.ID, "catalog item"); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if err := validateCatalogItem(*item); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if updated, err := c.DB.UpdateCatalogItem(r.Context(), *item); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(updated)
+		if err != nil {
+			log.Println("error marshaling catalog item", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// DeleteCatalogItem - accepts catalog item id to be deleted; appointments already booked for it keep their labor time
+func (c *CatalogController) DeleteCatalogItem(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte(fmt.Sprintf("catalog item %v successfully deleted", id))
+
+	if err := c.DB.DeleteCatalogItem(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// laborMinutes - the total standard labor time of the catalog items with the given ids, or errMissingReference if one doesn't exist
+func (a *AppointmentsController) laborMinutes(ctx context.Context, ids []primitive.ObjectID) (int, error) {
+	total := 0
+	for _, id := range ids {
+		item, err := a.Catalog.GetCatalogItem(ctx, id.Hex())
+		if err != nil {
+			return 0, missingReference(err, "catalog item", id.Hex())
+		}
+		total += item.LaborMinutes
+	}
+	return total, nil
+}
+
+// resolveServices - sets the duration of appointment to the labor time of the catalog items it is booked for
+func (a *AppointmentsController) resolveServices(ctx context.Context, appointment *models.Appointment) error {
+	appointment.DurationMinutes = 0
+	if a.Catalog == nil {
+		return nil
+	}
+	minutes, err := a.laborMinutes(ctx, appointment.ServiceIDs)
+	appointment.DurationMinutes = minutes
+	return err
+}
+
+// sameServices - reports whether a and b name the same catalog items in the same order
+func sameServices(a, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
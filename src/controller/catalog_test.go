@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/scheduling"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateCatalogItem(t *testing.T) {
+	store := db.NewMemoryStore()
+	catalogController := CatalogController{DB: store}
+
+	rr := serveWithID(catalogController.CreateCatalogItem, "POST", "",
+		`{"code":"brake_job","name":"Brake job","labor_minutes":120,"labor_rate":"95.00","parts":[{"sku":"BP-100","quantity":2,"unit_price":45.5}]}`)
+	var item models.CatalogItem
+	json.Unmarshal(rr.Body.Bytes(), &item)
+	if rr.Code != http.StatusOK || item.LaborRate != 9500 || item.Parts[0].UnitPrice != 4550 {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+	if item.LaborPrice() != 19000 || item.PartsPrice() != 9100 {
+		t.Errorf("got labor %v parts %v want 190.00 and 91.00", item.LaborPrice(), item.PartsPrice())
+	}
+
+	tests := map[string]struct {
+		body     string
+		status   int
+		expected string
+	}{
+		"duplicate code": {`{"code":"brake_job","name":"Brakes","labor_minutes":60}`,
+			http.StatusConflict, `{"error":"a catalog item with code brake_job already exists: conflict"}`},
+		"bad code": {`{"code":"Brake Job","name":"Brakes","labor_minutes":60}`,
+			http.StatusBadRequest, `{"error":"code must only contain lower-case letters, digits and underscores"}`},
+		"no labor time": {`{"code":"inspection","name":"Inspection"}`,
+			http.StatusBadRequest, `{"error":"labor_minutes must be positive"}`},
+		"fractional cents": {`{"code":"inspection","name":"Inspection","labor_minutes":30,"labor_rate":"95.001"}`,
+			http.StatusBadRequest, `{"error":"request body must be a valid catalog item"}`},
+		"part without quantity": {`{"code":"inspection","name":"Inspection","labor_minutes":30,"parts":[{"sku":"F-1"}]}`,
+			http.StatusBadRequest, `{"error":"quantity of part F-1 must be positive"}`},
+	}
+	for name, test := range tests {
+		rr := serveWithID(catalogController.CreateCatalogItem, "POST", "", test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+
+	rr = serveWithID(catalogController.UpdateCatalogItem, "PATCH", item.ID.Hex(), `{"labor_minutes":90,"parts":null}`)
+	var patched models.CatalogItem
+	json.Unmarshal(rr.Body.Bytes(), &patched)
+	if rr.Code != http.StatusOK || patched.LaborMinutes != 90 || patched.Parts != nil || patched.Code != "brake_job" {
+		t.Errorf("got %v %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAppointmentCatalogDuration(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	cfg := config.Default().Scheduling
+	cfg.Bays = 1
+	scheduler, err := scheduling.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler.Now = func() time.Time { return time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC) }
+	appointmentsController := AppointmentsController{DB: store, Scheduler: scheduler, Catalog: store}
+
+	brakes, _ := store.CreateCatalogItem(ctx, models.CatalogItem{Code: "brake_job", Name: "Brake job", LaborMinutes: 120})
+	oil, _ := store.CreateCatalogItem(ctx, models.CatalogItem{Code: "oil_change", Name: "Oil change", LaborMinutes: 30})
+
+	body := `{"name":"Service","description":"brakes and oil","date":"2019-08-28T09:00:00Z","duration_minutes":5,` +
+		`"service_ids":["` + brakes.ID.Hex() + `","` + oil.ID.Hex() + `"]}`
+	rr := serveWithID(appointmentsController.CreateAppointment, "POST", "", body)
+	var appointment models.Appointment
+	json.Unmarshal(rr.Body.Bytes(), &appointment)
+	if rr.Code != http.StatusOK || appointment.DurationMinutes != 150 {
+		t.Fatalf("got %v %v want the labor time of both services", rr.Code, rr.Body.String())
+	}
+
+	// the only bay is taken until 11:30
+	body = `{"name":"Service","description":"oil","date":"2019-08-28T11:00:00Z","service_ids":["` + oil.ID.Hex() + `"]}`
+	if rr := serveWithID(appointmentsController.CreateAppointment, "POST", "", body); rr.Code != http.StatusConflict {
+		t.Errorf("got %v %v want %v", rr.Code, rr.Body.String(), http.StatusConflict)
+	}
+	body = `{"name":"Service","description":"oil","date":"2019-08-28T11:30:00Z","service_ids":["5d66f16e7c0e4a5d3c9f1a2b"]}`
+	rr = serveWithID(appointmentsController.CreateAppointment, "POST", "", body)
+	if expected := `{"error":"catalog item 5d66f16e7c0e4a5d3c9f1a2b does not exist"}`; rr.Code != http.StatusBadRequest || rr.Body.String() != expected {
+		t.Errorf("got %v %v want %v", rr.Code, rr.Body.String(), expected)
+	}
+
+	rr = serveWithID(appointmentsController.UpdateAppointment, "PUT", appointment.ID.Hex(),
+		`{"name":"Service","description":"oil only","date":"2019-08-28T09:00:00Z","service_ids":["`+oil.ID.Hex()+`"]}`)
+	json.Unmarshal(rr.Body.Bytes(), &appointment)
+	if rr.Code != http.StatusOK || appointment.DurationMinutes != 30 {
+		t.Errorf("got %v %v want the appointment shortened to 30 minutes", rr.Code, rr.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/availability?date=2019-08-28&service_ids="+brakes.ID.Hex(), nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(appointmentsController.GetAvailability).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"duration":"2h0m0s"`) {
+		t.Errorf("got %v %v want slots for a two hour job", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// CustomersController - struct that has references to the customer store and the appointments and vehicles that point at customers
+type CustomersController struct {
+	DB db.CustomerStore
+	// Appointments and Vehicles - consulted so customers who still have appointments or vehicles can't be deleted
+	Appointments db.ClientInterface
+	Vehicles     db.VehicleStore
+}
+
+// validateCustomer - returns a description of the first problem with customer, or nil if it can be stored
+func validateCustomer(customer models.Customer) error {
+	if strings.TrimSpace(customer.Name) == "" {
+		return errors.New("customer must have a name")
+	}
+	for _, phone := range customer.Phones {
+		if strings.TrimSpace(phone) == "" {
+			return errors.New("phone numbers must not be empty")
+		}
+	}
+	for _, email := range customer.Emails {
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			return fmt.Errorf("%q is not a valid email address", email)
+		}
+	}
+
+	switch customer.PreferredContact {
+	case "":
+	case models.ContactPhone, models.ContactSMS:
+		if len(customer.Phones) == 0 {
+			return fmt.Errorf("a phone number is needed to be contacted by %v", customer.PreferredContact)
+		}
+	case models.ContactEmail:
+		if len(customer.Emails) == 0 {
+			return errors.New("an email address is needed to be contacted by email")
+		}
+	case models.ContactMail:
+		if customer.Address == nil {
+			return errors.New("an address is needed to be contacted by mail")
+		}
+	default:
+		return fmt.Errorf("preferred_contact must be one of %v", strings.Join(models.ContactChannels(), ", "))
+	}
+	return nil
+}
+
+// CreateCustomer - accepts a customer and returns it with its generated id
+func (c *CustomersController) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var customer models.Customer
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&customer)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid customer")
+	} else if err := validateCustomer(customer); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if created, err := c.DB.CreateCustomer(r.Context(), customer); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(created)
+		if err != nil {
+			log.Println("error marshaling customer", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// GetCustomer - accepts customer id and returns the specified customer
+func (c *CustomersController) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	customer, err := c.DB.GetCustomer(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(customer)
+		if err != nil {
+			log.Println("error marshaling customer", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListCustomers - returns every customer matching the name, phone and email query parameters, ordered by name
+func (c *CustomersController) ListCustomers(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+	values := r.URL.Query()
+	filter := models.CustomerFilter{
+		Name:  values.Get("name"),
+		Phone: values.Get("phone"),
+		Email: values.Get("email"),
+	}
+
+	customers, err := c.DB.ListCustomers(r.Context(), filter)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(customers)
+		if err != nil {
+			log.Println("error marshaling customers", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// UpdateCustomer - accepts id and either a whole customer (PUT) or a JSON Merge Patch of one (PATCH) and returns the updated customer
+func (c *CustomersController) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	customer, err := c.DB.GetCustomer(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := decodeUpdate(r, customer, &customer.ID, "customer"); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if err := validateCustomer(*customer); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if updated, err := c.DB.UpdateCustomer(r.Context(), *customer); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(updated)
+		if err != nil {
+			log.Println("error marshaling customer", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// DeleteCustomer - accepts customer id to be deleted; customers who still have appointments or vehicles are kept
+func (c *CustomersController) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte(fmt.Sprintf("customer %v successfully deleted", id))
+
+	if err := c.checkUnreferenced(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := c.DB.DeleteCustomer(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// checkUnreferenced - returns db.ErrConflict if any appointment or vehicle references the customer with the given id
+func (c *CustomersController) checkUnreferenced(ctx context.Context, id string) error {
+	customer, err := c.DB.GetCustomer(ctx, id)
+	if err != nil {
+		return err
+	}
+	page, err := c.Appointments.ListAppointments(ctx, models.AppointmentQuery{
+		Filter: models.AppointmentFilter{CustomerID: &customer.ID},
+		Limit:  1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(page.Appointments) != 0 {
+		return fmt.Errorf("customer %v still has appointments: %w", id, db.ErrConflict)
+	}
+	vehicles, err := c.Vehicles.ListVehicles(ctx, models.VehicleFilter{CustomerID: &customer.ID})
+	if err != nil {
+		return err
+	}
+	if len(*vehicles) != 0 {
+		return fmt.Errorf("customer %v still owns vehicles: %w", id, db.ErrConflict)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// signedIn - ctx carrying the claims Authenticate adds for a signed in advisor, whom test requests come from
+func signedIn(ctx context.Context) context.Context {
+	return auth.WithClaims(ctx, &auth.Claims{Subject: "000000000000000000000001", Username: "advisor", Role: models.RoleAdvisor, Use: auth.UseAccess})
+}
+
+// serveWithID - sends a request with the given id URL parameter to handler and returns the recorded response
+func serveWithID(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+	return serveWithParam(handler, method, "id", id, body)
+}
+
+// serveWithParam - sends a request with the URL parameter key set to value to handler and returns the recorded response
+func serveWithParam(handler http.HandlerFunc, method, key, value, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/"+value, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	req = req.WithContext(context.WithValue(signedIn(req.Context()), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCreateCustomer(t *testing.T) {
+	store := db.NewMemoryStore()
+	customersController := CustomersController{DB: store, Appointments: store}
+
+	tests := map[string]struct {
+		body     string
+		status   int
+		expected string
+	}{
+		"valid":              {`{"name":"Jane Doe","emails":["jane@example.com"],"preferred_contact":"email"}`, http.StatusOK, ""},
+		"missing name":       {`{"phones":["555-0100"]}`, http.StatusBadRequest, `{"error":"customer must have a name"}`},
+		"bad email":          {`{"name":"Jane Doe","emails":["jane"]}`, http.StatusBadRequest, `{"error":"\"jane\" is not a valid email address"}`},
+		"unknown channel":    {`{"name":"Jane Doe","preferred_contact":"pigeon"}`, http.StatusBadRequest, `{"error":"preferred_contact must be one of phone, sms, email, mail"}`},
+		"unreachable by sms": {`{"name":"Jane Doe","preferred_contact":"sms"}`, http.StatusBadRequest, `{"error":"a phone number is needed to be contacted by sms"}`},
+		"not json":           {`Jane Doe`, http.StatusBadRequest, `{"error":"request body must be a valid customer"}`},
+	}
+	for name, test := range tests {
+		rr := serveWithID(customersController.CreateCustomer, "POST", "", test.body)
+		if rr.Code != test.status || test.expected != "" && rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
+
+func TestUpdateCustomer(t *testing.T) {
+	store := db.NewMemoryStore()
+	customersController := CustomersController{DB: store, Appointments: store}
+	customer, err := store.CreateCustomer(context.Background(), models.Customer{
+		Name:    "Jane Doe",
+		Phones:  []string{"555-0100"},
+		Address: &models.Address{Street: "1 Main St", City: "Springfield"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := customer.ID.Hex()
+
+	rr := serveWithID(customersController.UpdateCustomer, "PATCH", id, `{"address":{"street":null,"state":"IL"},"notes":"call after 5pm"}`)
+	var updated models.Customer
+	json.Unmarshal(rr.Body.Bytes(), &updated)
+	if rr.Code != http.StatusOK || updated.Name != "Jane Doe" || updated.Notes != "call after 5pm" ||
+		updated.Address == nil || updated.Address.Street != "" || updated.Address.City != "Springfield" || updated.Address.State != "IL" {
+		t.Fatalf("merge patch returned %v %v", rr.Code, rr.Body.String())
+	}
+
+	rr = serveWithID(customersController.UpdateCustomer, "PUT", id, `{"name":"Jane Roe"}`)
+	var replaced models.Customer
+	json.Unmarshal(rr.Body.Bytes(), &replaced)
+	if rr.Code != http.StatusOK || replaced.ID != customer.ID || replaced.Name != "Jane Roe" || replaced.Address != nil || len(replaced.Phones) != 0 {
+		t.Fatalf("put returned %v %v", rr.Code, rr.Body.String())
+	}
+
+	tests := map[string]struct {
+		method   string
+		body     string
+		expected string
+	}{
+		"patch id":      {"PATCH", `{"id":"5d66f16e7c0e4a5d3c9f1a2b"}`, `{"error":"id cannot be changed with this request"}`},
+		"unknown field": {"PATCH", `{"age":40}`, `{"error":"unknown field \"age\""}`},
+		"remove name":   {"PATCH", `{"name":null}`, `{"error":"customer must have a name"}`},
+		"put id":        {"PUT", `{"id":"5d66f16e7c0e4a5d3c9f1a2b","name":"Jane Roe"}`, `{"error":"id cannot be changed with this request"}`},
+	}
+	for name, test := range tests {
+		rr := serveWithID(customersController.UpdateCustomer, test.method, id, test.body)
+		if rr.Code != http.StatusBadRequest || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), http.StatusBadRequest, test.expected)
+		}
+	}
+}
+
+func TestDeleteCustomerWithAppointments(t *testing.T) {
+	store := db.NewMemoryStore()
+	customersController := CustomersController{DB: store, Appointments: store, Vehicles: store}
+	ctx := context.Background()
+	customer, err := store.CreateCustomer(ctx, models.Customer{Name: "Jane Doe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointment, err := store.CreateAppointment(ctx, models.Appointment{Name: "Oil change", Date: time.Now(), CustomerID: &customer.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := customer.ID.Hex()
+
+	rr := serveWithID(customersController.DeleteCustomer, "DELETE", id, "")
+	if rr.Code != http.StatusConflict {
+		t.Errorf("got %v %v want %v", rr.Code, rr.Body.String(), http.StatusConflict)
+	}
+
+	if _, err := store.DeleteAppointment(ctx, appointment.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	rr = serveWithID(customersController.DeleteCustomer, "DELETE", id, "")
+	if rr.Code != http.StatusOK || rr.Body.String() != "customer "+id+" successfully deleted" {
+		t.Errorf("got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+	rr = serveWithID(customersController.DeleteCustomer, "DELETE", id, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got %v %v want %v", rr.Code, rr.Body.String(), http.StatusNotFound)
+	}
+}
+
+func TestCreateAppointmentForUnknownCustomer(t *testing.T) {
+	store := db.NewMemoryStore()
+	appointmentsController := AppointmentsController{DB: store, Customers: store}
+	customer, err := store.CreateCustomer(context.Background(), models.Customer{Name: "Jane Doe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for customerID, expected := range map[string]int{
+		customer.ID.Hex():          http.StatusOK,
+		"5d66f16e7c0e4a5d3c9f1a2b": http.StatusBadRequest,
+	} {
+		body := `{"name":"Oil change","description":"5W-30","date":"2019-08-28T09:00:00Z","customer_id":"` + customerID + `"}`
+		req := httptest.NewRequest("POST", "/appointment/", bytes.NewReader([]byte(body)))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(appointmentsController.CreateAppointment).ServeHTTP(rr, req)
+		if rr.Code != expected {
+			t.Errorf("customer %v: got %v %v want %v", customerID, rr.Code, rr.Body.String(), expected)
+		}
+	}
+}
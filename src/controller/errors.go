@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/vin"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// errorResponse - JSON body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// errorJSON - marshals message into an errorResponse body
+func errorJSON(message string) []byte {
+	response, err := json.Marshal(errorResponse{Error: message})
+	if err != nil {
+		log.Println("error marshaling error response", err)
+	}
+	return response
+}
+
+// dbErrorResponse - maps an error returned by the db package to an http status and JSON body
+func dbErrorResponse(err error) (int, []byte) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound, errorJSON(err.Error())
+	case errors.Is(err, db.ErrInvalidID), errors.Is(err, db.ErrInvalidQuery), errors.Is(err, errMissingReference), errors.Is(err, vin.ErrInvalidVIN):
+		return http.StatusBadRequest, errorJSON(err.Error())
+	case errors.Is(err, db.ErrConflict), errors.Is(err, db.ErrInvalidTransition):
+		return http.StatusConflict, errorJSON(err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Println("db request timed out:", err)
+		return http.StatusGatewayTimeout, errorJSON("request timed out")
+	case errors.Is(err, context.Canceled):
+		log.Println("db request canceled:", err)
+		return http.StatusServiceUnavailable, errorJSON("request canceled")
+	case errors.Is(err, db.ErrUnavailable):
+		log.Println("db unavailable:", err)
+		return http.StatusServiceUnavailable, errorJSON("service temporarily unavailable")
+	default:
+		log.Println("unexpected db error:", err)
+		return http.StatusInternalServerError, errorJSON("internal server error")
+	}
+}
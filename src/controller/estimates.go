@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/money"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EstimatesController - struct that has references to the estimate store and the records estimates are built from
+type EstimatesController struct {
+	DB           db.EstimateStore
+	Appointments db.ClientInterface
+	// Catalog - optional; when set, new estimates get lines for the catalog items their appointment was booked for
+	Catalog db.CatalogStore
+}
+
+// estimateResponse - an estimate together with its totals
+type estimateResponse struct {
+	models.Estimate
+	Total         money.Amount `json:"total"`
+	ApprovedTotal money.Amount `json:"approved_total"`
+}
+
+// newEstimateRequest - body of a request to estimate an appointment's work
+type newEstimateRequest struct {
+	AppointmentID primitive.ObjectID     `json:"appointment_id"`
+	Lines         []models.WorkOrderLine `json:"lines"`
+}
+
+// approvalRequest - body of a request recording the customer's decision on an estimate. Decision applies to every
+// line not given its own approval state in Lines, which is keyed by line id.
+type approvalRequest struct {
+	Approver string            `json:"approver"`
+	Decision string            `json:"decision"`
+	Lines    map[string]string `json:"lines"`
+}
+
+// estimateStatuses - the appointment statuses an estimate can be made in
+var estimateStatuses = []string{models.StatusOpen, models.StatusConfirmed, models.StatusCheckedIn, models.StatusInProgress, models.StatusWaitingParts}
+
+// validDecision - reports whether decision approves or declines a line
+func validDecision(decision string) bool {
+	return decision == models.ApprovalApproved || decision == models.ApprovalDeclined
+}
+
+// marshalEstimate - the status and body of a successful response carrying estimate and its totals
+func marshalEstimate(estimate *models.Estimate) (int, []byte) {
+	response, err := json.Marshal(estimateResponse{
+		Estimate:      *estimate,
+		Total:         estimate.Total("", ""),
+		ApprovedTotal: estimate.Total("", models.ApprovalApproved),
+	})
+	if err != nil {
+		log.Println("error marshaling estimate", err)
+	}
+	return http.StatusOK, response
+}
+
+// CreateEstimate - accepts an appointment id and optional extra lines, and returns a pending estimate with lines for
+// the labor and default parts of every catalog item the appointment was booked for followed by the extra lines
+func (e *EstimatesController) CreateEstimate(w http.ResponseWriter, r *http.Request) {
+	var request newEstimateRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.AppointmentID.IsZero() {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must contain a valid appointment_id")
+	} else {
+		status, response = e.createEstimate(r.Context(), request)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// createEstimate - builds and stores the estimate for the requested appointment, and returns the status and body to respond with
+func (e *EstimatesController) createEstimate(ctx context.Context, request newEstimateRequest) (int, []byte) {
+	for _, line := range request.Lines {
+		if err := validateWorkOrderLine(line); err != nil {
+			return http.StatusBadRequest, errorJSON(err.Error())
+		}
+	}
+	appointmentID := request.AppointmentID.Hex()
+	appointment, err := e.Appointments.GetAppointment(ctx, appointmentID)
+	if err != nil {
+		return dbErrorResponse(missingReference(err, "appointment", appointmentID))
+	}
+	if !containsStatus(estimateStatuses, appointment.Status) {
+		return http.StatusConflict, errorJSON(fmt.Sprintf("appointment %v is %v; estimates can only be made for appointments that are %v",
+			appointmentID, appointment.Status, strings.Join(estimateStatuses, ", ")))
+	}
+
+	lines := []models.WorkOrderLine{}
+	if e.Catalog != nil {
+		for _, id := range appointment.ServiceIDs {
+			item, err := e.Catalog.GetCatalogItem(ctx, id.Hex())
+			if err != nil {
+				return dbErrorResponse(missingReference(err, "catalog item", id.Hex()))
+			}
+			lines = append(lines, catalogLines(*item)...)
+		}
+	}
+	for _, line := range request.Lines {
+		line.ID = primitive.NewObjectID()
+		line.Reserved = false
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return http.StatusBadRequest, errorJSON("estimate must have at least one line")
+	}
+
+	now := time.Now().UTC()
+	estimate := models.Estimate{
+		AppointmentID: appointment.ID,
+		CustomerID:    appointment.CustomerID,
+		VehicleID:     appointment.VehicleID,
+		Status:        models.ApprovalPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	for _, line := range lines {
+		estimate.Lines = append(estimate.Lines, models.EstimateLine{WorkOrderLine: line, Approval: models.ApprovalPending})
+	}
+
+	created, err := e.DB.CreateEstimate(ctx, estimate)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	return marshalEstimate(created)
+}
+
+// GetEstimate - accepts estimate id and returns the specified estimate with its totals
+func (e *EstimatesController) GetEstimate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	estimate, err := e.DB.GetEstimate(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		status, response = marshalEstimate(estimate)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListEstimates - returns the estimates matching the appointment and status query parameters, oldest first
+func (e *EstimatesController) ListEstimates(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	appointmentID, err := parseIDParam(r.URL.Query(), "appointment")
+	filter := models.EstimateFilter{AppointmentID: appointmentID, Status: r.URL.Query().Get("status")}
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if estimates, err := e.DB.ListEstimates(r.Context(), filter); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(estimates)
+		if err != nil {
+			log.Println("error marshaling estimates", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ApproveEstimate - accepts who is deciding for the customer and their decision on every line of a pending estimate,
+// records it with the current time and returns the decided estimate. An estimate can only be decided once.
+func (e *EstimatesController) ApproveEstimate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var request approvalRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid approval")
+	} else if err := validateApproval(request); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if estimate, err := e.DB.GetEstimate(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := decideEstimate(estimate, request); errors.Is(err, db.ErrConflict) {
+		status, response = dbErrorResponse(err)
+	} else if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if updated, err := e.DB.UpdateEstimate(r.Context(), *estimate); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		status, response = marshalEstimate(updated)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// validateApproval - returns a description of the first problem with request that doesn't depend on the estimate, or nil
+func validateApproval(request approvalRequest) error {
+	if strings.TrimSpace(request.Approver) == "" {
+		return errors.New("approval must name its approver")
+	}
+	if request.Decision != "" && !validDecision(request.Decision) {
+		return fmt.Errorf("decision must be %v or %v", models.ApprovalApproved, models.ApprovalDeclined)
+	}
+	for lineID, decision := range request.Lines {
+		if !validDecision(decision) {
+			return fmt.Errorf("decision for line %v must be %v or %v", lineID, models.ApprovalApproved, models.ApprovalDeclined)
+		}
+	}
+	return nil
+}
+
+// decideEstimate - applies request to the lines of estimate and records who decided and when. Returns db.ErrConflict
+// if estimate was already decided, and an error describing the problem if request leaves a line undecided.
+func decideEstimate(estimate *models.Estimate, request approvalRequest) error {
+	if estimate.Status != models.ApprovalPending {
+		return fmt.Errorf("estimate %v is already %v: %w", estimate.ID.Hex(), estimate.Status, db.ErrConflict)
+	}
+	decided := 0
+	for i := range estimate.Lines {
+		line := &estimate.Lines[i]
+		if decision, ok := request.Lines[line.ID.Hex()]; ok {
+			line.Approval = decision
+			decided++
+		} else if request.Decision != "" {
+			line.Approval = request.Decision
+		}
+	}
+	if decided != len(request.Lines) {
+		return fmt.Errorf("lines must be ids of lines on estimate %v", estimate.ID.Hex())
+	}
+	if estimate.Status = estimate.ApprovalStatus(); estimate.Status == models.ApprovalPending {
+		return errors.New("every line must be approved or declined")
+	}
+	now := time.Now().UTC()
+	estimate.Approver = strings.TrimSpace(request.Approver)
+	estimate.DecidedAt = &now
+	estimate.UpdatedAt = now
+	return nil
+}
+
+// checkEstimate - returns db.ErrConflict if the appointment with the given id could otherwise move to newStatus, but
+// work would start without an approved estimate
+func (a *AppointmentsController) checkEstimate(ctx context.Context, appointmentID, newStatus string) error {
+	if a.Estimates == nil || newStatus != models.StatusInProgress {
+		return nil
+	}
+	appointment, err := a.DB.GetAppointment(ctx, appointmentID)
+	if err != nil {
+		return err
+	}
+	// leave disallowed transitions for the status update to report
+	if !models.CanTransition(appointment.Status, newStatus) {
+		return nil
+	}
+	estimates, err := a.Estimates.ListEstimates(ctx, models.EstimateFilter{AppointmentID: &appointment.ID})
+	if err != nil {
+		return err
+	}
+	for _, estimate := range *estimates {
+		if estimate.Approved() {
+			return nil
+		}
+	}
+	return fmt.Errorf("appointment %v has no approved estimate: %w", appointmentID, db.ErrConflict)
+}
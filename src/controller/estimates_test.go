@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// decodeEstimate - unmarshals an estimate response body
+func decodeEstimate(t *testing.T, rr *httptest.ResponseRecorder) estimateResponse {
+	t.Helper()
+	var estimate estimateResponse
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &estimate); err != nil {
+		t.Fatal(err)
+	}
+	return estimate
+}
+
+func TestCreateEstimate(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	estimatesController := EstimatesController{DB: store, Appointments: store, Catalog: store}
+	date := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+
+	item, _ := store.CreateCatalogItem(ctx, models.CatalogItem{Code: "brake_job", Name: "Brake job", LaborMinutes: 90, LaborRate: 9500,
+		Parts: []models.CatalogPart{{SKU: "BP-100", Quantity: 2, UnitPrice: 4550}}})
+	appointment, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Date: date,
+		Status: models.StatusConfirmed, ServiceIDs: []primitive.ObjectID{item.ID}})
+	cancelled, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Date: date, Status: models.StatusCancelled})
+
+	body := `{"appointment_id":"` + appointment.ID.Hex() + `","lines":[{"type":"part","sku":"W-1","description":"wiper blades","quantity":2,"unit_price":"12.00"}]}`
+	estimate := decodeEstimate(t, serveWithID(estimatesController.CreateEstimate, "POST", "", body))
+	if len(estimate.Lines) != 3 || estimate.Lines[2].SKU != "W-1" || estimate.Lines[0].Approval != models.ApprovalPending {
+		t.Errorf("got lines %+v want the catalog item's lines and the wiper blades, pending", estimate.Lines)
+	}
+	if estimate.Status != models.ApprovalPending || estimate.Total != 25750 || estimate.ApprovedTotal != 0 {
+		t.Errorf("got status %v total %v approved %v want pending 257.50 with nothing approved", estimate.Status, estimate.Total, estimate.ApprovedTotal)
+	}
+
+	tests := map[string]struct {
+		body     string
+		status   int
+		expected string
+	}{
+		"no appointment": {`{}`, http.StatusBadRequest, `{"error":"request body must contain a valid appointment_id"}`},
+		"invalid line": {`{"appointment_id":"` + appointment.ID.Hex() + `","lines":[{"type":"part","quantity":1}]}`,
+			http.StatusBadRequest, `{"error":"part lines must have a sku"}`},
+		"closed appointment": {`{"appointment_id":"` + cancelled.ID.Hex() + `"}`, http.StatusConflict,
+			`{"error":"appointment ` + cancelled.ID.Hex() + ` is cancelled; estimates can only be made for appointments that are open, confirmed, checked_in, in_progress, waiting_parts"}`},
+	}
+	for name, test := range tests {
+		rr := serveWithID(estimatesController.CreateEstimate, "POST", "", test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
+
+func TestEstimateApproval(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	estimatesController := EstimatesController{DB: store, Appointments: store}
+	appointmentsController := AppointmentsController{DB: store, Estimates: store}
+	date := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+
+	appointment, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Date: date, Status: models.StatusCheckedIn})
+	id := appointment.ID.Hex()
+	body := `{"appointment_id":"` + id + `","lines":[{"type":"labor","description":"Brake job","minutes":90,"rate":"95.00"},` +
+		`{"type":"part","sku":"W-1","quantity":2,"unit_price":"12.00"}]}`
+	declined := decodeEstimate(t, serveWithID(estimatesController.CreateEstimate, "POST", "", body))
+	decodeEstimate(t, serveWithID(estimatesController.ApproveEstimate, "POST", declined.ID.Hex(), `{"approver":"Pat Lee","decision":"declined"}`))
+
+	rr := serveWithID(appointmentsController.UpdateAppointmentStatus, "PATCH", id, `{"status":"in_progress"}`)
+	if rr.Code != http.StatusConflict || rr.Body.String() != `{"error":"appointment `+id+` has no approved estimate: conflict"}` {
+		t.Errorf("got %v %v want work blocked by the declined estimate", rr.Code, rr.Body.String())
+	}
+
+	revised := decodeEstimate(t, serveWithID(estimatesController.CreateEstimate, "POST", "", body))
+	estimateID := revised.ID.Hex()
+	tests := map[string]struct {
+		body     string
+		status   int
+		expected string
+	}{
+		"no approver":      {`{"decision":"approved"}`, http.StatusBadRequest, `{"error":"approval must name its approver"}`},
+		"unknown decision": {`{"approver":"Pat Lee","decision":"maybe"}`, http.StatusBadRequest, `{"error":"decision must be approved or declined"}`},
+		"line left pending": {`{"approver":"Pat Lee","lines":{"` + revised.Lines[0].ID.Hex() + `":"approved"}}`,
+			http.StatusBadRequest, `{"error":"every line must be approved or declined"}`},
+		"unknown line": {`{"approver":"Pat Lee","decision":"approved","lines":{"` + estimateID + `":"approved"}}`,
+			http.StatusBadRequest, `{"error":"lines must be ids of lines on estimate ` + estimateID + `"}`},
+	}
+	for name, test := range tests {
+		rr := serveWithID(estimatesController.ApproveEstimate, "POST", estimateID, test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+
+	approval := `{"approver":"Pat Lee (phone)","decision":"approved","lines":{"` + revised.Lines[1].ID.Hex() + `":"declined"}}`
+	estimate := decodeEstimate(t, serveWithID(estimatesController.ApproveEstimate, "POST", estimateID, approval))
+	if estimate.Status != models.ApprovalPartiallyApproved || estimate.Approver != "Pat Lee (phone)" || estimate.DecidedAt == nil {
+		t.Errorf("got %+v want partially approved by Pat Lee", estimate.Estimate)
+	}
+	if estimate.Lines[1].Approval != models.ApprovalDeclined || estimate.ApprovedTotal != 14250 || estimate.Total != 16650 {
+		t.Errorf("got lines %+v approved total %v want only the labor approved", estimate.Lines, estimate.ApprovedTotal)
+	}
+	rr = serveWithID(estimatesController.ApproveEstimate, "POST", estimateID, `{"approver":"Pat Lee","decision":"declined"}`)
+	if rr.Code != http.StatusConflict || rr.Body.String() != `{"error":"estimate `+estimateID+` is already partially_approved: conflict"}` {
+		t.Errorf("got %v %v want a decided estimate left unchanged", rr.Code, rr.Body.String())
+	}
+
+	if rr := serveWithID(appointmentsController.UpdateAppointmentStatus, "PATCH", id, `{"status":"in_progress"}`); rr.Code != http.StatusOK {
+		t.Errorf("got %v %v want work allowed to start", rr.Code, rr.Body.String())
+	}
+}
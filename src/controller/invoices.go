@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"CarServiceCenter/src/billing"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvoicesController - struct that has references to the invoice store, the work orders invoices are issued for and the shop's billing settings
+type InvoicesController struct {
+	DB         db.InvoiceStore
+	WorkOrders db.WorkOrderStore
+	Billing    *billing.Billing
+}
+
+// newInvoiceRequest - body of a request to invoice a work order
+type newInvoiceRequest struct {
+	WorkOrderID primitive.ObjectID `json:"work_order_id"`
+	Coupon      string             `json:"coupon"`
+	Discounts   []billing.Discount `json:"discounts"`
+}
+
+// invoicedStatuses - the work order statuses an invoice can be issued in
+var invoicedStatuses = []string{models.StatusCompleted, models.StatusPickedUp}
+
+// CreateInvoice - accepts a completed work order's id with an optional coupon code and discounts, and returns the invoice issued for it
+func (i *InvoicesController) CreateInvoice(w http.ResponseWriter, r *http.Request) {
+	var request newInvoiceRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.WorkOrderID.IsZero() {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must contain a valid work_order_id")
+	} else {
+		status, response = i.createInvoice(r.Context(), request)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// createInvoice - prices and stores the invoice for the requested work order, and returns the status and body to respond with
+func (i *InvoicesController) createInvoice(ctx context.Context, request newInvoiceRequest) (int, []byte) {
+	order, err := i.WorkOrders.GetWorkOrder(ctx, request.WorkOrderID.Hex())
+	if err != nil {
+		return dbErrorResponse(missingReference(err, "work order", request.WorkOrderID.Hex()))
+	}
+	if !containsStatus(invoicedStatuses, order.Status) {
+		return http.StatusConflict, errorJSON(fmt.Sprintf("work order %v is %v; only work orders that are %v can be invoiced",
+			order.ID.Hex(), order.Status, strings.Join(invoicedStatuses, ", ")))
+	}
+
+	// Invoice only fails on unknown coupons and invalid discounts
+	invoice, err := i.Billing.Invoice(*order, request.Coupon, request.Discounts)
+	if err != nil {
+		return http.StatusBadRequest, errorJSON(err.Error())
+	}
+	created, err := i.DB.CreateInvoice(ctx, invoice, i.Billing.Number)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	response, err := json.Marshal(created)
+	if err != nil {
+		log.Println("error marshaling invoice", err)
+	}
+	return http.StatusOK, response
+}
+
+// GetInvoice - accepts invoice id and returns the specified invoice, as a printable HTML page when the client
+// accepts text/html or format=html is given and as JSON otherwise
+func (i *InvoicesController) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+	contentType := "application/json"
+
+	invoice, err := i.DB.GetInvoice(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if wantsHTML(r) {
+		var page bytes.Buffer
+		if err := i.Billing.RenderHTML(&page, *invoice); err != nil {
+			log.Println("error rendering invoice", err)
+			status = http.StatusInternalServerError
+			response = errorJSON("internal server error")
+		} else {
+			contentType = "text/html; charset=utf-8"
+			response = page.Bytes()
+		}
+	} else {
+		response, err = json.Marshal(invoice)
+		if err != nil {
+			log.Println("error marshaling invoice", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// wantsHTML - reports whether r asks for HTML through the format query parameter or, failing that, its Accept header
+func wantsHTML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "html"
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accepted); err == nil && mediaType == "text/html" {
+			return true
+		}
+	}
+	return false
+}
+
+// ListInvoices - returns the invoices matching the work_order and customer query parameters, oldest first
+func (i *InvoicesController) ListInvoices(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	workOrderID, workOrderErr := parseIDParam(r.URL.Query(), "work_order")
+	customerID, customerErr := parseIDParam(r.URL.Query(), "customer")
+	if workOrderErr != nil || customerErr != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("work_order and customer must be valid ids")
+	} else if invoices, err := i.DB.ListInvoices(r.Context(), models.InvoiceFilter{WorkOrderID: workOrderID, CustomerID: customerID}); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(invoices)
+		if err != nil {
+			log.Println("error marshaling invoices", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"CarServiceCenter/src/billing"
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/money"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateInvoice(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	invoicesController := InvoicesController{DB: store, WorkOrders: store, Billing: billing.New(config.BillingConfig{
+		InvoicePrefix: "INV-",
+		TaxRates:      map[string]money.Rate{models.LinePart: money.Percent(8)},
+		Coupons:       map[string]config.Coupon{"SAVE10": {Percent: money.Percent(10)}},
+	})}
+
+	lines := []models.WorkOrderLine{{Type: models.LinePart, SKU: "F-1", Quantity: 2, UnitPrice: 1000}}
+	completed, _ := store.CreateWorkOrder(ctx, models.WorkOrder{AppointmentID: primitive.NewObjectID(), Status: models.StatusCompleted, Lines: lines})
+	inProgress, _ := store.CreateWorkOrder(ctx, models.WorkOrder{AppointmentID: primitive.NewObjectID(), Status: models.StatusInProgress, Lines: lines})
+
+	rr := serveWithID(invoicesController.CreateInvoice, "POST", "", `{"work_order_id":"`+completed.ID.Hex()+`","coupon":"save10"}`)
+	var invoice models.Invoice
+	json.Unmarshal(rr.Body.Bytes(), &invoice)
+	// 20.00 less 10% is 18.00, plus 8% tax
+	if rr.Code != http.StatusOK || invoice.Number != "INV-000001" || invoice.DiscountTotal != 200 || invoice.Total != 1944 {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+
+	tests := map[string]struct {
+		body     string
+		status   int
+		expected string
+	}{
+		"no work order": {`{}`, http.StatusBadRequest, `{"error":"request body must contain a valid work_order_id"}`},
+		"not completed": {`{"work_order_id":"` + inProgress.ID.Hex() + `"}`, http.StatusConflict,
+			`{"error":"work order ` + inProgress.ID.Hex() + ` is in_progress; only work orders that are completed, picked_up can be invoiced"}`},
+		"already invoiced": {`{"work_order_id":"` + completed.ID.Hex() + `"}`, http.StatusConflict,
+			`{"error":"work order ` + completed.ID.Hex() + ` has already been invoiced: conflict"}`},
+	}
+	for name, test := range tests {
+		rr := serveWithID(invoicesController.CreateInvoice, "POST", "", test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+	inProgress.Status = models.StatusCompleted
+	store.UpdateWorkOrder(ctx, *inProgress)
+	rr = serveWithID(invoicesController.CreateInvoice, "POST", "", `{"work_order_id":"`+inProgress.ID.Hex()+`","coupon":"FREE"}`)
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != `{"error":"unknown coupon \"FREE\""}` {
+		t.Errorf("got %v %v want unknown coupons rejected", rr.Code, rr.Body.String())
+	}
+	rr = serveWithID(invoicesController.CreateInvoice, "POST", "", `{"work_order_id":"`+inProgress.ID.Hex()+`"}`)
+	var next models.Invoice
+	json.Unmarshal(rr.Body.Bytes(), &next)
+	if rr.Code != http.StatusOK || next.Number != "INV-000002" {
+		t.Errorf("got %v %v want refused invoices to leave no gap in the numbers", rr.Code, rr.Body.String())
+	}
+
+	rr = serveWithID(invoicesController.GetInvoice, "GET", invoice.ID.Hex(), "")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("got %v %v want JSON by default", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	req := httptest.NewRequest("GET", "/invoices/"+invoice.ID.Hex(), nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", invoice.ID.Hex())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr = httptest.NewRecorder()
+	invoicesController.GetInvoice(rr, req)
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") || !strings.Contains(rr.Body.String(), "Invoice INV-000001") {
+		t.Errorf("got %v %v want the printable page", rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+}
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// PartsController - struct that has a reference to the parts inventory
+type PartsController struct {
+	DB db.PartStore
+}
+
+// stockRequest - body of a request to receive or write off stock
+type stockRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// validatePart - returns a description of the first problem with part, or nil if it can be stored
+func validatePart(part models.Part) error {
+	switch {
+	case strings.TrimSpace(part.SKU) == "":
+		return errors.New("part must have a sku")
+	case strings.TrimSpace(part.Name) == "":
+		return errors.New("part must have a name")
+	case part.OnHand < 0:
+		return errors.New("on_hand must not be negative")
+	case part.ReorderPoint < 0:
+		return errors.New("reorder_point must not be negative")
+	}
+	return nil
+}
+
+// CreatePart - accepts a part with its opening on_hand quantity and returns it with its generated id
+func (p *PartsController) CreatePart(w http.ResponseWriter, r *http.Request) {
+	var part models.Part
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&part)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid part")
+	} else if err := validatePart(part); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else {
+		// reservations only come from work orders
+		part.Reserved = 0
+		created, err := p.DB.CreatePart(r.Context(), part)
+		if err != nil {
+			status, response = dbErrorResponse(err)
+		} else if response, err = json.Marshal(created); err != nil {
+			log.Println("error marshaling part", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// GetPart - accepts part id and returns the specified part
+func (p *PartsController) GetPart(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	part, err := p.DB.GetPart(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(part)
+		if err != nil {
+			log.Println("error marshaling part", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListParts - returns the parts matching the sku and bin query parameters ordered by SKU; reorder=true
+// narrows them to the parts at or below their reorder point
+func (p *PartsController) ListParts(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+	query := r.URL.Query()
+	filter := models.PartFilter{SKU: query.Get("sku"), Bin: query.Get("bin"), Reorder: query.Get("reorder") == "true"}
+
+	parts, err := p.DB.ListParts(r.Context(), filter)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(parts)
+		if err != nil {
+			log.Println("error marshaling parts", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// UpdatePart - accepts id and either a whole part (PUT) or a JSON Merge Patch of one (PATCH) and returns the updated part.
+// Quantities can't be changed this way; stock is received through AdjustStock and reserved by work orders.
+func (p *PartsController) UpdatePart(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	part, err := p.DB.GetPart(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := decodeUpdate(r, part, &part.ID, "part"); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if err := validatePart(*part); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if updated, err := p.DB.UpdatePart(r.Context(), *part); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(updated)
+		if err != nil {
+			log.Println("error marshaling part", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// DeletePart - accepts part id to be deleted; parts reserved for work orders are kept
+func (p *PartsController) DeletePart(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte(fmt.Sprintf("part %v successfully deleted", id))
+
+	if err := p.DB.DeletePart(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// AdjustStock - accepts a quantity received into stock, or a negative quantity written off, and returns the updated part.
+// Stock reserved for work orders can't be written off.
+func (p *PartsController) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var request stockRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Quantity == 0 {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must contain a non-zero quantity")
+	} else if part, err := p.DB.GetPart(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if updated, err := p.DB.AdjustStock(r.Context(), part.SKU, request.Quantity, 0); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(updated)
+		if err != nil {
+			log.Println("error marshaling part", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// reserveParts - reserves the units of every part line whose SKU is stocked and marks the line reserved. Lines for
+// parts that aren't stocked, such as special orders, are left unreserved. If any part is short, the reservations
+// already made are released and db.ErrInsufficientStock returned.
+func reserveParts(ctx context.Context, parts db.PartStore, lines []models.WorkOrderLine) error {
+	if parts == nil {
+		return nil
+	}
+	for i := range lines {
+		line := &lines[i]
+		line.Reserved = false
+		if line.Type != models.LinePart {
+			continue
+		}
+		_, err := parts.AdjustStock(ctx, line.SKU, 0, line.Quantity)
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			releaseParts(ctx, parts, lines[:i])
+			return err
+		}
+		line.Reserved = true
+	}
+	return nil
+}
+
+// settleParts - hands back the reservations of the reserved lines, taking the units out of stock too when consume is set
+func settleParts(ctx context.Context, parts db.PartStore, lines []models.WorkOrderLine, consume bool) error {
+	if parts == nil {
+		return nil
+	}
+	for _, line := range lines {
+		if !line.Reserved {
+			continue
+		}
+		onHand := 0
+		if consume {
+			onHand = -line.Quantity
+		}
+		if _, err := parts.AdjustStock(ctx, line.SKU, onHand, -line.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseParts - settleParts without consuming, for undoing reservations; failures are only logged since the caller is already failing
+func releaseParts(ctx context.Context, parts db.PartStore, lines []models.WorkOrderLine) {
+	if err := settleParts(ctx, parts, lines, false); err != nil {
+		log.Println("error releasing reserved parts", err)
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPartsController(t *testing.T) {
+	partsController := PartsController{DB: db.NewMemoryStore()}
+
+	rr := serveWithID(partsController.CreatePart, "POST", "", `{"sku":"BP-100","name":"Brake pads","bin":"A1","on_hand":4,"reserved":3,"reorder_point":2}`)
+	var part models.Part
+	if err := json.Unmarshal(rr.Body.Bytes(), &part); rr.Code != http.StatusOK || err != nil || part.Reserved != 0 {
+		t.Fatalf("got %v %v want the part created without reservations", rr.Code, rr.Body.String())
+	}
+	id := part.ID.Hex()
+
+	// in order, since each builds on the stock left by the last
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		id       string
+		body     string
+		status   int
+		expected string
+	}{
+		{"no sku", partsController.CreatePart, "POST", "", `{"name":"Brake pads"}`, http.StatusBadRequest, `{"error":"part must have a sku"}`},
+		{"duplicate sku", partsController.CreatePart, "POST", "", `{"sku":"BP-100","name":"Pads"}`,
+			http.StatusConflict, `{"error":"a part with sku BP-100 already exists: conflict"}`},
+		{"receive stock", partsController.AdjustStock, "POST", id, `{"quantity":6}`, http.StatusOK,
+			`{"id":"` + id + `","sku":"BP-100","name":"Brake pads","bin":"A1","on_hand":10,"reserved":0,"reorder_point":2}`},
+		{"write off too much", partsController.AdjustStock, "POST", id, `{"quantity":-11}`,
+			http.StatusConflict, `{"error":"part BP-100 has too little stock: conflict: 10 on hand, 0 reserved"}`},
+		{"no quantity", partsController.AdjustStock, "POST", id, `{}`, http.StatusBadRequest, `{"error":"request body must contain a non-zero quantity"}`},
+		{"patch keeps quantities", partsController.UpdatePart, "PATCH", id, `{"bin":"C3","on_hand":99}`, http.StatusOK,
+			`{"id":"` + id + `","sku":"BP-100","name":"Brake pads","bin":"C3","on_hand":10,"reserved":0,"reorder_point":2}`},
+	}
+	for _, test := range tests {
+		rr := serveWithID(test.handler, test.method, test.id, test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", test.name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
+
+func TestWorkOrderPartReservations(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	workOrdersController := WorkOrdersController{DB: store, Appointments: store, Catalog: store, Parts: store}
+	appointmentsController := AppointmentsController{DB: store, WorkOrders: store, Parts: store}
+	date := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+
+	pads, _ := store.CreatePart(ctx, models.Part{SKU: "BP-100", Name: "Brake pads", OnHand: 3})
+	stock := func() models.Part {
+		part, err := store.GetPart(ctx, pads.ID.Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *part
+	}
+	openOrder := func() (models.Appointment, string) {
+		appointment, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Date: date, Status: models.StatusCheckedIn})
+		order := decodeWorkOrder(t, serveWithID(workOrdersController.CreateWorkOrder, "POST", "", `{"appointment_id":"`+appointment.ID.Hex()+`"}`))
+		return *appointment, order.ID.Hex()
+	}
+	line := `{"type":"part","sku":"BP-100","quantity":2,"unit_price":"45.50"}`
+
+	completed, completedID := openOrder()
+	order := decodeWorkOrder(t, serveWithID(workOrdersController.AddWorkOrderLine, "POST", completedID, line))
+	if !order.Lines[0].Reserved || stock().Reserved != 2 {
+		t.Errorf("got line %+v stock %+v want 2 reserved", order.Lines[0], stock())
+	}
+	partsController := PartsController{DB: store}
+	if rr := serveWithID(partsController.UpdatePart, "PATCH", pads.ID.Hex(), `{"sku":"BP-200"}`); rr.Code != http.StatusConflict ||
+		rr.Body.String() != `{"error":"part BP-100 has 2 units reserved, so its sku can't change: conflict"}` {
+		t.Errorf("got %v %v want the sku of reserved pads kept", rr.Code, rr.Body.String())
+	}
+	special := decodeWorkOrder(t, serveWithID(workOrdersController.AddWorkOrderLine, "POST", completedID, `{"type":"part","sku":"SPECIAL","quantity":1}`))
+	if special.Lines[1].Reserved {
+		t.Errorf("got %+v want parts that aren't stocked left unreserved", special.Lines[1])
+	}
+
+	cancelled, cancelledID := openOrder()
+	rr := serveWithID(workOrdersController.AddWorkOrderLine, "POST", cancelledID, line)
+	if rr.Code != http.StatusConflict || rr.Body.String() != `{"error":"part BP-100 has too little stock: conflict: 3 on hand, 2 reserved"}` {
+		t.Errorf("got %v %v want the last brake pads kept for the first work order", rr.Code, rr.Body.String())
+	}
+	order = decodeWorkOrder(t, serveWithID(workOrdersController.AddWorkOrderLine, "POST", cancelledID, `{"type":"part","sku":"BP-100","quantity":1}`))
+	if stock().Available() != 0 {
+		t.Errorf("got %+v want every pad reserved", stock())
+	}
+	decodeWorkOrder(t, serveWorkOrderItem(workOrdersController.RemoveWorkOrderLine, cancelledID, "lineID", order.Lines[0].ID.Hex()))
+	decodeWorkOrder(t, serveWithID(workOrdersController.AddWorkOrderLine, "POST", cancelledID, `{"type":"part","sku":"BP-100","quantity":1}`))
+	if part := stock(); part.Reserved != 3 {
+		t.Errorf("got %+v want removing a line to release its pad", part)
+	}
+
+	rr = serveWithID(appointmentsController.DeleteAppointment, "DELETE", cancelled.ID.Hex(), "")
+	if expected := `{"error":"appointment ` + cancelled.ID.Hex() + ` has work order ` + cancelledID + ` checked_in, so it must be cancelled or completed before it is deleted: conflict"}`; rr.Code != http.StatusConflict || rr.Body.String() != expected {
+		t.Errorf("got %v %v want %v %v", rr.Code, rr.Body.String(), http.StatusConflict, expected)
+	}
+
+	// a job can be abandoned while it is in progress
+	for _, status := range []string{models.StatusInProgress, models.StatusCancelled} {
+		if rr := serveWithID(appointmentsController.UpdateAppointmentStatus, "PATCH", cancelled.ID.Hex(), `{"status":"`+status+`"}`); rr.Code != http.StatusOK {
+			t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+		}
+	}
+	if part := stock(); part.OnHand != 3 || part.Reserved != 2 {
+		t.Errorf("got %+v want the cancelled work order's pad released", part)
+	}
+	if rr := serveWithID(appointmentsController.DeleteAppointment, "DELETE", cancelled.ID.Hex(), ""); rr.Code != http.StatusOK {
+		t.Errorf("deleting the cancelled appointment got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+	for _, status := range []string{models.StatusInProgress, models.StatusCompleted} {
+		if rr := serveWithID(appointmentsController.UpdateAppointmentStatus, "PATCH", completed.ID.Hex(), `{"status":"`+status+`"}`); rr.Code != http.StatusOK {
+			t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+		}
+	}
+	if part := stock(); part.OnHand != 1 || part.Reserved != 0 {
+		t.Errorf("got %+v want the completed work order's pads taken out of stock", part)
+	}
+	order = decodeWorkOrder(t, serveWithID(workOrdersController.GetWorkOrder, "GET", completedID, ""))
+	if order.Lines[0].Reserved {
+		t.Errorf("got %+v want settled lines no longer reserved", order.Lines[0])
+	}
+}
+
+func TestWorkOrderSyncAfterClientLeaves(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	workOrdersController := WorkOrdersController{DB: store, Appointments: store, Catalog: store, Parts: store}
+	appointmentsController := AppointmentsController{DB: store, WorkOrders: store, Parts: store}
+	pads, _ := store.CreatePart(ctx, models.Part{SKU: "BP-100", Name: "Brake pads", OnHand: 3})
+	appointment, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal",
+		Date: time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC), Status: models.StatusCheckedIn})
+	id := appointment.ID.Hex()
+	order := decodeWorkOrder(t, serveWithID(workOrdersController.CreateWorkOrder, "POST", "", `{"appointment_id":"`+id+`"}`))
+	decodeWorkOrder(t, serveWithID(workOrdersController.AddWorkOrderLine, "POST", order.ID.Hex(), `{"type":"part","sku":"BP-100","quantity":2}`))
+
+	// the status is saved before the client goes away, and its work order is still brought along
+	if _, err := store.UpdateAppointmentStatus(ctx, id, models.StatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+	gone, cancel := context.WithCancel(ctx)
+	cancel()
+	appointmentsController.syncWorkOrder(gone, id, models.StatusCancelled)
+
+	if part, _ := store.GetPart(ctx, pads.ID.Hex()); part.Reserved != 0 {
+		t.Errorf("got %+v want the pads released after the client went away", part)
+	}
+	if synced, _ := store.GetWorkOrder(ctx, order.ID.Hex()); synced.Status != models.StatusCancelled || synced.Lines[0].Reserved {
+		t.Errorf("got %+v want the work order cancelled and its line settled", synced)
+	}
+}
+
+// failingStock - a part store whose stock can't be adjusted, as when the database is unreachable
+type failingStock struct {
+	db.PartStore
+}
+
+func (failingStock) AdjustStock(context.Context, string, int, int) (*models.Part, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func TestWorkOrderSettlementFailure(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	workOrdersController := WorkOrdersController{DB: store, Appointments: store, Catalog: store, Parts: store}
+	appointmentsController := AppointmentsController{DB: store, WorkOrders: store, Parts: failingStock{store}}
+
+	pads, _ := store.CreatePart(ctx, models.Part{SKU: "BP-100", Name: "Brake pads", OnHand: 3})
+	appointment, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal",
+		Date: time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC), Status: models.StatusCheckedIn})
+	order := decodeWorkOrder(t, serveWithID(workOrdersController.CreateWorkOrder, "POST", "", `{"appointment_id":"`+appointment.ID.Hex()+`"}`))
+	decodeWorkOrder(t, serveWithID(workOrdersController.AddWorkOrderLine, "POST", order.ID.Hex(), `{"type":"part","sku":"BP-100","quantity":2}`))
+
+	if rr := serveWithID(appointmentsController.UpdateAppointmentStatus, "PATCH", appointment.ID.Hex(), `{"status":"cancelled"}`); rr.Code != http.StatusOK {
+		t.Fatalf("got %v %v want the status change to succeed", rr.Code, rr.Body.String())
+	}
+	order = decodeWorkOrder(t, serveWithID(workOrdersController.GetWorkOrder, "GET", order.ID.Hex(), ""))
+	part, _ := store.GetPart(ctx, pads.ID.Hex())
+	if order.Status != models.StatusCancelled || !order.Lines[0].Reserved || part.Reserved != 2 {
+		t.Errorf("got order %+v part %+v want the unsettled line still reserved on both", order, part)
+	}
+}
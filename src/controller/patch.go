@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"CarServiceCenter/src/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mergePatchContentType - media type of JSON Merge Patch documents (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
+// editableFields - pointers to the appointment fields clients may change after creation, keyed by lower-case JSON name
+func editableFields(appointment *models.Appointment) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        &appointment.Name,
+		"description": &appointment.Description,
+		"date":        &appointment.Date,
+		"service":     &appointment.Service,
+		"service_ids": &appointment.ServiceIDs,
+	}
+}
+
+// applyAppointmentPatch - returns appointment with the JSON Merge Patch document in body applied to its editable fields.
+// Members set to null reset the field; when replace is true every editable field is reset before the patch is applied.
+// Keys are matched case-insensitively, the same way CreateAppointment decodes its body.
+func applyAppointmentPatch(appointment models.Appointment, body io.Reader, replace bool) (models.Appointment, error) {
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&patch); err != nil || patch == nil {
+		return appointment, errors.New("request body must be a JSON object")
+	}
+
+	fields := editableFields(&appointment)
+	if replace {
+		for _, field := range fields {
+			resetField(field)
+		}
+	}
+	for key, value := range patch {
+		field, ok := fields[strings.ToLower(key)]
+		switch {
+		case !ok && (strings.EqualFold(key, "id") || strings.EqualFold(key, "status")):
+			return appointment, fmt.Errorf("%s cannot be changed with this request", key)
+		case !ok:
+			return appointment, fmt.Errorf("unknown appointment field %q", key)
+		case bytes.Equal(value, []byte("null")):
+			resetField(field)
+		default:
+			if err := json.Unmarshal(value, field); err != nil {
+				return appointment, fmt.Errorf("invalid value for %s", key)
+			}
+		}
+	}
+	return appointment, nil
+}
+
+// resetField - sets the value field points to back to its zero value
+func resetField(field interface{}) {
+	value := reflect.ValueOf(field).Elem()
+	value.Set(reflect.Zero(value.Type()))
+}
+
+// applyMergePatch - applies the JSON Merge Patch document in body to the JSON form of record and decodes the result back into record.
+// Members the record doesn't have are rejected, as are members named in readOnly.
+func applyMergePatch(record interface{}, body io.Reader, readOnly ...string) error {
+	var patch map[string]interface{}
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&patch); err != nil || patch == nil {
+		return errors.New("request body must be a JSON object")
+	}
+	for _, key := range readOnly {
+		if _, ok := patch[key]; ok {
+			return fmt.Errorf("%s cannot be changed with this request", key)
+		}
+	}
+
+	current, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	decoder = json.NewDecoder(bytes.NewReader(current))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return err
+	}
+	merged, err := json.Marshal(mergeValues(document, patch))
+	if err != nil {
+		return err
+	}
+
+	resetField(record)
+	decoder = json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(record); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// decodeUpdate - applies the body of a PUT or PATCH request to record, whose ID field id points to.
+// PUT replaces the whole record and PATCH applies a JSON Merge Patch; neither may change the ID. what names the record in errors.
+func decodeUpdate(r *http.Request, record interface{}, id *primitive.ObjectID, what string) error {
+	if r.Method == http.MethodPatch {
+		return applyMergePatch(record, r.Body, "id")
+	}
+	original := *id
+	resetField(record)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(record); err != nil {
+		return fmt.Errorf("request body must be a valid %s", what)
+	}
+	if !id.IsZero() && *id != original {
+		return errors.New("id cannot be changed with this request")
+	}
+	*id = original
+	return nil
+}
+
+// mergeValues - the RFC 7396 MergePatch algorithm over decoded JSON values
+func mergeValues(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergeValues(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
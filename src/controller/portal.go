@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PortalController - customer self-service booking. Customers sign in with a portal link handed to them by the shop
+// and can only see and change their own appointments, which are booked and cancelled through Appointments so the
+// same schedule, catalog and work order rules apply as when staff do it.
+type PortalController struct {
+	Appointments *AppointmentsController
+	Customers    db.CustomerStore
+	Tokens       *auth.Tokens
+	// LinkTTL - how long a portal link keeps working
+	LinkTTL time.Duration
+	// CancellationCutoff - customers can't cancel online once an appointment is closer than this
+	CancellationCutoff time.Duration
+	// Now - the clock bookings and cancellations are checked against
+	Now func() time.Time
+}
+
+// portalLinkResponse - body returned when creating a portal link
+type portalLinkResponse struct {
+	CustomerID string    `json:"customer_id"`
+	Token      string    `json:"token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// portalBooking - the fields of an appointment customers choose when booking one themselves
+type portalBooking struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Date        time.Time            `json:"date"`
+	Service     string               `json:"service"`
+	VehicleID   *primitive.ObjectID  `json:"vehicle_id"`
+	ServiceIDs  []primitive.ObjectID `json:"service_ids"`
+}
+
+// portalCustomer - the id of the customer signed in to the portal
+func portalCustomer(ctx context.Context) primitive.ObjectID {
+	claims, _ := auth.ClaimsFrom(ctx)
+	id, _ := primitive.ObjectIDFromHex(claims.Subject)
+	return id
+}
+
+// forCustomer - narrows query to the appointments of the customer with the given id
+func forCustomer(query models.AppointmentQuery, customerID primitive.ObjectID) models.AppointmentQuery {
+	query.Filter.CustomerID = &customerID
+	return query
+}
+
+// ownAppointment - returns the appointment with the given id if it belongs to the signed in customer. Other
+// customers' appointments are reported as not found, so customers can't learn which ids exist.
+func (p *PortalController) ownAppointment(ctx context.Context, id string) (*models.Appointment, error) {
+	appointment, err := p.Appointments.DB.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.CustomerID == nil || *appointment.CustomerID != portalCustomer(ctx) {
+		return nil, fmt.Errorf("appointment %v %w", id, db.ErrNotFound)
+	}
+	return appointment, nil
+}
+
+// CreatePortalLink - accepts customer id and returns a token the customer can use the portal with until it expires.
+// The shop hands it to the customer, for example in a link on their preferred contact channel.
+func (p *PortalController) CreatePortalLink(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	customer, err := p.Customers.GetCustomer(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if token, expires, err := p.Tokens.IssuePortal(*customer, p.LinkTTL); err != nil {
+		log.Println("error issuing portal token", err)
+		status = http.StatusInternalServerError
+		response = errorJSON("internal server error")
+	} else {
+		response, err = json.Marshal(portalLinkResponse{CustomerID: id, Token: token, ExpiresAt: expires})
+		if err != nil {
+			log.Println("error marshaling portal link", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListAppointments - returns one page of the signed in customer's appointments, taking the same query parameters as
+// AppointmentsController.ListAppointments except customer
+func (p *PortalController) ListAppointments(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	query, err := parseAppointmentQuery(r.URL.Query())
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if page, err := p.Appointments.DB.ListAppointments(r.Context(), forCustomer(*query, portalCustomer(r.Context()))); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(page)
+		if err != nil {
+			log.Println("error marshaling appointment page")
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// GetAppointment - accepts appointment id and returns the appointment if it is the signed in customer's
+func (p *PortalController) GetAppointment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	appointment, err := p.ownAppointment(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(appointment)
+		if err != nil {
+			log.Println("error marshaling appointment struct")
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// BookAppointment - accepts a name, description, date and optionally a service, vehicle and catalog items, and
+// returns the appointment booked for the signed in customer. The date can't be in the past, and the vehicle must
+// be one of the customer's.
+func (p *PortalController) BookAppointment(w http.ResponseWriter, r *http.Request) {
+	var booking portalBooking
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&booking)
+	customerID := portalCustomer(r.Context())
+	appointment := models.Appointment{Name: booking.Name, Description: booking.Description, Date: booking.Date, Service: booking.Service,
+		CustomerID: &customerID, VehicleID: booking.VehicleID, ServiceIDs: booking.ServiceIDs, Status: models.StatusOpen}
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid appointment")
+	} else if !validAppointment(appointment) {
+		status = http.StatusBadRequest
+		response = errorJSON("appointment must have valid name, description and date values")
+	} else if appointment.Date.Before(p.Now()) {
+		status = http.StatusBadRequest
+		response = errorJSON("appointment date must not be in the past")
+	} else {
+		status, response = p.Appointments.createAppointment(r.Context(), appointment)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// CancelAppointment - accepts appointment id and cancels the signed in customer's appointment, as long as it is
+// further away than the cancellation cutoff; later cancellations have to go through the shop
+func (p *PortalController) CancelAppointment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	appointment, err := p.ownAppointment(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if appointment.Date.Sub(p.Now()) < p.CancellationCutoff {
+		status = http.StatusConflict
+		response = errorJSON(fmt.Sprintf("appointments can't be cancelled online less than %v before they start, please call the shop", p.CancellationCutoff))
+	} else if err := p.Appointments.setStatus(r.Context(), id, models.StatusCancelled); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		appointment.Status = models.StatusCancelled
+		response, err = json.Marshal(appointment)
+		if err != nil {
+			log.Println("error marshaling appointment struct")
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// portalNow - the time the portal's clock is stopped at in tests
+var portalNow = time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+
+func newPortalController(t *testing.T, store db.Database) PortalController {
+	tokens, err := auth.NewTokens(config.Default().Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens.Now = func() time.Time { return portalNow }
+	appointmentsController := &AppointmentsController{DB: store, Customers: store, Vehicles: store, Catalog: store,
+		WorkOrders: store, Parts: store, Estimates: store, Audit: store}
+	return PortalController{Appointments: appointmentsController, Customers: store, Tokens: tokens,
+		LinkTTL: 72 * time.Hour, CancellationCutoff: 24 * time.Hour, Now: func() time.Time { return portalNow }}
+}
+
+// portalLink - creates a portal link for customer through portalController and returns its token
+func portalLink(t *testing.T, portalController PortalController, customer *models.Customer) string {
+	rr := serveWithID(portalController.CreatePortalLink, "POST", customer.ID.Hex(), "")
+	var link portalLinkResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &link); rr.Code != http.StatusOK || err != nil || link.Token == "" {
+		t.Fatalf("got %v %v want a portal link", rr.Code, rr.Body.String())
+	}
+	return link.Token
+}
+
+// serveAsCustomer - sends a request with the given id URL parameter, portal token and body to handler, behind the
+// portal's authentication, and returns the recorded response
+func serveAsCustomer(portalController PortalController, handler http.HandlerFunc, method, id, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/"+id, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+	portalController.Tokens.AuthenticateCustomer(handler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPortalCancellationCutoff(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	portalController := newPortalController(t, store)
+	jane, _ := store.CreateCustomer(ctx, models.Customer{Name: "Jane Doe"})
+	token := portalLink(t, portalController, jane)
+	book := func(in time.Duration) string {
+		appointment, err := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Status: models.StatusOpen,
+			Date: portalNow.Add(in), CustomerID: &jane.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return appointment.ID.Hex()
+	}
+
+	tests := []struct {
+		name   string
+		in     time.Duration
+		status int
+	}{
+		{"just outside the cutoff", portalController.CancellationCutoff + time.Second, http.StatusOK},
+		{"exactly at the cutoff", portalController.CancellationCutoff, http.StatusOK},
+		{"just inside the cutoff", portalController.CancellationCutoff - time.Second, http.StatusConflict},
+		{"already started", -time.Minute, http.StatusConflict},
+	}
+	for _, test := range tests {
+		id := book(test.in)
+		rr := serveAsCustomer(portalController, portalController.CancelAppointment, "POST", id, token, "")
+		if rr.Code != test.status {
+			t.Errorf("%v: got %v %v want %v", test.name, rr.Code, rr.Body.String(), test.status)
+		}
+		cancelled := test.status == http.StatusOK
+		if appointment, err := store.GetAppointment(ctx, id); err != nil || (appointment.Status == models.StatusCancelled) != cancelled {
+			t.Errorf("%v: got %+v, %v want cancelled %v", test.name, appointment, err, cancelled)
+		}
+	}
+
+	rr := serveAsCustomer(portalController, portalController.CancelAppointment, "POST", book(time.Hour), token, "")
+	if expected := `{"error":"appointments can't be cancelled online less than 24h0m0s before they start, please call the shop"}`; rr.Body.String() != expected {
+		t.Errorf("got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestPortalBookingDate(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	portalController := newPortalController(t, store)
+	jane, _ := store.CreateCustomer(ctx, models.Customer{Name: "Jane Doe"})
+	token := portalLink(t, portalController, jane)
+
+	tests := []struct {
+		name     string
+		date     time.Time
+		status   int
+		expected string
+	}{
+		{"yesterday", portalNow.Add(-24 * time.Hour), http.StatusBadRequest, `{"error":"appointment date must not be in the past"}`},
+		{"a second ago", portalNow.Add(-time.Second), http.StatusBadRequest, `{"error":"appointment date must not be in the past"}`},
+		{"tomorrow", portalNow.Add(24 * time.Hour), http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		body := `{"name":"Brakes","description":"squeal","date":"` + test.date.Format(time.RFC3339) + `"}`
+		rr := serveAsCustomer(portalController, portalController.BookAppointment, "POST", "", token, body)
+		if rr.Code != test.status || test.expected != "" && rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", test.name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+	if page, err := store.ListAppointments(ctx, models.AppointmentQuery{Limit: 10}); err != nil || len(page.Appointments) != 1 {
+		t.Errorf("got %+v, %v want only the future appointment booked", page, err)
+	}
+}
+
+func TestPortalOtherCustomersAppointment(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	portalController := newPortalController(t, store)
+	jane, _ := store.CreateCustomer(ctx, models.Customer{Name: "Jane Doe"})
+	john, _ := store.CreateCustomer(ctx, models.Customer{Name: "John Roe"})
+	johns, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Oil", Description: "change", Status: models.StatusOpen,
+		Date: portalNow.Add(72 * time.Hour), CustomerID: &john.ID})
+	unassigned, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Tires", Description: "rotate", Status: models.StatusOpen,
+		Date: portalNow.Add(72 * time.Hour)})
+	janesToken := portalLink(t, portalController, jane)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		id      string
+	}{
+		{"get another customer's appointment", portalController.GetAppointment, "GET", johns.ID.Hex()},
+		{"cancel another customer's appointment", portalController.CancelAppointment, "POST", johns.ID.Hex()},
+		{"get an appointment without a customer", portalController.GetAppointment, "GET", unassigned.ID.Hex()},
+		{"get an appointment that doesn't exist", portalController.GetAppointment, "GET", "000000000000000000000000"},
+	}
+	for _, test := range tests {
+		rr := serveAsCustomer(portalController, test.handler, test.method, test.id, janesToken, "")
+		if expected := `{"error":"appointment ` + test.id + ` not found"}`; rr.Code != http.StatusNotFound || rr.Body.String() != expected {
+			t.Errorf("%v: got %v %v want %v %v", test.name, rr.Code, rr.Body.String(), http.StatusNotFound, expected)
+		}
+	}
+	if appointment, _ := store.GetAppointment(ctx, johns.ID.Hex()); appointment.Status != models.StatusOpen {
+		t.Errorf("got %+v want another customer's appointment left open", appointment)
+	}
+
+	rr := serveAsCustomer(portalController, portalController.GetAppointment, "GET", johns.ID.Hex(), portalLink(t, portalController, john), "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("owner getting their appointment got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+}
+
+func TestPortalLinkNoLongerValid(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	portalController := newPortalController(t, store)
+	jane, _ := store.CreateCustomer(ctx, models.Customer{Name: "Jane Doe"})
+	janes, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Oil", Description: "change", Status: models.StatusOpen,
+		Date: portalNow.Add(72 * time.Hour), CustomerID: &jane.ID})
+	id := janes.ID.Hex()
+	token := portalLink(t, portalController, jane)
+
+	portalController.Tokens.Now = func() time.Time { return portalNow.Add(portalController.LinkTTL - time.Minute) }
+	if rr := serveAsCustomer(portalController, portalController.GetAppointment, "GET", id, token, ""); rr.Code != http.StatusOK {
+		t.Errorf("link before it expires got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+	portalController.Tokens.Now = func() time.Time { return portalNow.Add(portalController.LinkTTL + time.Minute) }
+	rr := serveAsCustomer(portalController, portalController.CancelAppointment, "POST", id, token, "")
+	if expected := `{"error":"token expired: invalid token"}`; rr.Code != http.StatusUnauthorized || rr.Body.String() != expected {
+		t.Errorf("expired link got %v %v want %v %v", rr.Code, rr.Body.String(), http.StatusUnauthorized, expected)
+	}
+
+	// links can't be revoked one by one; changing the signing secret revokes every link handed out with the old one
+	rotated := newPortalController(t, store)
+	rr = serveAsCustomer(rotated, rotated.CancelAppointment, "POST", id, portalLink(t, portalController, jane), "")
+	if expected := `{"error":"bad token signature: invalid token"}`; rr.Code != http.StatusUnauthorized || rr.Body.String() != expected {
+		t.Errorf("link signed with a revoked secret got %v %v want %v %v", rr.Code, rr.Body.String(), http.StatusUnauthorized, expected)
+	}
+	if appointment, _ := store.GetAppointment(ctx, id); appointment.Status != models.StatusOpen {
+		t.Errorf("got %+v want the appointment left open", appointment)
+	}
+}
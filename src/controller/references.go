@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/vin"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errMissingReference - the request names a related record that doesn't exist; reported as a bad request rather than a 404
+var errMissingReference = errors.New("does not exist")
+
+// missingReference - turns the db.ErrNotFound from looking up the what with the given id into errMissingReference
+func missingReference(err error, what, id string) error {
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("%s %v %w", what, id, errMissingReference)
+	}
+	return err
+}
+
+// resolveReferences - makes sure the customer, vehicle and technicians appointment names exist, that the vehicle has a valid VIN and that it belongs to the customer.
+// An appointment for a vehicle without a customer is booked for the vehicle's owner.
+func (a *AppointmentsController) resolveReferences(ctx context.Context, appointment *models.Appointment) error {
+	if appointment.VehicleID != nil && a.Vehicles != nil {
+		vehicle, err := a.Vehicles.GetVehicle(ctx, appointment.VehicleID.Hex())
+		if err != nil {
+			return missingReference(err, "vehicle", appointment.VehicleID.Hex())
+		}
+		// vehicles registered before VINs were validated may still have a mistyped one
+		if err := vin.Validate(vehicle.VIN); err != nil {
+			return fmt.Errorf("vehicle %v: %w", vehicle.ID.Hex(), err)
+		}
+		if appointment.CustomerID == nil {
+			appointment.CustomerID = &vehicle.CustomerID
+		} else if *appointment.CustomerID != vehicle.CustomerID {
+			return fmt.Errorf("vehicle %v does not belong to customer %v: %w",
+				vehicle.ID.Hex(), appointment.CustomerID.Hex(), errMissingReference)
+		}
+	}
+	if appointment.CustomerID != nil && a.Customers != nil {
+		if _, err := a.Customers.GetCustomer(ctx, appointment.CustomerID.Hex()); err != nil {
+			return missingReference(err, "customer", appointment.CustomerID.Hex())
+		}
+	}
+	if a.Technicians != nil {
+		for _, id := range appointment.TechnicianIDs {
+			if _, err := a.Technicians.GetTechnician(ctx, id.Hex()); err != nil {
+				return missingReference(err, "technician", id.Hex())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/scheduling"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// scheduleConflictResponse - JSON body returned when an appointment doesn't fit the schedule
+type scheduleConflictResponse struct {
+	Error       string      `json:"error"`
+	Suggestions []time.Time `json:"suggestions"`
+}
+
+// checkSchedule - returns a non-zero status and its body if appointment can't be booked, or its technicians can't work on it then.
+// Callers must hold scheduleMu until the appointment is written.
+func (a *AppointmentsController) checkSchedule(ctx context.Context, appointment models.Appointment) (int, []byte) {
+	start, end := a.Scheduler.Window(appointment.Date)
+	existing, err := a.DB.GetAppointmentsWithinDateRange(ctx, start, end)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+
+	err = a.Scheduler.Check(appointment, *existing)
+	switch {
+	case err == nil && a.Technicians != nil:
+		return a.checkTechnicians(ctx, appointment, appointment.TechnicianIDs, *existing)
+	case err == nil:
+		return 0, nil
+	case errors.Is(err, scheduling.ErrUnknownService):
+		return http.StatusBadRequest, errorJSON(err.Error())
+	}
+	response, err := json.Marshal(scheduleConflictResponse{
+		Error:       err.Error(),
+		Suggestions: a.Scheduler.Suggest(appointment, *existing),
+	})
+	if err != nil {
+		log.Println("error marshaling schedule conflict", err)
+	}
+	return http.StatusConflict, response
+}
+
+// createAppointment - stores appointment once its references are checked and it is known to fit the schedule,
+// and returns the status and body to respond with
+func (a *AppointmentsController) createAppointment(ctx context.Context, appointment models.Appointment) (int, []byte) {
+	if err := a.resolveReferences(ctx, &appointment); err != nil {
+		return dbErrorResponse(err)
+	}
+	if err := a.resolveServices(ctx, &appointment); err != nil {
+		return dbErrorResponse(err)
+	}
+	if a.Scheduler != nil {
+		a.scheduleMu.Lock()
+		defer a.scheduleMu.Unlock()
+		if status, response := a.checkSchedule(ctx, appointment); status != 0 {
+			return status, response
+		}
+	}
+
+	newAppointment, err := a.DB.CreateAppointment(ctx, appointment)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	a.audit(ctx, models.AuditCreated, nil, newAppointment)
+	response, err := json.Marshal(newAppointment)
+	if err != nil {
+		log.Println("error:", err)
+	}
+	return http.StatusOK, response
+}
+
+// updateAppointment - saves appointment over previous, checking the schedule first if its date, service or catalog items changed,
+// and returns the status and body to respond with
+func (a *AppointmentsController) updateAppointment(ctx context.Context, appointment, previous models.Appointment) (int, []byte) {
+	servicesChanged := !sameServices(appointment.ServiceIDs, previous.ServiceIDs)
+	if servicesChanged {
+		if err := a.resolveServices(ctx, &appointment); err != nil {
+			return dbErrorResponse(err)
+		}
+	}
+	rescheduled := !appointment.Date.Equal(previous.Date) || appointment.Service != previous.Service || servicesChanged
+	if a.Scheduler != nil && rescheduled {
+		a.scheduleMu.Lock()
+		defer a.scheduleMu.Unlock()
+		if status, response := a.checkSchedule(ctx, appointment); status != 0 {
+			return status, response
+		}
+	}
+
+	updated, err := a.DB.UpdateAppointment(ctx, appointment)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	a.audit(ctx, models.AuditUpdated, &previous, updated)
+	response, err := json.Marshal(updated)
+	if err != nil {
+		log.Println("error marshaling appointment struct")
+	}
+	return http.StatusOK, response
+}
@@ -0,0 +1,367 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/scheduling"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TechniciansController - struct that has references to the technician store and the appointments technicians are assigned to
+type TechniciansController struct {
+	DB db.TechnicianStore
+	// Appointments - searched for a technician's schedule, and so technicians with assignments can't be deleted
+	Appointments db.ClientInterface
+}
+
+// validateTechnician - returns a description of the first problem with technician, or nil if it can be stored
+func validateTechnician(technician models.Technician) error {
+	if strings.TrimSpace(technician.Name) == "" {
+		return errors.New("technician must have a name")
+	}
+	if technician.Email != "" {
+		if address, err := mail.ParseAddress(technician.Email); err != nil || address.Address != technician.Email {
+			return fmt.Errorf("%q is not a valid email address", technician.Email)
+		}
+	}
+	for _, certification := range technician.Certifications {
+		if strings.TrimSpace(certification.Name) == "" {
+			return errors.New("certifications must have a name")
+		}
+	}
+	for day, shift := range technician.WorkingHours {
+		if !validWeekday(day) {
+			return fmt.Errorf("working_hours key %q must be a lower-case weekday", day)
+		}
+		start, startErr := time.Parse("15:04", shift.Start)
+		end, endErr := time.Parse("15:04", shift.End)
+		if startErr != nil || endErr != nil || !start.Before(end) {
+			return fmt.Errorf("working_hours for %s must start before they end, as \"15:04\" times", day)
+		}
+	}
+	return nil
+}
+
+// validWeekday - reports whether day is the lower-case name of a weekday
+func validWeekday(day string) bool {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if day == strings.ToLower(weekday.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateTechnician - accepts a technician and returns it with its generated id
+func (t *TechniciansController) CreateTechnician(w http.ResponseWriter, r *http.Request) {
+	var technician models.Technician
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&technician)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid technician")
+	} else if err := validateTechnician(technician); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if created, err := t.DB.CreateTechnician(r.Context(), technician); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(created)
+		if err != nil {
+			log.Println("error marshaling technician", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// GetTechnician - accepts technician id and returns the specified technician
+func (t *TechniciansController) GetTechnician(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	technician, err := t.DB.GetTechnician(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(technician)
+		if err != nil {
+			log.Println("error marshaling technician", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListTechnicians - returns every technician with the skill query parameter, or every technician, ordered by name
+func (t *TechniciansController) ListTechnicians(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+	filter := models.TechnicianFilter{Skill: r.URL.Query().Get("skill")}
+
+	technicians, err := t.DB.ListTechnicians(r.Context(), filter)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(technicians)
+		if err != nil {
+			log.Println("error marshaling technicians", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// UpdateTechnician - accepts id and either a whole technician (PUT) or a JSON Merge Patch of one (PATCH) and returns the updated technician.
+// Existing assignments are kept even if they fall outside the new working hours.
+func (t *TechniciansController) UpdateTechnician(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	technician, err := t.DB.GetTechnician(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := decodeUpdate(r, technician, &technician.ID, "technician"); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if err := validateTechnician(*technician); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if updated, err := t.DB.UpdateTechnician(r.Context(), *technician); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(updated)
+		if err != nil {
+			log.Println("error marshaling technician", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// DeleteTechnician - accepts technician id to be deleted; technicians still assigned to appointments are kept
+func (t *TechniciansController) DeleteTechnician(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte(fmt.Sprintf("technician %v successfully deleted", id))
+
+	if err := t.checkUnassigned(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := t.DB.DeleteTechnician(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// checkUnassigned - returns db.ErrConflict if the technician with the given id is assigned to any appointment
+func (t *TechniciansController) checkUnassigned(ctx context.Context, id string) error {
+	technician, err := t.DB.GetTechnician(ctx, id)
+	if err != nil {
+		return err
+	}
+	page, err := t.Appointments.ListAppointments(ctx, models.AppointmentQuery{
+		Filter: models.AppointmentFilter{TechnicianID: &technician.ID},
+		Limit:  1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(page.Appointments) != 0 {
+		return fmt.Errorf("technician %v is still assigned to appointments: %w", id, db.ErrConflict)
+	}
+	return nil
+}
+
+// GetTechnicianSchedule - accepts technician id and a start and end date and returns the technician's appointments
+// dated within that range, oldest first. Cancelled and no-show appointments are left out.
+func (t *TechniciansController) GetTechnicianSchedule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	start, startErr := parseTimeParam(r.URL.Query(), "start")
+	end, endErr := parseTimeParam(r.URL.Query(), "end")
+	if startErr != nil || endErr != nil || start.IsZero() || end.IsZero() || end.Before(start) {
+		status = http.StatusBadRequest
+		response = errorJSON("request must have valid start and end date range")
+	} else if schedule, err := t.schedule(r.Context(), id, start, end); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(schedule)
+		if err != nil {
+			log.Println("error marshaling technician schedule", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// schedule - the appointments dated between start and end that the technician with the given id is assigned to
+func (t *TechniciansController) schedule(ctx context.Context, id string, start, end time.Time) ([]models.Appointment, error) {
+	technician, err := t.DB.GetTechnician(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	appointments, err := t.Appointments.GetAppointmentsWithinDateRange(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	schedule := []models.Appointment{}
+	for _, appointment := range *appointments {
+		if appointment.Status == models.StatusCancelled || appointment.Status == models.StatusNoShow {
+			continue
+		}
+		for _, assigned := range appointment.TechnicianIDs {
+			if assigned == technician.ID {
+				schedule = append(schedule, appointment)
+				break
+			}
+		}
+	}
+	return schedule, nil
+}
+
+// AssignTechnician - assigns the technician in the path to the appointment and returns the updated appointment.
+// When scheduling is configured the technician must be working and free for the whole appointment.
+func (a *AppointmentsController) AssignTechnician(w http.ResponseWriter, r *http.Request) {
+	status, response := a.assignTechnician(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "technicianID"))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// assignTechnician - adds technicianID to the technicians of the appointment with the given id once it is known to be free,
+// and returns the status and body to respond with
+func (a *AppointmentsController) assignTechnician(ctx context.Context, id, technicianID string) (int, []byte) {
+	technician, err := a.Technicians.GetTechnician(ctx, technicianID)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	a.scheduleMu.Lock()
+	defer a.scheduleMu.Unlock()
+
+	appointment, err := a.DB.GetAppointment(ctx, id)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	for _, assigned := range appointment.TechnicianIDs {
+		if assigned == technician.ID {
+			return marshalAppointment(appointment)
+		}
+	}
+	if a.Scheduler != nil {
+		start, end := a.Scheduler.Window(appointment.Date)
+		existing, err := a.DB.GetAppointmentsWithinDateRange(ctx, start, end)
+		if err != nil {
+			return dbErrorResponse(err)
+		}
+		if status, response := a.checkTechnicians(ctx, *appointment, []primitive.ObjectID{technician.ID}, *existing); status != 0 {
+			return status, response
+		}
+	}
+
+	before, err := a.DB.AssignAppointmentTechnician(ctx, id, technician.ID)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	updated := *before
+	updated.TechnicianIDs = models.WithTechnician(before.TechnicianIDs, technician.ID)
+	a.audit(ctx, models.AuditUpdated, before, &updated)
+	return marshalAppointment(&updated)
+}
+
+// UnassignTechnician - removes the technician in the path from the appointment and returns the updated appointment
+func (a *AppointmentsController) UnassignTechnician(w http.ResponseWriter, r *http.Request) {
+	status, response := a.unassignTechnician(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "technicianID"))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// unassignTechnician - removes technicianID from the technicians of the appointment with the given id,
+// and returns the status and body to respond with
+func (a *AppointmentsController) unassignTechnician(ctx context.Context, id, technicianID string) (int, []byte) {
+	a.scheduleMu.Lock()
+	defer a.scheduleMu.Unlock()
+
+	appointment, err := a.DB.GetAppointment(ctx, id)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	var unassigned primitive.ObjectID
+	for _, assigned := range appointment.TechnicianIDs {
+		if assigned.Hex() == technicianID {
+			unassigned = assigned
+		}
+	}
+	if unassigned.IsZero() {
+		return http.StatusNotFound, errorJSON(fmt.Sprintf("technician %v is not assigned to appointment %v", technicianID, id))
+	}
+
+	before, err := a.DB.UnassignAppointmentTechnician(ctx, id, unassigned)
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	updated := *before
+	updated.TechnicianIDs = models.WithoutTechnician(before.TechnicianIDs, unassigned)
+	a.audit(ctx, models.AuditUpdated, before, &updated)
+	return marshalAppointment(&updated)
+}
+
+// checkTechnicians - returns a non-zero status and its body if any of the technicians with the given ids doesn't exist
+// or can't work on appointment alongside existing. Callers must hold scheduleMu until the appointment is written.
+func (a *AppointmentsController) checkTechnicians(ctx context.Context, appointment models.Appointment, ids []primitive.ObjectID, existing []models.Appointment) (int, []byte) {
+	for _, id := range ids {
+		technician, err := a.Technicians.GetTechnician(ctx, id.Hex())
+		if err != nil {
+			return dbErrorResponse(missingReference(err, "technician", id.Hex()))
+		}
+		err = a.Scheduler.CheckTechnician(appointment, *technician, existing)
+		switch {
+		case errors.Is(err, scheduling.ErrTechnicianOffDuty), errors.Is(err, scheduling.ErrTechnicianBusy):
+			return http.StatusConflict, errorJSON(fmt.Sprintf("%v: %v", technician.Name, err))
+		case err != nil:
+			return http.StatusBadRequest, errorJSON(err.Error())
+		}
+	}
+	return 0, nil
+}
+
+// marshalAppointment - the status and body of a successful response carrying appointment
+func marshalAppointment(appointment *models.Appointment) (int, []byte) {
+	response, err := json.Marshal(appointment)
+	if err != nil {
+		log.Println("error marshaling appointment", err)
+	}
+	return http.StatusOK, response
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/scheduling"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// serveAssignment - sends an assignment request for the appointment and technician with the given ids to handler
+func serveAssignment(handler http.HandlerFunc, method, id, technicianID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/appointment/"+id+"/technicians/"+technicianID, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	rctx.URLParams.Add("technicianID", technicianID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCreateTechnician(t *testing.T) {
+	techniciansController := TechniciansController{DB: db.NewMemoryStore()}
+
+	rr := serveWithID(techniciansController.CreateTechnician, "POST", "",
+		`{"name":"Sam Rivera","skills":["brakes"],"certifications":[{"name":"ASE A5"}],"working_hours":{"monday":{"start":"08:00","end":"16:00"}}}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+
+	tests := map[string]struct {
+		body     string
+		expected string
+	}{
+		"no name":     {`{"skills":["brakes"]}`, `{"error":"technician must have a name"}`},
+		"bad email":   {`{"name":"Sam","email":"sam"}`, `{"error":"\"sam\" is not a valid email address"}`},
+		"bad weekday": {`{"name":"Sam","working_hours":{"Monday":{"start":"08:00","end":"16:00"}}}`, `{"error":"working_hours key \"Monday\" must be a lower-case weekday"}`},
+		"shift too short": {`{"name":"Sam","working_hours":{"monday":{"start":"16:00","end":"08:00"}}}`,
+			`{"error":"working_hours for monday must start before they end, as \"15:04\" times"}`},
+	}
+	for name, test := range tests {
+		rr := serveWithID(techniciansController.CreateTechnician, "POST", "", test.body)
+		if rr.Code != http.StatusBadRequest || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v", name, rr.Code, rr.Body.String(), test.expected)
+		}
+	}
+}
+
+func TestAssignTechnician(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	cfg := config.Default().Scheduling
+	cfg.Bays = 2
+	scheduler, err := scheduling.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler.Now = func() time.Time { return time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC) }
+	appointmentsController := AppointmentsController{DB: store, Scheduler: scheduler, Technicians: store}
+	techniciansController := TechniciansController{DB: store, Appointments: store}
+
+	technician, _ := store.CreateTechnician(ctx, models.Technician{
+		Name:         "Sam Rivera",
+		WorkingHours: map[string]models.Shift{"wednesday": {Start: "08:00", End: "12:00"}},
+	})
+	book := func(hour, minute int) *models.Appointment {
+		date := time.Date(2019, 8, 28, hour, minute, 0, 0, time.UTC)
+		appointment, err := store.CreateAppointment(ctx, models.Appointment{Name: "Service", Description: "visit", Status: models.StatusOpen, Date: date})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return appointment
+	}
+	first, overlapping, afternoon := book(9, 0), book(9, 30), book(13, 0)
+
+	rr := serveAssignment(appointmentsController.AssignTechnician, "PUT", first.ID.Hex(), technician.ID.Hex())
+	var assigned models.Appointment
+	json.Unmarshal(rr.Body.Bytes(), &assigned)
+	if rr.Code != http.StatusOK || len(assigned.TechnicianIDs) != 1 || assigned.TechnicianIDs[0] != technician.ID {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+	if rr := serveAssignment(appointmentsController.AssignTechnician, "PUT", first.ID.Hex(), technician.ID.Hex()); rr.Code != http.StatusOK {
+		t.Errorf("assigning twice should be a no-op, got %v %v", rr.Code, rr.Body.String())
+	}
+
+	tests := map[string]struct {
+		id, technicianID string
+		status           int
+		expected         string
+	}{
+		"busy": {overlapping.ID.Hex(), technician.ID.Hex(), http.StatusConflict,
+			`{"error":"Sam Rivera: technician is assigned to another appointment at that time"}`},
+		"off duty": {afternoon.ID.Hex(), technician.ID.Hex(), http.StatusConflict, `{"error":"Sam Rivera: technician is not working then"}`},
+		"unknown technician": {first.ID.Hex(), "5d66f16e7c0e4a5d3c9f1a2b", http.StatusNotFound,
+			`{"error":"technician 5d66f16e7c0e4a5d3c9f1a2b not found"}`},
+	}
+	for name, test := range tests {
+		rr := serveAssignment(appointmentsController.AssignTechnician, "PUT", test.id, test.technicianID)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/technicians/"+technician.ID.Hex()+"/schedule?start=2019-08-28T00:00:00Z&end=2019-08-29T00:00:00Z", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", technician.ID.Hex())
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(techniciansController.GetTechnicianSchedule).ServeHTTP(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+	var schedule []models.Appointment
+	json.Unmarshal(rr.Body.Bytes(), &schedule)
+	if rr.Code != http.StatusOK || len(schedule) != 1 || schedule[0].ID != first.ID {
+		t.Errorf("got %v %v want only the assigned appointment", rr.Code, rr.Body.String())
+	}
+
+	if rr := serveWithID(techniciansController.DeleteTechnician, "DELETE", technician.ID.Hex(), ""); rr.Code != http.StatusConflict {
+		t.Errorf("deleting an assigned technician: got %v %v want %v", rr.Code, rr.Body.String(), http.StatusConflict)
+	}
+	rr = serveAssignment(appointmentsController.UnassignTechnician, "DELETE", first.ID.Hex(), technician.ID.Hex())
+	var unassigned models.Appointment
+	json.Unmarshal(rr.Body.Bytes(), &unassigned)
+	if rr.Code != http.StatusOK || len(unassigned.TechnicianIDs) != 0 {
+		t.Errorf("got %v %v", rr.Code, rr.Body.String())
+	}
+	if rr := serveAssignment(appointmentsController.UnassignTechnician, "DELETE", first.ID.Hex(), technician.ID.Hex()); rr.Code != http.StatusNotFound {
+		t.Errorf("unassigning twice: got %v %v want %v", rr.Code, rr.Body.String(), http.StatusNotFound)
+	}
+	if rr := serveAssignment(appointmentsController.AssignTechnician, "PUT", overlapping.ID.Hex(), technician.ID.Hex()); rr.Code != http.StatusOK {
+		t.Errorf("technician should be free once unassigned, got %v %v", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,323 @@
+package controller
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// UsersController - struct that has references to the staff account store and the tokens sessions are made of
+type UsersController struct {
+	DB     db.UserStore
+	Tokens *auth.Tokens
+}
+
+// newUserRequest - body of a request to create a staff account
+type newUserRequest struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
+
+// loginRequest - body of a request to sign in
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// refreshRequest - body of a request to extend a session
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// passwordRequest - body of a request to change a password
+type passwordRequest struct {
+	Password string `json:"password"`
+}
+
+// errInvalidLogin - the response to every failed sign in, so callers can't tell which usernames exist
+const errInvalidLogin = "invalid username or password"
+
+// normalizeUsername - usernames are matched case-insensitively and stored in lower case
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// prepareUser - normalizes user's username and gives users without a role the read-only one
+func prepareUser(user *models.User) {
+	user.Username = normalizeUsername(user.Username)
+	if user.Role == "" {
+		user.Role = models.RoleReadOnly
+	}
+}
+
+// validateUser - returns a description of the first problem with user, or nil if it can be stored
+func validateUser(user models.User) error {
+	switch {
+	case user.Username == "":
+		return errors.New("user must have a username")
+	case strings.ContainsAny(user.Username, " \t\r\n"):
+		return errors.New("username must not contain spaces")
+	case strings.TrimSpace(user.Name) == "":
+		return errors.New("user must have a name")
+	case !models.ValidRole(user.Role):
+		return fmt.Errorf("role must be one of %v", strings.Join(models.Roles(), ", "))
+	}
+	return nil
+}
+
+// Login - accepts a username and password and returns an access and refresh token for the user
+func (u *UsersController) Login(w http.ResponseWriter, r *http.Request) {
+	var request loginRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Username == "" || request.Password == "" {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must contain a username and password")
+	} else if user, err := u.DB.GetUserByUsername(r.Context(), normalizeUsername(request.Username)); err != nil && !errors.Is(err, db.ErrNotFound) {
+		status, response = dbErrorResponse(err)
+	} else if user == nil || !auth.CheckPassword(user.PasswordHash, request.Password) || user.Disabled {
+		if user == nil {
+			// spend as long as a real comparison would
+			auth.CheckPassword("", request.Password)
+		}
+		status = http.StatusUnauthorized
+		response = errorJSON(errInvalidLogin)
+	} else {
+		status, response = u.issue(*user)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// Refresh - accepts a refresh token and returns a new access and refresh token, as long as its user can still sign in
+// and hasn't changed their password since the token was issued
+func (u *UsersController) Refresh(w http.ResponseWriter, r *http.Request) {
+	var request refreshRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.RefreshToken == "" {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must contain a refresh_token")
+	} else if claims, err := u.Tokens.Verify(request.RefreshToken, auth.UseRefresh); err != nil {
+		status = http.StatusUnauthorized
+		response = errorJSON(err.Error())
+	} else if user, err := u.DB.GetUser(r.Context(), claims.Subject); errors.Is(err, db.ErrNotFound) || (err == nil && user.Disabled) {
+		status = http.StatusUnauthorized
+		response = errorJSON(fmt.Sprintf("user %v can no longer sign in", claims.Username))
+	} else if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if claims.Version != user.TokenVersion {
+		status = http.StatusUnauthorized
+		response = errorJSON(fmt.Sprintf("the password of user %v has changed since this session began, sign in again", claims.Username))
+	} else {
+		status, response = u.issue(*user)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// issue - the status and body of a response carrying a new session for user
+func (u *UsersController) issue(user models.User) (int, []byte) {
+	tokens, err := u.Tokens.Issue(user)
+	if err != nil {
+		log.Println("error issuing tokens", err)
+		return http.StatusInternalServerError, errorJSON("internal server error")
+	}
+	response, err := json.Marshal(tokens)
+	if err != nil {
+		log.Println("error marshaling tokens", err)
+	}
+	return http.StatusOK, response
+}
+
+// CreateUser - accepts a username, name, role and password and returns the new staff account; the role defaults to read-only
+func (u *UsersController) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var request newUserRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	user := models.User{Username: request.Username, Name: request.Name, Role: request.Role, CreatedAt: time.Now().UTC()}
+	prepareUser(&user)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid user")
+	} else if err := validateUser(user); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if user.PasswordHash, err = auth.HashPassword(request.Password); err != nil {
+		status, response = passwordErrorResponse(err)
+	} else if created, err := u.DB.CreateUser(r.Context(), user); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(created)
+		if err != nil {
+			log.Println("error marshaling user", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// changePassword - sets the password of user and bumps its token version, so sessions issued before can't be refreshed
+func changePassword(user *models.User, password string) error {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	user.TokenVersion++
+	return nil
+}
+
+// passwordErrorResponse - maps an error from auth.HashPassword to an http status and JSON body
+func passwordErrorResponse(err error) (int, []byte) {
+	if errors.Is(err, auth.ErrPasswordTooShort) || errors.Is(err, auth.ErrPasswordTooLong) {
+		return http.StatusBadRequest, errorJSON(err.Error())
+	}
+	log.Println("error hashing password", err)
+	return http.StatusInternalServerError, errorJSON("internal server error")
+}
+
+// GetUser - accepts user id and returns the specified staff account
+func (u *UsersController) GetUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	user, err := u.DB.GetUser(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(user)
+		if err != nil {
+			log.Println("error marshaling user", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListUsers - returns every staff account ordered by username
+func (u *UsersController) ListUsers(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	users, err := u.DB.ListUsers(r.Context())
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(users)
+		if err != nil {
+			log.Println("error marshaling users", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// UpdateUser - accepts id and either a whole user (PUT) or a JSON Merge Patch of one (PATCH) and returns the updated
+// staff account. Passwords are changed through SetPassword.
+func (u *UsersController) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	user, err := u.DB.GetUser(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := decodeUser(r, user); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if updated, err := u.DB.UpdateUser(r.Context(), *user); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(updated)
+		if err != nil {
+			log.Println("error marshaling user", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// decodeUser - applies the body of a PUT or PATCH request to user, keeping the fields requests can't set, and validates the result
+func decodeUser(r *http.Request, user *models.User) error {
+	passwordHash, tokenVersion, createdAt := user.PasswordHash, user.TokenVersion, user.CreatedAt
+	if err := decodeUpdate(r, user, &user.ID, "user"); err != nil {
+		return err
+	}
+	user.PasswordHash, user.TokenVersion, user.CreatedAt = passwordHash, tokenVersion, createdAt
+	prepareUser(user)
+	return validateUser(*user)
+}
+
+// SetPassword - accepts user id and a new password for the staff account and ends the sessions it already has;
+// users other than admins may only change their own
+func (u *UsersController) SetPassword(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var request passwordRequest
+	status := http.StatusOK
+	response := []byte(fmt.Sprintf("password of user %v successfully changed", id))
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if claims, ok := auth.ClaimsFrom(r.Context()); ok && claims.Role != models.RoleAdmin && claims.Subject != id {
+		status = http.StatusForbidden
+		response = errorJSON("only admins may change other users' passwords")
+	} else if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must contain a password")
+	} else if user, err := u.DB.GetUser(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := changePassword(user, request.Password); err != nil {
+		status, response = passwordErrorResponse(err)
+	} else if _, err := u.DB.UpdateUser(r.Context(), *user); err != nil {
+		status, response = dbErrorResponse(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// DeleteUser - accepts user id of the staff account to be deleted; its sessions can't be refreshed afterwards
+func (u *UsersController) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte(fmt.Sprintf("user %v successfully deleted", id))
+
+	if err := u.DB.DeleteUser(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+func newUsersController(t *testing.T) UsersController {
+	tokens, err := auth.NewTokens(config.Default().Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return UsersController{DB: db.NewMemoryStore(), Tokens: tokens}
+}
+
+func TestCreateUser(t *testing.T) {
+	usersController := newUsersController(t)
+
+	rr := serveWithID(usersController.CreateUser, "POST", "", `{"username":" Advisor ","name":"Sam Advisor","password":"correct horse"}`)
+	var user models.User
+	if err := json.Unmarshal(rr.Body.Bytes(), &user); rr.Code != http.StatusOK || err != nil || user.Username != "advisor" || user.Role != models.RoleReadOnly {
+		t.Fatalf("got %v %v want the user created read-only with a lower case username", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "password") {
+		t.Errorf("response %v exposes the password hash", rr.Body.String())
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		expected string
+	}{
+		{"no username", `{"name":"Sam","password":"correct horse"}`, http.StatusBadRequest, `{"error":"user must have a username"}`},
+		{"space in username", `{"username":"sam advisor","name":"Sam","password":"correct horse"}`, http.StatusBadRequest, `{"error":"username must not contain spaces"}`},
+		{"unknown role", `{"username":"sam","name":"Sam","role":"owner","password":"correct horse"}`,
+			http.StatusBadRequest, `{"error":"role must be one of admin, advisor, technician, read_only"}`},
+		{"short password", `{"username":"sam","name":"Sam","password":"short"}`, http.StatusBadRequest, `{"error":"password must be at least 8 characters"}`},
+		{"duplicate username", `{"username":"ADVISOR","name":"Other","password":"correct horse"}`,
+			http.StatusConflict, `{"error":"username advisor is already taken: conflict"}`},
+	}
+	for _, test := range tests {
+		rr := serveWithID(usersController.CreateUser, "POST", "", test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", test.name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
+
+func TestLoginAndRefresh(t *testing.T) {
+	usersController := newUsersController(t)
+	ctx := context.Background()
+	hash, _ := auth.HashPassword("correct horse")
+	user, _ := usersController.DB.CreateUser(ctx, models.User{Username: "advisor", Name: "Sam Advisor", PasswordHash: hash})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"wrong password", `{"username":"advisor","password":"wrong horse"}`, http.StatusUnauthorized},
+		{"unknown user", `{"username":"nobody","password":"correct horse"}`, http.StatusUnauthorized},
+		{"no password", `{"username":"advisor"}`, http.StatusBadRequest},
+		{"mixed case username", `{"username":"Advisor","password":"correct horse"}`, http.StatusOK},
+	}
+	for _, test := range tests {
+		rr := serveWithID(usersController.Login, "POST", "", test.body)
+		if rr.Code != test.status {
+			t.Errorf("%v: got %v %v want %v", test.name, rr.Code, rr.Body.String(), test.status)
+		}
+	}
+
+	rr := serveWithID(usersController.Login, "POST", "", `{"username":"advisor","password":"correct horse"}`)
+	var session auth.TokenPair
+	json.Unmarshal(rr.Body.Bytes(), &session)
+	if claims, err := usersController.Tokens.Verify(session.AccessToken, auth.UseAccess); err != nil || claims.Subject != user.ID.Hex() {
+		t.Fatalf("got claims %+v error %v want an access token for %v", claims, err, user.ID.Hex())
+	}
+
+	rr = serveWithID(usersController.Refresh, "POST", "", `{"refresh_token":"`+session.AccessToken+`"}`)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("refresh with access token got %v %v want %v", rr.Code, rr.Body.String(), http.StatusUnauthorized)
+	}
+	rr = serveWithID(usersController.Refresh, "POST", "", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("refresh got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+
+	user.Disabled = true
+	if _, err := usersController.DB.UpdateUser(ctx, *user); err != nil {
+		t.Fatal(err)
+	}
+	rr = serveWithID(usersController.Refresh, "POST", "", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	if rr.Code != http.StatusUnauthorized || rr.Body.String() != `{"error":"user advisor can no longer sign in"}` {
+		t.Errorf("refresh of disabled user got %v %v want %v", rr.Code, rr.Body.String(), http.StatusUnauthorized)
+	}
+	rr = serveWithID(usersController.Login, "POST", "", `{"username":"advisor","password":"correct horse"}`)
+	if rr.Code != http.StatusUnauthorized || rr.Body.String() != `{"error":"invalid username or password"}` {
+		t.Errorf("login of disabled user got %v %v want %v", rr.Code, rr.Body.String(), http.StatusUnauthorized)
+	}
+}
+
+func TestSetPassword(t *testing.T) {
+	usersController := newUsersController(t)
+	ctx := context.Background()
+	sam, _ := usersController.DB.CreateUser(ctx, models.User{Username: "sam", Name: "Sam", Role: models.RoleTechnician})
+	alex, _ := usersController.DB.CreateUser(ctx, models.User{Username: "alex", Name: "Alex", Role: models.RoleAdmin})
+	asUser := func(user *models.User, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/users/"+id+"/password", strings.NewReader(`{"password":"correct horse"}`))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", id)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+		ctx = auth.WithClaims(ctx, &auth.Claims{Subject: user.ID.Hex(), Username: user.Username, Role: user.Role})
+		rr := httptest.NewRecorder()
+		usersController.SetPassword(rr, req.WithContext(ctx))
+		return rr
+	}
+	session, err := usersController.Tokens.Issue(*sam)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rr := asUser(sam, sam.ID.Hex()); rr.Code != http.StatusOK {
+		t.Errorf("changing own password got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+	if rr := asUser(sam, alex.ID.Hex()); rr.Code != http.StatusForbidden {
+		t.Errorf("technician changing another password got %v %v want %v", rr.Code, rr.Body.String(), http.StatusForbidden)
+	}
+	if rr := asUser(alex, sam.ID.Hex()); rr.Code != http.StatusOK {
+		t.Errorf("admin changing another password got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+	updated, _ := usersController.DB.GetUser(ctx, sam.ID.Hex())
+	if !auth.CheckPassword(updated.PasswordHash, "correct horse") {
+		t.Error("password was not changed")
+	}
+
+	rr := serveWithID(usersController.Refresh, "POST", "", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("refresh of a session from before the password changed got %v %v want %v", rr.Code, rr.Body.String(), http.StatusUnauthorized)
+	}
+	session, err = usersController.Tokens.Issue(*updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = serveWithID(usersController.Refresh, "POST", "", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("refresh of a session from after the password changed got %v %v want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+}
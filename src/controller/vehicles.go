@@ -0,0 +1,262 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/vin"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// firstModelYear - no vehicle can be older than this
+const firstModelYear = 1886
+
+// VehiclesController - struct that has references to the vehicle store and the customers and appointments vehicles relate to
+type VehiclesController struct {
+	DB db.VehicleStore
+	// Customers - owners must exist
+	Customers db.CustomerStore
+	// Appointments - searched for a vehicle's history, and so vehicles that have appointments can't be deleted
+	Appointments db.ClientInterface
+}
+
+// prepareVehicle - brings the VIN and plate of vehicle into the form they are stored and searched in and fills in a missing
+// make and year from the VIN, then returns a description of the first problem with vehicle, or nil if it can be stored
+func prepareVehicle(vehicle *models.Vehicle) error {
+	vehicle.VIN = models.NormalizeVIN(vehicle.VIN)
+	vehicle.Plate = models.NormalizePlate(vehicle.Plate)
+	if vehicle.VIN == "" {
+		return errors.New("vehicle must have a vin")
+	}
+	decoded, err := vin.Decode(vehicle.VIN)
+	if err != nil {
+		return err
+	}
+	if vehicle.Make == "" {
+		vehicle.Make = decoded.Make
+	}
+	if vehicle.Year == 0 {
+		vehicle.Year = decoded.ModelYear
+	}
+
+	switch {
+	case vehicle.CustomerID.IsZero():
+		return errors.New("vehicle must have a customer_id")
+	case vehicle.Make == "" || vehicle.Model == "":
+		return errors.New("vehicle must have a make and model")
+	case vehicle.Year < firstModelYear || vehicle.Year > time.Now().Year()+1:
+		return fmt.Errorf("year must be between %d and %d", firstModelYear, time.Now().Year()+1)
+	case vehicle.Mileage < 0:
+		return errors.New("mileage must not be negative")
+	}
+	return nil
+}
+
+// checkOwner - returns errMissingReference if vehicle's customer doesn't exist
+func (v *VehiclesController) checkOwner(ctx context.Context, vehicle models.Vehicle) error {
+	_, err := v.Customers.GetCustomer(ctx, vehicle.CustomerID.Hex())
+	return missingReference(err, "customer", vehicle.CustomerID.Hex())
+}
+
+// CreateVehicle - accepts a vehicle and returns it with its generated id
+func (v *VehiclesController) CreateVehicle(w http.ResponseWriter, r *http.Request) {
+	var vehicle models.Vehicle
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&vehicle)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid vehicle")
+	} else if err := prepareVehicle(&vehicle); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if err := v.checkOwner(r.Context(), vehicle); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if created, err := v.DB.CreateVehicle(r.Context(), vehicle); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(created)
+		if err != nil {
+			log.Println("error marshaling vehicle", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// GetVehicle - accepts vehicle id and returns the specified vehicle
+func (v *VehiclesController) GetVehicle(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	vehicle, err := v.DB.GetVehicle(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(vehicle)
+		if err != nil {
+			log.Println("error marshaling vehicle", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListVehicles - returns every vehicle matching the customer, vin and plate query parameters in the order they were added
+func (v *VehiclesController) ListVehicles(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+	values := r.URL.Query()
+	filter := models.VehicleFilter{
+		VIN:   models.NormalizeVIN(values.Get("vin")),
+		Plate: models.NormalizePlate(values.Get("plate")),
+	}
+
+	customerID, err := parseIDParam(values, "customer")
+	filter.CustomerID = customerID
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if vehicles, err := v.DB.ListVehicles(r.Context(), filter); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(vehicles)
+		if err != nil {
+			log.Println("error marshaling vehicles", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// UpdateVehicle - accepts id and either a whole vehicle (PUT) or a JSON Merge Patch of one (PATCH) and returns the updated vehicle
+func (v *VehiclesController) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	vehicle, err := v.DB.GetVehicle(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := decodeUpdate(r, vehicle, &vehicle.ID, "vehicle"); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if err := prepareVehicle(vehicle); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if err := v.checkOwner(r.Context(), *vehicle); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if updated, err := v.DB.UpdateVehicle(r.Context(), *vehicle); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(updated)
+		if err != nil {
+			log.Println("error marshaling vehicle", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// DeleteVehicle - accepts vehicle id to be deleted; vehicles that have appointments are kept
+func (v *VehiclesController) DeleteVehicle(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte(fmt.Sprintf("vehicle %v successfully deleted", id))
+
+	if history, err := v.history(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if len(history) != 0 {
+		status, response = dbErrorResponse(fmt.Errorf("vehicle %v still has appointments: %w", id, db.ErrConflict))
+	} else if err := v.DB.DeleteVehicle(r.Context(), id); err != nil {
+		status, response = dbErrorResponse(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// GetVehicleHistory - accepts vehicle id and returns every appointment ever booked for it, oldest first
+func (v *VehiclesController) GetVehicleHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	history, err := v.history(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(history)
+		if err != nil {
+			log.Println("error marshaling vehicle history", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// history - every appointment for the vehicle with the given id ordered by date, reading as many pages as it takes
+func (v *VehiclesController) history(ctx context.Context, id string) ([]models.Appointment, error) {
+	vehicle, err := v.DB.GetVehicle(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	query := models.AppointmentQuery{
+		Filter: models.AppointmentFilter{VehicleID: &vehicle.ID},
+		SortBy: models.SortByDate,
+		Limit:  models.MaxPageSize,
+	}
+	appointments := []models.Appointment{}
+	for {
+		page, err := v.Appointments.ListAppointments(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, page.Appointments...)
+		if page.Next == "" {
+			return appointments, nil
+		}
+		query.After = page.Next
+	}
+}
+
+// DecodeVIN - accepts a VIN and returns the manufacturer, country, model year and plant it encodes
+func (v *VehiclesController) DecodeVIN(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	info, err := vin.Decode(chi.URLParam(r, "vin"))
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else {
+		response, err = json.Marshal(info)
+		if err != nil {
+			log.Println("error marshaling vin", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
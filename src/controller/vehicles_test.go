@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateVehicle(t *testing.T) {
+	store := db.NewMemoryStore()
+	vehiclesController := VehiclesController{DB: store, Customers: store, Appointments: store}
+	customer, err := store.CreateCustomer(context.Background(), models.Customer{Name: "Jane Doe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := customer.ID.Hex()
+
+	rr := serveWithID(vehiclesController.CreateVehicle, "POST", "",
+		`{"customer_id":"`+owner+`","vin":" 1hgcm82633a004352","make":"Honda","model":"Accord","year":2003,"plate":"abc-123"}`)
+	var vehicle models.Vehicle
+	json.Unmarshal(rr.Body.Bytes(), &vehicle)
+	if rr.Code != http.StatusOK || vehicle.VIN != "1HGCM82633A004352" || vehicle.Plate != "ABC123" {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+
+	tests := map[string]struct {
+		body     string
+		status   int
+		expected string
+	}{
+		"duplicate vin": {`{"customer_id":"` + owner + `","vin":"1HGCM82633A004352","make":"Honda","model":"Civic","year":2005}`,
+			http.StatusConflict, `{"error":"a vehicle with VIN 1HGCM82633A004352 already exists: conflict"}`},
+		"unknown owner": {`{"customer_id":"5d66f16e7c0e4a5d3c9f1a2b","vin":"JH4KA7561PC008269","make":"Acura","model":"Legend","year":1993}`,
+			http.StatusBadRequest, `{"error":"customer 5d66f16e7c0e4a5d3c9f1a2b does not exist"}`},
+		"no owner": {`{"vin":"JH4KA7561PC008269","make":"Acura","model":"Legend","year":1993}`,
+			http.StatusBadRequest, `{"error":"vehicle must have a customer_id"}`},
+		"bad year": {`{"customer_id":"` + owner + `","vin":"JH4KA7561PC008269","make":"Acura","model":"Legend","year":93}`,
+			http.StatusBadRequest, ""},
+	}
+	for name, test := range tests {
+		rr := serveWithID(vehiclesController.CreateVehicle, "POST", "", test.body)
+		if rr.Code != test.status || test.expected != "" && rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
+
+func TestVehicleHistory(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	vehiclesController := VehiclesController{DB: store, Customers: store, Appointments: store}
+	appointmentsController := AppointmentsController{DB: store, Customers: store, Vehicles: store}
+
+	customer, _ := store.CreateCustomer(ctx, models.Customer{Name: "Jane Doe"})
+	otherCustomer, _ := store.CreateCustomer(ctx, models.Customer{Name: "Adam Smith"})
+	vehicle, err := store.CreateVehicle(ctx, models.Vehicle{CustomerID: customer.ID, VIN: "1HGCM82633A004352", Make: "Honda", Model: "Accord", Year: 2003})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, date := range []string{"2019-09-02T09:00:00Z", "2019-08-28T09:00:00Z"} {
+		body := `{"name":"Service","description":"visit","date":"` + date + `","vehicle_id":"` + vehicle.ID.Hex() + `"}`
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(appointmentsController.CreateAppointment).ServeHTTP(rr, httptest.NewRequest("POST", "/appointment/", strings.NewReader(body)))
+		var created models.Appointment
+		json.Unmarshal(rr.Body.Bytes(), &created)
+		if rr.Code != http.StatusOK || created.CustomerID == nil || *created.CustomerID != customer.ID {
+			t.Fatalf("appointment %d: got %v %v, want it booked for the vehicle's owner", i, rr.Code, rr.Body.String())
+		}
+	}
+	body := `{"name":"Service","description":"visit","date":"2019-08-28T09:00:00Z","vehicle_id":"` + vehicle.ID.Hex() + `","customer_id":"` + otherCustomer.ID.Hex() + `"}`
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(appointmentsController.CreateAppointment).ServeHTTP(rr, httptest.NewRequest("POST", "/appointment/", strings.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("someone else's vehicle: got %v %v want %v", rr.Code, rr.Body.String(), http.StatusBadRequest)
+	}
+	store.CreateAppointment(ctx, models.Appointment{Name: "Other car", Date: time.Now()})
+
+	rr = serveWithID(vehiclesController.GetVehicleHistory, "GET", vehicle.ID.Hex(), "")
+	var history []models.Appointment
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if rr.Code != http.StatusOK || len(history) != 2 || !history[0].Date.Before(history[1].Date) {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+
+	rr = serveWithID(vehiclesController.DeleteVehicle, "DELETE", vehicle.ID.Hex(), "")
+	if rr.Code != http.StatusConflict {
+		t.Errorf("deleting a vehicle with appointments: got %v %v want %v", rr.Code, rr.Body.String(), http.StatusConflict)
+	}
+	rr = serveWithID(vehiclesController.GetVehicleHistory, "GET", "5d66f16e7c0e4a5d3c9f1a2b", "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown vehicle: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestCreateVehicleDecodesVIN(t *testing.T) {
+	store := db.NewMemoryStore()
+	vehiclesController := VehiclesController{DB: store, Customers: store, Appointments: store}
+	customer, _ := store.CreateCustomer(context.Background(), models.Customer{Name: "Jane Doe"})
+	owner := customer.ID.Hex()
+
+	rr := serveWithID(vehiclesController.CreateVehicle, "POST", "", `{"customer_id":"`+owner+`","vin":"1HGCM82633A004352","model":"Accord"}`)
+	var vehicle models.Vehicle
+	json.Unmarshal(rr.Body.Bytes(), &vehicle)
+	if rr.Code != http.StatusOK || vehicle.Make != "Honda" || vehicle.Year != 2003 {
+		t.Errorf("make and year should be filled in from the VIN, got %v %v", rr.Code, rr.Body.String())
+	}
+
+	rr = serveWithID(vehiclesController.CreateVehicle, "POST", "", `{"customer_id":"`+owner+`","vin":"1HGCM82633A004353","make":"Honda","model":"Accord","year":2003}`)
+	expected := `{"error":"invalid VIN \"1HGCM82633A004353\": check digit is 3 but should be 5"}`
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != expected {
+		t.Errorf("got %v %v want %v %v", rr.Code, rr.Body.String(), http.StatusBadRequest, expected)
+	}
+}
+
+func TestDecodeVIN(t *testing.T) {
+	vehiclesController := VehiclesController{}
+
+	rr := serveWithParam(vehiclesController.DecodeVIN, "GET", "vin", "JH4KA7561PC008269", "")
+	expected := `{"vin":"JH4KA7561PC008269","wmi":"JH4","make":"Acura","country":"Japan","model_year":1993,"plant_code":"C","serial_number":"008269"}`
+	if rr.Code != http.StatusOK || rr.Body.String() != expected {
+		t.Errorf("got %v %v want %v", rr.Code, rr.Body.String(), expected)
+	}
+	rr = serveWithParam(vehiclesController.DecodeVIN, "GET", "vin", "JH4KA7561PC00826", "")
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != `{"error":"invalid VIN \"JH4KA7561PC00826\": must be 17 characters long"}` {
+		t.Errorf("got %v %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCreateAppointmentForVehicleWithInvalidVIN(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	appointmentsController := AppointmentsController{DB: store, Customers: store, Vehicles: store}
+	customer, _ := store.CreateCustomer(ctx, models.Customer{Name: "Jane Doe"})
+	// stored directly, the way vehicles registered before VIN validation were
+	vehicle, err := store.CreateVehicle(ctx, models.Vehicle{CustomerID: customer.ID, VIN: "1HGCM82633A004353", Make: "Honda", Model: "Accord", Year: 2003})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"name":"Service","description":"visit","date":"2019-08-28T09:00:00Z","vehicle_id":"` + vehicle.ID.Hex() + `"}`
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(appointmentsController.CreateAppointment).ServeHTTP(rr, httptest.NewRequest("POST", "/appointment/", strings.NewReader(body)))
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "invalid VIN") {
+		t.Errorf("got %v %v want %v", rr.Code, rr.Body.String(), http.StatusBadRequest)
+	}
+}
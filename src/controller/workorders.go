@@ -0,0 +1,535 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/money"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// workOrderRetries - how many times a change is retried when another request updated the work order first
+const workOrderRetries = 3
+
+// WorkOrdersController - struct that has references to the work order store and the records work orders are built from
+type WorkOrdersController struct {
+	DB           db.WorkOrderStore
+	Appointments db.ClientInterface
+	// Catalog - optional; when set, new work orders get lines for the catalog items their appointment was booked for
+	Catalog db.CatalogStore
+	// Technicians - optional; when set, time entries may only be logged for technicians that exist
+	Technicians db.TechnicianStore
+	// Parts - optional; when set, stocked parts are reserved as part lines are added and released as they are removed
+	Parts db.PartStore
+}
+
+// workOrderResponse - a work order together with its totals
+type workOrderResponse struct {
+	models.WorkOrder
+	LaborTotal money.Amount `json:"labor_total"`
+	PartsTotal money.Amount `json:"parts_total"`
+	Total      money.Amount `json:"total"`
+}
+
+// newWorkOrderRequest - body of a request to open a work order
+type newWorkOrderRequest struct {
+	AppointmentID primitive.ObjectID `json:"appointment_id"`
+}
+
+// workOrderStatuses - the appointment statuses a work order can be opened in
+var workOrderStatuses = []string{models.StatusCheckedIn, models.StatusInProgress, models.StatusWaitingParts}
+
+// validateWorkOrderLine - returns a description of the first problem with line, or nil if it can be added to a work order
+func validateWorkOrderLine(line models.WorkOrderLine) error {
+	switch line.Type {
+	case models.LineLabor:
+		switch {
+		case strings.TrimSpace(line.Description) == "":
+			return errors.New("labor lines must have a description")
+		case line.Minutes <= 0:
+			return errors.New("minutes must be positive")
+		case line.Rate < 0:
+			return errors.New("rate must not be negative")
+		}
+	case models.LinePart:
+		switch {
+		case strings.TrimSpace(line.SKU) == "":
+			return errors.New("part lines must have a sku")
+		case line.Quantity <= 0:
+			return fmt.Errorf("quantity of part %v must be positive", line.SKU)
+		case line.UnitPrice < 0:
+			return fmt.Errorf("unit_price of part %v must not be negative", line.SKU)
+		}
+	default:
+		return fmt.Errorf("type must be %v or %v", models.LineLabor, models.LinePart)
+	}
+	return nil
+}
+
+// validateTimeEntry - returns a description of the first problem with entry, or nil if it can be logged
+func validateTimeEntry(entry models.TimeEntry) error {
+	switch {
+	case entry.TechnicianID.IsZero():
+		return errors.New("time entries must have a technician_id")
+	case entry.Start.IsZero() || !entry.End.After(entry.Start):
+		return errors.New("time entries must have a start before their end")
+	}
+	return nil
+}
+
+// catalogLines - the labor line and default part lines of a catalog item
+func catalogLines(item models.CatalogItem) []models.WorkOrderLine {
+	lines := []models.WorkOrderLine{{
+		ID:            primitive.NewObjectID(),
+		Type:          models.LineLabor,
+		CatalogItemID: &item.ID,
+		Description:   item.Name,
+		Minutes:       item.LaborMinutes,
+		Rate:          item.LaborRate,
+	}}
+	for _, part := range item.Parts {
+		lines = append(lines, models.WorkOrderLine{
+			ID:            primitive.NewObjectID(),
+			Type:          models.LinePart,
+			CatalogItemID: &item.ID,
+			Description:   part.Description,
+			SKU:           part.SKU,
+			Quantity:      part.Quantity,
+			UnitPrice:     part.UnitPrice,
+		})
+	}
+	return lines
+}
+
+// marshalWorkOrder - the status and body of a successful response carrying order and its totals
+func marshalWorkOrder(order *models.WorkOrder) (int, []byte) {
+	response, err := json.Marshal(workOrderResponse{
+		WorkOrder:  *order,
+		LaborTotal: order.Total(models.LineLabor),
+		PartsTotal: order.Total(models.LinePart),
+		Total:      order.Total(""),
+	})
+	if err != nil {
+		log.Println("error marshaling work order", err)
+	}
+	return http.StatusOK, response
+}
+
+// modifyWorkOrder - applies change to the work order with the given id and stores the result. If another request
+// updated the work order in the meantime it is read again and change reapplied, up to workOrderRetries times.
+func modifyWorkOrder(ctx context.Context, store db.WorkOrderStore, id string, change func(*models.WorkOrder) error) (*models.WorkOrder, error) {
+	var err error
+	for attempt := 0; attempt < workOrderRetries; attempt++ {
+		var order *models.WorkOrder
+		if order, err = store.GetWorkOrder(ctx, id); err != nil {
+			return nil, err
+		}
+		if err = change(order); err != nil {
+			return nil, err
+		}
+		order.UpdatedAt = time.Now().UTC()
+		var updated *models.WorkOrder
+		if updated, err = store.UpdateWorkOrder(ctx, *order); !errors.Is(err, db.ErrStale) {
+			return updated, err
+		}
+	}
+	return nil, err
+}
+
+// checkOpen - returns db.ErrConflict once order is closed
+func checkOpen(order *models.WorkOrder) error {
+	if order.Closed() {
+		return fmt.Errorf("work order %v is %v and can no longer change: %w", order.ID.Hex(), order.Status, db.ErrConflict)
+	}
+	return nil
+}
+
+// CreateWorkOrder - accepts the id of a checked-in appointment and returns a new work order for it, with lines for
+// the labor and default parts of every catalog item the appointment was booked for
+func (wo *WorkOrdersController) CreateWorkOrder(w http.ResponseWriter, r *http.Request) {
+	var request newWorkOrderRequest
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.AppointmentID.IsZero() {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must contain a valid appointment_id")
+	} else {
+		status, response = wo.createWorkOrder(r.Context(), request.AppointmentID.Hex())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// createWorkOrder - builds and stores the work order for the appointment with the given id, and returns the status and body to respond with
+func (wo *WorkOrdersController) createWorkOrder(ctx context.Context, appointmentID string) (int, []byte) {
+	appointment, err := wo.Appointments.GetAppointment(ctx, appointmentID)
+	if err != nil {
+		return dbErrorResponse(missingReference(err, "appointment", appointmentID))
+	}
+	if !containsStatus(workOrderStatuses, appointment.Status) {
+		return http.StatusConflict, errorJSON(fmt.Sprintf("appointment %v is %v; work orders can only be opened for appointments that are %v",
+			appointmentID, appointment.Status, strings.Join(workOrderStatuses, ", ")))
+	}
+
+	now := time.Now().UTC()
+	order := models.WorkOrder{
+		AppointmentID: appointment.ID,
+		CustomerID:    appointment.CustomerID,
+		VehicleID:     appointment.VehicleID,
+		Status:        appointment.Status,
+		StatusHistory: []models.StatusChange{{Status: appointment.Status, At: now}},
+		Lines:         []models.WorkOrderLine{},
+		TimeEntries:   []models.TimeEntry{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if wo.Catalog != nil {
+		for _, id := range appointment.ServiceIDs {
+			item, err := wo.Catalog.GetCatalogItem(ctx, id.Hex())
+			if err != nil {
+				return dbErrorResponse(missingReference(err, "catalog item", id.Hex()))
+			}
+			order.Lines = append(order.Lines, catalogLines(*item)...)
+		}
+	}
+
+	if err := reserveParts(ctx, wo.Parts, order.Lines); err != nil {
+		return dbErrorResponse(err)
+	}
+	created, err := wo.DB.CreateWorkOrder(ctx, order)
+	if err != nil {
+		releaseParts(ctx, wo.Parts, order.Lines)
+		return dbErrorResponse(err)
+	}
+	return marshalWorkOrder(created)
+}
+
+// containsStatus - reports whether statuses includes status
+func containsStatus(statuses []string, status string) bool {
+	for _, value := range statuses {
+		if value == status {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWorkOrder - accepts work order id and returns the specified work order with its totals
+func (wo *WorkOrdersController) GetWorkOrder(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := http.StatusOK
+	response := []byte{}
+
+	order, err := wo.DB.GetWorkOrder(r.Context(), id)
+	if err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		status, response = marshalWorkOrder(order)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ListWorkOrders - returns the work orders matching the appointment and status query parameters, oldest first
+func (wo *WorkOrdersController) ListWorkOrders(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	response := []byte{}
+
+	appointmentID, err := parseIDParam(r.URL.Query(), "appointment")
+	filter := models.WorkOrderFilter{AppointmentID: appointmentID, Status: r.URL.Query().Get("status")}
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if filter.Status != "" && !models.ValidStatus(filter.Status) {
+		status = http.StatusBadRequest
+		response = errorJSON(fmt.Sprintf("status must be one of %v", strings.Join(models.Statuses(), ", ")))
+	} else if orders, err := wo.DB.ListWorkOrders(r.Context(), filter); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		response, err = json.Marshal(orders)
+		if err != nil {
+			log.Println("error marshaling work orders", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// AddWorkOrderLine - accepts a labor or part line, adds it to the work order and returns the updated work order.
+// Labor lines for a catalog item default to the item's name, labor time and rate, and stocked parts are reserved.
+func (wo *WorkOrdersController) AddWorkOrderLine(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var line models.WorkOrderLine
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&line)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid work order line")
+	} else if err := wo.resolveCatalogLine(r.Context(), &line); err != nil {
+		status, response = dbErrorResponse(err)
+	} else if err := validateWorkOrderLine(line); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else {
+		line.ID = primitive.NewObjectID()
+		status, response = wo.addLine(r.Context(), id, line)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// addLine - reserves line's part, then adds it to the work order with the given id, releasing the part again if that fails
+func (wo *WorkOrdersController) addLine(ctx context.Context, id string, line models.WorkOrderLine) (int, []byte) {
+	// a closed work order would release the reservation straight away
+	order, err := wo.DB.GetWorkOrder(ctx, id)
+	if err == nil {
+		err = checkOpen(order)
+	}
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	lines := []models.WorkOrderLine{line}
+	if err := reserveParts(ctx, wo.Parts, lines); err != nil {
+		return dbErrorResponse(err)
+	}
+	status, response := wo.modify(ctx, id, func(order *models.WorkOrder) error {
+		order.Lines = append(order.Lines, lines[0])
+		return nil
+	})
+	if status != http.StatusOK {
+		releaseParts(ctx, wo.Parts, lines)
+	}
+	return status, response
+}
+
+// resolveCatalogLine - makes sure the catalog item line names exists, and fills in a labor line's blank fields from it
+func (wo *WorkOrdersController) resolveCatalogLine(ctx context.Context, line *models.WorkOrderLine) error {
+	if line.CatalogItemID == nil || wo.Catalog == nil {
+		return nil
+	}
+	item, err := wo.Catalog.GetCatalogItem(ctx, line.CatalogItemID.Hex())
+	if err != nil {
+		return missingReference(err, "catalog item", line.CatalogItemID.Hex())
+	}
+	if line.Type == models.LineLabor {
+		if line.Description == "" {
+			line.Description = item.Name
+		}
+		if line.Minutes == 0 {
+			line.Minutes = item.LaborMinutes
+		}
+		if line.Rate == 0 {
+			line.Rate = item.LaborRate
+		}
+	}
+	return nil
+}
+
+// RemoveWorkOrderLine - removes the line in the path from the work order, releasing its reserved part, and returns the updated work order
+func (wo *WorkOrdersController) RemoveWorkOrderLine(w http.ResponseWriter, r *http.Request) {
+	id, lineID := chi.URLParam(r, "id"), chi.URLParam(r, "lineID")
+	var removed []models.WorkOrderLine
+
+	status, response := wo.modify(r.Context(), id, func(order *models.WorkOrder) error {
+		remaining := order.Lines[:0:0]
+		removed = nil
+		for _, line := range order.Lines {
+			if line.ID.Hex() != lineID {
+				remaining = append(remaining, line)
+			} else {
+				removed = append(removed, line)
+			}
+		}
+		if len(removed) == 0 {
+			return fmt.Errorf("line %v of work order %v %w", lineID, id, db.ErrNotFound)
+		}
+		order.Lines = remaining
+		return nil
+	})
+	if status == http.StatusOK {
+		releaseParts(r.Context(), wo.Parts, removed)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// AddTimeEntry - accepts the technician, start, end and notes of time spent on the work order and returns the updated work order
+func (wo *WorkOrdersController) AddTimeEntry(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var entry models.TimeEntry
+	status := http.StatusOK
+	response := []byte{}
+
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON("request body must be a valid time entry")
+	} else if err := validateTimeEntry(entry); err != nil {
+		status = http.StatusBadRequest
+		response = errorJSON(err.Error())
+	} else if err := wo.checkTechnician(r.Context(), entry.TechnicianID); err != nil {
+		status, response = dbErrorResponse(err)
+	} else {
+		entry.ID = primitive.NewObjectID()
+		status, response = wo.modify(r.Context(), id, func(order *models.WorkOrder) error {
+			order.TimeEntries = append(order.TimeEntries, entry)
+			return nil
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// checkTechnician - returns errMissingReference if the technician with the given id doesn't exist
+func (wo *WorkOrdersController) checkTechnician(ctx context.Context, id primitive.ObjectID) error {
+	if wo.Technicians == nil {
+		return nil
+	}
+	if _, err := wo.Technicians.GetTechnician(ctx, id.Hex()); err != nil {
+		return missingReference(err, "technician", id.Hex())
+	}
+	return nil
+}
+
+// RemoveTimeEntry - removes the time entry in the path from the work order and returns the updated work order
+func (wo *WorkOrdersController) RemoveTimeEntry(w http.ResponseWriter, r *http.Request) {
+	id, entryID := chi.URLParam(r, "id"), chi.URLParam(r, "entryID")
+
+	status, response := wo.modify(r.Context(), id, func(order *models.WorkOrder) error {
+		remaining := order.TimeEntries[:0:0]
+		for _, entry := range order.TimeEntries {
+			if entry.ID.Hex() != entryID {
+				remaining = append(remaining, entry)
+			}
+		}
+		if len(remaining) == len(order.TimeEntries) {
+			return fmt.Errorf("time entry %v of work order %v %w", entryID, id, db.ErrNotFound)
+		}
+		order.TimeEntries = remaining
+		return nil
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// modify - applies change to the open work order with the given id, and returns the status and body to respond with
+func (wo *WorkOrdersController) modify(ctx context.Context, id string, change func(*models.WorkOrder) error) (int, []byte) {
+	updated, err := modifyWorkOrder(ctx, wo.DB, id, func(order *models.WorkOrder) error {
+		if err := checkOpen(order); err != nil {
+			return err
+		}
+		return change(order)
+	})
+	if err != nil {
+		return dbErrorResponse(err)
+	}
+	return marshalWorkOrder(updated)
+}
+
+// syncTimeout - how long bringing a work order along with its appointment may take once the request that changed it has gone
+const syncTimeout = 10 * time.Second
+
+// syncWorkOrder - moves the work order of the appointment with the given id, if it has one, to the appointment's new status.
+// Parts reserved for a work order are released when it is cancelled and taken out of stock when it is completed.
+// The appointment has already changed by then, so this carries on if the client goes away, and failures are logged
+// rather than failing the status change.
+func (a *AppointmentsController) syncWorkOrder(ctx context.Context, appointmentID, newStatus string) {
+	objectID, err := primitive.ObjectIDFromHex(appointmentID)
+	if err != nil || a.WorkOrders == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), syncTimeout)
+	defer cancel()
+	orders, err := a.WorkOrders.ListWorkOrders(ctx, models.WorkOrderFilter{AppointmentID: &objectID})
+	if err != nil {
+		log.Printf("error listing work orders of appointment %v: %v\n", appointmentID, err)
+		return
+	}
+	settle := a.Parts != nil && (newStatus == models.StatusCancelled || newStatus == models.StatusCompleted)
+	for _, order := range *orders {
+		updated, err := modifyWorkOrder(ctx, a.WorkOrders, order.ID.Hex(), func(order *models.WorkOrder) error {
+			order.Status = newStatus
+			order.StatusHistory = append(order.StatusHistory, models.StatusChange{Status: newStatus, At: time.Now().UTC()})
+			return nil
+		})
+		if err != nil {
+			log.Printf("error moving work order %v to %v: %v\n", order.ID.Hex(), newStatus, err)
+			continue
+		}
+		if settle {
+			a.settleWorkOrder(ctx, *updated, newStatus == models.StatusCompleted)
+		}
+	}
+}
+
+// checkNoOpenWorkOrder - returns ErrConflict if the appointment with the given id has a work order that isn't finished
+// or abandoned, since deleting the appointment would leave it behind with its parts reserved for good
+func (a *AppointmentsController) checkNoOpenWorkOrder(ctx context.Context, appointmentID string) error {
+	objectID, err := primitive.ObjectIDFromHex(appointmentID)
+	if err != nil || a.WorkOrders == nil {
+		return nil
+	}
+	orders, err := a.WorkOrders.ListWorkOrders(ctx, models.WorkOrderFilter{AppointmentID: &objectID})
+	if err != nil {
+		return err
+	}
+	for _, order := range *orders {
+		if !order.Closed() {
+			return fmt.Errorf("appointment %v has work order %v %v, so it must be cancelled or completed before it is deleted: %w",
+				appointmentID, order.ID.Hex(), order.Status, db.ErrConflict)
+		}
+	}
+	return nil
+}
+
+// settleWorkOrder - releases or consumes the reserved parts of order one line at a time, and only marks a line unreserved
+// once its stock is settled, so a line that can't be settled stays reserved on both the work order and the part
+func (a *AppointmentsController) settleWorkOrder(ctx context.Context, order models.WorkOrder, consume bool) {
+	for _, line := range order.Lines {
+		if !line.Reserved {
+			continue
+		}
+		if err := settleParts(ctx, a.Parts, []models.WorkOrderLine{line}, consume); err != nil {
+			log.Printf("error settling part %v of work order %v: %v\n", line.SKU, order.ID.Hex(), err)
+			continue
+		}
+		_, err := modifyWorkOrder(ctx, a.WorkOrders, order.ID.Hex(), func(order *models.WorkOrder) error {
+			for i := range order.Lines {
+				if order.Lines[i].ID == line.ID {
+					order.Lines[i].Reserved = false
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("error marking part %v of work order %v settled: %v\n", line.SKU, order.ID.Hex(), err)
+		}
+	}
+}
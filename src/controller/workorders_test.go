@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// serveWorkOrderItem - sends a request for the line or time entry key of the work order with the given id to handler
+func serveWorkOrderItem(handler http.HandlerFunc, id, key, itemID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("DELETE", "/workorders/"+id+"/"+itemID, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	rctx.URLParams.Add(key, itemID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// decodeWorkOrder - unmarshals a work order response body
+func decodeWorkOrder(t *testing.T, rr *httptest.ResponseRecorder) workOrderResponse {
+	t.Helper()
+	var order workOrderResponse
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &order); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestCreateWorkOrder(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	workOrdersController := WorkOrdersController{DB: store, Appointments: store, Catalog: store, Technicians: store}
+	date := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+
+	item, err := store.CreateCatalogItem(ctx, models.CatalogItem{Code: "brake_job", Name: "Brake job", LaborMinutes: 90, LaborRate: 9500,
+		Parts: []models.CatalogPart{{SKU: "BP-100", Quantity: 2, UnitPrice: 4550}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	open, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Date: date, Status: models.StatusOpen})
+	checkedIn, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Date: date,
+		Status: models.StatusCheckedIn, ServiceIDs: []primitive.ObjectID{item.ID}})
+
+	order := decodeWorkOrder(t, serveWithID(workOrdersController.CreateWorkOrder, "POST", "", `{"appointment_id":"`+checkedIn.ID.Hex()+`"}`))
+	if len(order.Lines) != 2 || order.Lines[0].Type != models.LineLabor || order.Lines[1].SKU != "BP-100" {
+		t.Errorf("got lines %+v want the catalog item's labor and parts", order.Lines)
+	}
+	if order.LaborTotal != 14250 || order.PartsTotal != 9100 || order.Total != 23350 {
+		t.Errorf("got totals %v %v %v want 142.50, 91.00 and 233.50", order.LaborTotal, order.PartsTotal, order.Total)
+	}
+	if order.Status != models.StatusCheckedIn || len(order.StatusHistory) != 1 {
+		t.Errorf("got status %v history %+v want checked_in", order.Status, order.StatusHistory)
+	}
+
+	tests := map[string]struct {
+		body     string
+		status   int
+		expected string
+	}{
+		"no appointment": {`{}`, http.StatusBadRequest, `{"error":"request body must contain a valid appointment_id"}`},
+		"unknown appointment": {`{"appointment_id":"5d63c0a1e1b2c3d4e5f60718"}`,
+			http.StatusBadRequest, `{"error":"appointment 5d63c0a1e1b2c3d4e5f60718 does not exist"}`},
+		"not checked in": {`{"appointment_id":"` + open.ID.Hex() + `"}`, http.StatusConflict,
+			`{"error":"appointment ` + open.ID.Hex() + ` is open; work orders can only be opened for appointments that are checked_in, in_progress, waiting_parts"}`},
+		"second work order": {`{"appointment_id":"` + checkedIn.ID.Hex() + `"}`, http.StatusConflict,
+			`{"error":"appointment ` + checkedIn.ID.Hex() + ` already has a work order: conflict"}`},
+	}
+	for name, test := range tests {
+		rr := serveWithID(workOrdersController.CreateWorkOrder, "POST", "", test.body)
+		if rr.Code != test.status || rr.Body.String() != test.expected {
+			t.Errorf("%v: got %v %v want %v %v", name, rr.Code, rr.Body.String(), test.status, test.expected)
+		}
+	}
+}
+
+func TestWorkOrderLinesAndTime(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+	workOrdersController := WorkOrdersController{DB: store, Appointments: store, Catalog: store, Technicians: store}
+	appointmentsController := AppointmentsController{DB: store, WorkOrders: store}
+	date := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+
+	technician, _ := store.CreateTechnician(ctx, models.Technician{Name: "Sam Rivera"})
+	appointment, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Date: date, Status: models.StatusCheckedIn})
+	order := decodeWorkOrder(t, serveWithID(workOrdersController.CreateWorkOrder, "POST", "", `{"appointment_id":"`+appointment.ID.Hex()+`"}`))
+	id := order.ID.Hex()
+
+	order = decodeWorkOrder(t, serveWithID(workOrdersController.AddWorkOrderLine, "POST", id, `{"type":"part","sku":"F-1","quantity":3,"unit_price":"4.25"}`))
+	if len(order.Lines) != 1 || order.PartsTotal != 1275 || order.Version != 2 {
+		t.Errorf("got %+v want one part line totalling 12.75 at version 2", order)
+	}
+	rr := serveWithID(workOrdersController.AddWorkOrderLine, "POST", id, `{"type":"labor","description":"Diagnosis"}`)
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != `{"error":"minutes must be positive"}` {
+		t.Errorf("got %v %v want labor without minutes rejected", rr.Code, rr.Body.String())
+	}
+	order = decodeWorkOrder(t, serveWorkOrderItem(workOrdersController.RemoveWorkOrderLine, id, "lineID", order.Lines[0].ID.Hex()))
+	if len(order.Lines) != 0 {
+		t.Errorf("got lines %+v want none", order.Lines)
+	}
+	if rr := serveWorkOrderItem(workOrdersController.RemoveWorkOrderLine, id, "lineID", order.ID.Hex()); rr.Code != http.StatusNotFound {
+		t.Errorf("got %v %v want 404 for a line that isn't on the work order", rr.Code, rr.Body.String())
+	}
+
+	entry := `{"technician_id":"` + technician.ID.Hex() + `","start":"2019-08-26T09:00:00Z","end":"2019-08-26T10:30:00Z"}`
+	order = decodeWorkOrder(t, serveWithID(workOrdersController.AddTimeEntry, "POST", id, entry))
+	if len(order.TimeEntries) != 1 || order.TimeEntries[0].Duration() != 90*time.Minute {
+		t.Errorf("got time entries %+v want one of 90 minutes", order.TimeEntries)
+	}
+	backwards := `{"technician_id":"` + technician.ID.Hex() + `","start":"2019-08-26T10:30:00Z","end":"2019-08-26T09:00:00Z"}`
+	if rr := serveWithID(workOrdersController.AddTimeEntry, "POST", id, backwards); rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v %v want an entry ending before it starts rejected", rr.Code, rr.Body.String())
+	}
+	unknown := strings.Replace(entry, technician.ID.Hex(), "5d63c0a1e1b2c3d4e5f60718", 1)
+	if rr := serveWithID(workOrdersController.AddTimeEntry, "POST", id, unknown); rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v %v want an unknown technician rejected", rr.Code, rr.Body.String())
+	}
+
+	for _, status := range []string{models.StatusInProgress, models.StatusCompleted} {
+		if rr := serveWithID(appointmentsController.UpdateAppointmentStatus, "PATCH", appointment.ID.Hex(), `{"status":"`+status+`"}`); rr.Code != http.StatusOK {
+			t.Fatalf("got %v %v", rr.Code, rr.Body.String())
+		}
+	}
+	order = decodeWorkOrder(t, serveWithID(workOrdersController.GetWorkOrder, "GET", id, ""))
+	if order.Status != models.StatusCompleted || len(order.StatusHistory) != 3 {
+		t.Errorf("got status %v history %+v want completed after following the appointment", order.Status, order.StatusHistory)
+	}
+	rr = serveWorkOrderItem(workOrdersController.RemoveTimeEntry, id, "entryID", order.TimeEntries[0].ID.Hex())
+	if rr.Code != http.StatusConflict {
+		t.Errorf("got %v %v want closed work orders left unchanged", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,374 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyStore interface - API key storage, following the same error and context conventions as ClientInterface.
+// Keys are looked up by the hash of the key presented with a request, so hashes are unique.
+type APIKeyStore interface {
+	CreateAPIKey(context.Context, models.APIKey) (*models.APIKey, error)
+	GetAPIKey(context.Context, string) (*models.APIKey, error)
+	GetAPIKeyByHash(context.Context, string) (*models.APIKey, error)
+	ListAPIKeys(context.Context) (*[]models.APIKey, error)
+	// RevokeAPIKey - marks the key revoked at the given time and returns it; keys already revoked keep their first revocation time
+	RevokeAPIKey(context.Context, string, time.Time) (*models.APIKey, error)
+	// TouchAPIKey - records that the key was used at the given time
+	TouchAPIKey(context.Context, string, time.Time) error
+}
+
+// apiKeyIndexes - index backing key hash uniqueness and lookups
+var apiKeyIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+}
+
+// duplicateAPIKey - the error returned when a key's hash is already stored, which only happens if a key is reused
+func duplicateAPIKey(key models.APIKey) error {
+	return fmt.Errorf("an api key starting %v already exists: %w", key.Prefix, ErrConflict)
+}
+
+// sortAPIKeys - orders keys from the oldest to the newest
+func sortAPIKeys(keys []models.APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID.Hex() < keys[j].ID.Hex()
+	})
+}
+
+// CreateAPIKey - writes key to its collection and returns the stored copy
+func (d *MongoStruct) CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	key.ID = primitive.NewObjectID()
+	if _, err := d.apiKeys().InsertOne(ctx, key); err != nil {
+		if err = mongoError(err, "api key"); errors.Is(err, ErrConflict) {
+			return nil, duplicateAPIKey(key)
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKey - returns the key with the given id
+func (d *MongoStruct) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	objectID, err := parseID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	var key models.APIKey
+	if err := d.apiKeys().FindOne(ctx, bson.M{"_id": objectID}).Decode(&key); err != nil {
+		return nil, mongoError(err, "api key "+keyID)
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash - returns the key whose hash is hash
+func (d *MongoStruct) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := d.apiKeys().FindOne(ctx, bson.M{"key_hash": hash}).Decode(&key); err != nil {
+		return nil, mongoError(err, "api key")
+	}
+	return &key, nil
+}
+
+// ListAPIKeys - returns every key, revoked or not, from the oldest to the newest
+func (d *MongoStruct) ListAPIKeys(ctx context.Context) (*[]models.APIKey, error) {
+	cur, err := d.apiKeys().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, mongoError(err, "api keys")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.APIKey{}
+	for cur.Next(ctx) {
+		var key models.APIKey
+		if err := cur.Decode(&key); err != nil {
+			return nil, mongoError(err, "api keys")
+		}
+		results = append(results, key)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "api keys")
+	}
+	return &results, nil
+}
+
+// RevokeAPIKey - sets revoked_at on the key unless it is already set, and returns the key
+func (d *MongoStruct) RevokeAPIKey(ctx context.Context, keyID string, at time.Time) (*models.APIKey, error) {
+	objectID, err := parseID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	var key models.APIKey
+	err = d.apiKeys().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// either there is no such key or it was already revoked
+		return d.GetAPIKey(ctx, keyID)
+	}
+	if err != nil {
+		return nil, mongoError(err, "api key "+keyID)
+	}
+	return &key, nil
+}
+
+// TouchAPIKey - sets last_used_at on the key
+func (d *MongoStruct) TouchAPIKey(ctx context.Context, keyID string, at time.Time) error {
+	objectID, err := parseID(keyID)
+	if err != nil {
+		return err
+	}
+	result, err := d.apiKeys().UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"last_used_at": at}})
+	if err != nil {
+		return mongoError(err, "api key "+keyID)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("api key %v %w", keyID, ErrNotFound)
+	}
+	return nil
+}
+
+// CreateAPIKey - stores key under a newly generated ID and returns the stored copy
+func (m *MemoryStore) CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, other := range m.apiKeys {
+		if other.KeyHash == key.KeyHash {
+			return nil, duplicateAPIKey(key)
+		}
+	}
+	key.ID = primitive.NewObjectID()
+	m.apiKeys[key.ID] = key
+	return &key, nil
+}
+
+// GetAPIKey - returns a copy of the key with the given id
+func (m *MemoryStore) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.apiKeys[objectID]
+	if !ok {
+		return nil, fmt.Errorf("api key %v %w", keyID, ErrNotFound)
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash - returns a copy of the key whose hash is hash
+func (m *MemoryStore) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.apiKeys {
+		if key.KeyHash == hash {
+			return &key, nil
+		}
+	}
+	return nil, fmt.Errorf("api key %w", ErrNotFound)
+}
+
+// ListAPIKeys - returns every key from the oldest to the newest
+func (m *MemoryStore) ListAPIKeys(ctx context.Context) (*[]models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.APIKey{}
+	for _, key := range m.apiKeys {
+		results = append(results, key)
+	}
+	sortAPIKeys(results)
+	return &results, nil
+}
+
+// RevokeAPIKey - marks the key with the given id revoked unless it already is
+func (m *MemoryStore) RevokeAPIKey(ctx context.Context, keyID string, at time.Time) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.apiKeys[objectID]
+	if !ok {
+		return nil, fmt.Errorf("api key %v %w", keyID, ErrNotFound)
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+		m.apiKeys[objectID] = key
+	}
+	return &key, nil
+}
+
+// TouchAPIKey - records the time the key with the given id was last used
+func (m *MemoryStore) TouchAPIKey(ctx context.Context, keyID string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(keyID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.apiKeys[objectID]
+	if !ok {
+		return fmt.Errorf("api key %v %w", keyID, ErrNotFound)
+	}
+	key.LastUsedAt = &at
+	m.apiKeys[objectID] = key
+	return nil
+}
+
+// updateAPIKey - applies change to the stored key with the given id within a bolt transaction and returns the result
+func (b *BoltStore) updateAPIKey(ctx context.Context, keyID string, change func(*models.APIKey)) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	var key models.APIKey
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		if err := getRecord(tx, apiKeysBucket, objectID, "api key", &key); err != nil {
+			return err
+		}
+		change(&key)
+		return putRecord(tx, apiKeysBucket, objectID, key)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &key, nil
+}
+
+// CreateAPIKey - stores key under a newly generated ID along with its hash index entry
+func (b *BoltStore) CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		if err := putUniqueKey(tx, apiKeysByHashBucket, key.KeyHash, "", key.ID, duplicateAPIKey(key)); err != nil {
+			return err
+		}
+		return putRecord(tx, apiKeysBucket, key.ID, key)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &key, nil
+}
+
+// GetAPIKey - returns the key with the given id
+func (b *BoltStore) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	var key models.APIKey
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, apiKeysBucket, objectID, "api key", &key)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash - looks the hash up in the hash index and returns its key
+func (b *BoltStore) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var key models.APIKey
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(apiKeysByHashBucket).Get([]byte(hash))
+		if value == nil {
+			return fmt.Errorf("api key %w", ErrNotFound)
+		}
+		var id primitive.ObjectID
+		copy(id[:], value)
+		return getRecord(tx, apiKeysBucket, id, "api key", &key)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &key, nil
+}
+
+// ListAPIKeys - returns every key; bolt keeps them ordered by ID, so from the oldest to the newest
+func (b *BoltStore) ListAPIKeys(ctx context.Context) (*[]models.APIKey, error) {
+	results := []models.APIKey{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(apiKeysBucket).ForEach(func(_, data []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var key models.APIKey
+			if err := bson.Unmarshal(data, &key); err != nil {
+				return err
+			}
+			results = append(results, key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &results, nil
+}
+
+// RevokeAPIKey - marks the key with the given id revoked unless it already is
+func (b *BoltStore) RevokeAPIKey(ctx context.Context, keyID string, at time.Time) (*models.APIKey, error) {
+	return b.updateAPIKey(ctx, keyID, func(key *models.APIKey) {
+		if key.RevokedAt == nil {
+			key.RevokedAt = &at
+		}
+	})
+}
+
+// TouchAPIKey - records the time the key with the given id was last used
+func (b *BoltStore) TouchAPIKey(ctx context.Context, keyID string, at time.Time) error {
+	_, err := b.updateAPIKey(ctx, keyID, func(key *models.APIKey) {
+		key.LastUsedAt = &at
+	})
+	return err
+}
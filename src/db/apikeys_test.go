@@ -0,0 +1,61 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoreAPIKeys(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		created := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+
+		kiosk, err := store.CreateAPIKey(ctx, models.APIKey{Name: "kiosk", Prefix: "csc_abc", KeyHash: "hash-1",
+			Scopes: []string{"appointments:read", "appointments:write"}, CreatedAt: created})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateAPIKey(ctx, models.APIKey{Name: "copy", KeyHash: "hash-1"}); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict for a reused hash", err)
+		}
+		if _, err := store.CreateAPIKey(ctx, models.APIKey{Name: "supplier", KeyHash: "hash-2", Scopes: []string{"parts:read"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := store.GetAPIKeyByHash(ctx, "hash-1")
+		if err != nil || found.ID != kiosk.ID || len(found.Scopes) != 2 {
+			t.Fatalf("got %+v, %v want %+v", found, err, kiosk)
+		}
+		if _, err := store.GetAPIKeyByHash(ctx, "hash-3"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound for an unknown hash", err)
+		}
+
+		used := created.Add(time.Hour)
+		if err := store.TouchAPIKey(ctx, kiosk.ID.Hex(), used); err != nil {
+			t.Fatal(err)
+		}
+		revoked, err := store.RevokeAPIKey(ctx, kiosk.ID.Hex(), used.Add(time.Hour))
+		if err != nil || revoked.RevokedAt == nil || !revoked.RevokedAt.Equal(used.Add(time.Hour)) {
+			t.Fatalf("got %+v, %v want the key revoked", revoked, err)
+		}
+		revoked, err = store.RevokeAPIKey(ctx, kiosk.ID.Hex(), used.Add(2*time.Hour))
+		if err != nil || !revoked.RevokedAt.Equal(used.Add(time.Hour)) {
+			t.Errorf("got %+v, %v want the first revocation time kept", revoked, err)
+		}
+
+		keys, err := store.ListAPIKeys(ctx)
+		if err != nil || len(*keys) != 2 || (*keys)[0].Name != "kiosk" || (*keys)[1].Name != "supplier" {
+			t.Fatalf("got %+v, %v want both keys, oldest first", keys, err)
+		}
+		if lastUsed := (*keys)[0].LastUsedAt; lastUsed == nil || !lastUsed.Equal(used) {
+			t.Errorf("got last used %v want %v", lastUsed, used)
+		}
+
+		if _, err := store.RevokeAPIKey(ctx, "000000000000000000000000", used); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound revoking an unknown key", err)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"bytes"
+	"context"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditStore interface - append-only storage of appointment audit entries, following the same error and context
+// conventions as ClientInterface. Entries can't be changed or removed once stored.
+type AuditStore interface {
+	AppendAuditEntry(context.Context, models.AuditEntry) (*models.AuditEntry, error)
+	// ListAuditEntries - the entries of the appointment with the given id, oldest first; empty if there are none
+	ListAuditEntries(context.Context, string) (*[]models.AuditEntry, error)
+}
+
+// auditIndexes - index backing the history of an appointment
+var auditIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "appointment_id", Value: 1}, {Key: "at", Value: 1}}},
+}
+
+// sortAuditEntries - orders entries from the oldest to the newest
+func sortAuditEntries(entries []models.AuditEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.Before(entries[j].At)
+	})
+}
+
+// AppendAuditEntry - writes entry to its collection and returns the stored copy
+func (d *MongoStruct) AppendAuditEntry(ctx context.Context, entry models.AuditEntry) (*models.AuditEntry, error) {
+	entry.ID = primitive.NewObjectID()
+	if _, err := d.audit().InsertOne(ctx, entry); err != nil {
+		return nil, mongoError(err, "audit entry")
+	}
+	return &entry, nil
+}
+
+// ListAuditEntries - returns the entries of the appointment with the given id, oldest first
+func (d *MongoStruct) ListAuditEntries(ctx context.Context, appointmentID string) (*[]models.AuditEntry, error) {
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := d.audit().Find(ctx, bson.M{"appointment_id": objectID},
+		options.Find().SetSort(bson.D{{Key: "at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, mongoError(err, "audit entries")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.AuditEntry{}
+	for cur.Next(ctx) {
+		var entry models.AuditEntry
+		if err := cur.Decode(&entry); err != nil {
+			return nil, mongoError(err, "audit entries")
+		}
+		results = append(results, entry)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "audit entries")
+	}
+	return &results, nil
+}
+
+// AppendAuditEntry - stores entry under a newly generated ID and returns the stored copy
+func (m *MemoryStore) AppendAuditEntry(ctx context.Context, entry models.AuditEntry) (*models.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = primitive.NewObjectID()
+	m.audit = append(m.audit, entry)
+	return &entry, nil
+}
+
+// ListAuditEntries - returns the entries of the appointment with the given id, oldest first
+func (m *MemoryStore) ListAuditEntries(ctx context.Context, appointmentID string) (*[]models.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.AuditEntry{}
+	for _, entry := range m.audit {
+		if entry.AppointmentID == objectID {
+			results = append(results, entry)
+		}
+	}
+	sortAuditEntries(results)
+	return &results, nil
+}
+
+// auditIndexKey - key of an entry in the by-appointment index, which sorts an appointment's entries by ID
+func auditIndexKey(appointmentID, entryID primitive.ObjectID) []byte {
+	return append(append([]byte{}, appointmentID[:]...), entryID[:]...)
+}
+
+// AppendAuditEntry - stores entry under a newly generated ID along with its by-appointment index entry
+func (b *BoltStore) AppendAuditEntry(ctx context.Context, entry models.AuditEntry) (*models.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entry.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(auditByAppointmentBucket).Put(auditIndexKey(entry.AppointmentID, entry.ID), []byte{}); err != nil {
+			return err
+		}
+		return putRecord(tx, auditBucket, entry.ID, entry)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &entry, nil
+}
+
+// ListAuditEntries - walks the by-appointment index and returns the appointment's entries, oldest first
+func (b *BoltStore) ListAuditEntries(ctx context.Context, appointmentID string) (*[]models.AuditEntry, error) {
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	results := []models.AuditEntry{}
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(auditByAppointmentBucket).Cursor()
+		for key, _ := cursor.Seek(objectID[:]); key != nil && bytes.HasPrefix(key, objectID[:]); key, _ = cursor.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var entryID primitive.ObjectID
+			copy(entryID[:], key[len(objectID):])
+			var entry models.AuditEntry
+			if err := getRecord(tx, auditBucket, entryID, "audit entry", &entry); err != nil {
+				return err
+			}
+			results = append(results, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	sortAuditEntries(results)
+	return &results, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStoreAuditEntries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		at := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+		appointmentID, otherID := primitive.NewObjectID(), primitive.NewObjectID()
+
+		// appended out of order, to check entries are listed by when they happened
+		for _, entry := range []models.AuditEntry{
+			{AppointmentID: appointmentID, Action: models.AuditStatusChanged, At: at.Add(time.Hour),
+				Changes: []models.FieldChange{{Field: "status", Before: models.StatusOpen, After: models.StatusCheckedIn}}},
+			{AppointmentID: otherID, Action: models.AuditCreated, At: at},
+			{AppointmentID: appointmentID, Action: models.AuditCreated, At: at, Actor: models.Actor{Type: models.ActorUser, Name: "advisor"}},
+		} {
+			if _, err := store.AppendAuditEntry(ctx, entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		entries, err := store.ListAuditEntries(ctx, appointmentID.Hex())
+		if err != nil || len(*entries) != 2 {
+			t.Fatalf("got %+v, %v want the appointment's 2 entries", entries, err)
+		}
+		first, second := (*entries)[0], (*entries)[1]
+		if first.Action != models.AuditCreated || first.Actor.Name != "advisor" || second.Action != models.AuditStatusChanged {
+			t.Errorf("got %+v want the created entry before the status change", *entries)
+		}
+		if len(second.Changes) != 1 || second.Changes[0].After != models.StatusCheckedIn || second.ID.IsZero() {
+			t.Errorf("got %+v want the stored status change", second)
+		}
+
+		entries, err = store.ListAuditEntries(ctx, primitive.NewObjectID().Hex())
+		if err != nil || len(*entries) != 0 {
+			t.Errorf("got %+v, %v want no entries for an appointment without history", entries, err)
+		}
+		if _, err := store.ListAuditEntries(ctx, "not-an-id"); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("got %v want ErrInvalidID", err)
+		}
+	})
+}
@@ -0,0 +1,421 @@
+package db
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	appointmentsBucket            = []byte("appointments")
+	appointmentsByDateBucket      = []byte("appointments_by_date")
+	appointmentsByStatusBucket    = []byte("appointments_by_status")
+	customersBucket               = []byte("customers")
+	vehiclesBucket                = []byte("vehicles")
+	vehiclesByVINBucket           = []byte("vehicles_by_vin")
+	catalogBucket                 = []byte("catalog")
+	catalogByCodeBucket           = []byte("catalog_by_code")
+	techniciansBucket             = []byte("technicians")
+	workOrdersBucket              = []byte("workorders")
+	workOrdersByAppointmentBucket = []byte("workorders_by_appointment")
+	invoicesBucket                = []byte("invoices")
+	invoicesByWorkOrderBucket     = []byte("invoices_by_workorder")
+	invoicesByNumberBucket        = []byte("invoices_by_number")
+	partsBucket                   = []byte("parts")
+	partsBySKUBucket              = []byte("parts_by_sku")
+	estimatesBucket               = []byte("estimates")
+	usersBucket                   = []byte("users")
+	usersByUsernameBucket         = []byte("users_by_username")
+	apiKeysBucket                 = []byte("apikeys")
+	apiKeysByHashBucket           = []byte("apikeys_by_hash")
+	auditBucket                   = []byte("audit")
+	auditByAppointmentBucket      = []byte("audit_by_appointment")
+)
+
+// boltBuckets - every bucket NewBoltStore makes sure exists
+var boltBuckets = [][]byte{
+	appointmentsBucket, appointmentsByDateBucket, appointmentsByStatusBucket,
+	customersBucket,
+	vehiclesBucket, vehiclesByVINBucket,
+	catalogBucket, catalogByCodeBucket,
+	techniciansBucket,
+	workOrdersBucket, workOrdersByAppointmentBucket,
+	invoicesBucket, invoicesByWorkOrderBucket, invoicesByNumberBucket,
+	partsBucket, partsBySKUBucket,
+	estimatesBucket,
+	usersBucket, usersByUsernameBucket,
+	apiKeysBucket, apiKeysByHashBucket,
+	auditBucket, auditByAppointmentBucket,
+}
+
+// BoltStore - implements ClientInterface on top of an embedded bbolt file for shops that can't run MongoDB.
+// Appointments are stored as BSON keyed by ID, with secondary index buckets on date and status.
+type BoltStore struct {
+	DB *bolt.DB
+}
+
+// NewBoltStore - opens (creating if needed) the bbolt file at cfg.Path
+func NewBoltStore(cfg config.BoltConfig) (*BoltStore, error) {
+	database, err := bolt.Open(cfg.Path, 0600, &bolt.Options{Timeout: time.Duration(cfg.OpenTimeout)})
+	if err != nil {
+		return nil, err
+	}
+	err = database.Update(func(tx *bolt.Tx) error {
+		for _, name := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	log.Printf("Opened bolt database %s\n", cfg.Path)
+	return &BoltStore{DB: database}, nil
+}
+
+// Close - flushes and closes the bbolt file
+func (b *BoltStore) Close() error {
+	return b.DB.Close()
+}
+
+// dateIndexKey - big-endian millisecond timestamp followed by the ID, so keys sort by date.
+// Milliseconds match the precision BSON stores dates with.
+func dateIndexKey(date time.Time, id primitive.ObjectID) []byte {
+	key := make([]byte, 8, 8+len(id))
+	millis := date.UnixNano() / int64(time.Millisecond)
+	// flip the sign bit so negative timestamps sort before positive ones
+	binary.BigEndian.PutUint64(key, uint64(millis)^(1<<63))
+	return append(key, id[:]...)
+}
+
+// statusIndexKey - status and ID separated by a zero byte
+func statusIndexKey(status string, id primitive.ObjectID) []byte {
+	key := make([]byte, 0, len(status)+1+len(id))
+	key = append(key, status...)
+	key = append(key, 0)
+	return append(key, id[:]...)
+}
+
+// boltError - passes package errors through and reports anything else as ErrUnavailable
+func boltError(err error) error {
+	if err == nil || isPackageError(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+// getRecord - decodes the BSON document stored under id in bucket into record; what names the record in errors
+func getRecord(tx *bolt.Tx, bucket []byte, id primitive.ObjectID, what string, record interface{}) error {
+	data := tx.Bucket(bucket).Get(id[:])
+	if data == nil {
+		return fmt.Errorf("%s %v %w", what, id.Hex(), ErrNotFound)
+	}
+	return bson.Unmarshal(data, record)
+}
+
+// putRecord - stores record as BSON under id in bucket
+func putRecord(tx *bolt.Tx, bucket []byte, id primitive.ObjectID, record interface{}) error {
+	data, err := bson.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucket).Put(id[:], data)
+}
+
+// putUniqueKey - points key in the unique index bucket at id, releasing previousKey if it is set.
+// Returns conflict instead if a different record already holds key.
+func putUniqueKey(tx *bolt.Tx, bucket []byte, key, previousKey string, id primitive.ObjectID, conflict error) error {
+	index := tx.Bucket(bucket)
+	if owner := index.Get([]byte(key)); owner != nil && !bytes.Equal(owner, id[:]) {
+		return conflict
+	}
+	if previousKey != "" {
+		if err := index.Delete([]byte(previousKey)); err != nil {
+			return err
+		}
+	}
+	return index.Put([]byte(key), id[:])
+}
+
+func getAppointment(tx *bolt.Tx, id primitive.ObjectID) (*models.Appointment, error) {
+	var appointment models.Appointment
+	if err := getRecord(tx, appointmentsBucket, id, "appointment", &appointment); err != nil {
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+// putAppointment - writes appointment and its index entries, replacing the entries for previous if given
+func putAppointment(tx *bolt.Tx, appointment models.Appointment, previous *models.Appointment) error {
+	if previous != nil {
+		if err := deleteAppointment(tx, *previous); err != nil {
+			return err
+		}
+	}
+	if err := putRecord(tx, appointmentsBucket, appointment.ID, appointment); err != nil {
+		return err
+	}
+	if err := tx.Bucket(appointmentsByDateBucket).Put(dateIndexKey(appointment.Date, appointment.ID), nil); err != nil {
+		return err
+	}
+	return tx.Bucket(appointmentsByStatusBucket).Put(statusIndexKey(appointment.Status, appointment.ID), nil)
+}
+
+// deleteAppointment - removes appointment and its index entries
+func deleteAppointment(tx *bolt.Tx, appointment models.Appointment) error {
+	if err := tx.Bucket(appointmentsBucket).Delete(appointment.ID[:]); err != nil {
+		return err
+	}
+	if err := tx.Bucket(appointmentsByDateBucket).Delete(dateIndexKey(appointment.Date, appointment.ID)); err != nil {
+		return err
+	}
+	return tx.Bucket(appointmentsByStatusBucket).Delete(statusIndexKey(appointment.Status, appointment.ID))
+}
+
+// CreateAppointment - stores appointment under a newly generated ID and returns the stored copy
+func (b *BoltStore) CreateAppointment(ctx context.Context, appointment models.Appointment) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	appointment.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		return putAppointment(tx, appointment, nil)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &appointment, nil
+}
+
+// DeleteAppointment - removes the appointment with the given id and returns it
+func (b *BoltStore) DeleteAppointment(ctx context.Context, appointmentID string) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	var appointment *models.Appointment
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		if appointment, err = getAppointment(tx, objectID); err != nil {
+			return err
+		}
+		return deleteAppointment(tx, *appointment)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return appointment, nil
+}
+
+// UpdateAppointmentStatus - moves the appointment with the given id to newStatus if the lifecycle allows it
+// and returns it as it was before
+func (b *BoltStore) UpdateAppointmentStatus(ctx context.Context, appointmentID, newStatus string) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	var appointment *models.Appointment
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		if appointment, err = getAppointment(tx, objectID); err != nil {
+			return err
+		}
+		if err := checkTransition(appointmentID, appointment.Status, newStatus); err != nil {
+			return err
+		}
+		updated := *appointment
+		updated.Status = newStatus
+		return putAppointment(tx, updated, appointment)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return appointment, nil
+}
+
+// UpdateAppointment - replaces the name, description, date, service and catalog items of the stored appointment with the same ID,
+// leaving its status and technicians untouched; finished appointments return ErrInvalidTransition
+func (b *BoltStore) UpdateAppointment(ctx context.Context, appointment models.Appointment) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var updated models.Appointment
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		stored, err := getAppointment(tx, appointment.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkEditable(appointment.ID.Hex(), stored.Status); err != nil {
+			return err
+		}
+		updated = *stored
+		updated.Name = appointment.Name
+		updated.Description = appointment.Description
+		updated.Date = appointment.Date
+		updated.Service = appointment.Service
+		updated.ServiceIDs = appointment.ServiceIDs
+		updated.DurationMinutes = appointment.DurationMinutes
+		return putAppointment(tx, updated, stored)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &updated, nil
+}
+
+// AssignAppointmentTechnician - adds technicianID to the technicians of the appointment with the given id, unless it is
+// already one of them, and returns the appointment as it was before
+func (b *BoltStore) AssignAppointmentTechnician(ctx context.Context, appointmentID string, technicianID primitive.ObjectID) (*models.Appointment, error) {
+	return b.changeTechnicians(ctx, appointmentID, func(ids []primitive.ObjectID) []primitive.ObjectID {
+		return models.WithTechnician(ids, technicianID)
+	})
+}
+
+// UnassignAppointmentTechnician - removes technicianID from the technicians of the appointment with the given id
+// and returns the appointment as it was before
+func (b *BoltStore) UnassignAppointmentTechnician(ctx context.Context, appointmentID string, technicianID primitive.ObjectID) (*models.Appointment, error) {
+	return b.changeTechnicians(ctx, appointmentID, func(ids []primitive.ObjectID) []primitive.ObjectID {
+		return models.WithoutTechnician(ids, technicianID)
+	})
+}
+
+// changeTechnicians - replaces the technicians of the appointment with the given id by change applied to them, in one transaction
+func (b *BoltStore) changeTechnicians(ctx context.Context, appointmentID string, change func([]primitive.ObjectID) []primitive.ObjectID) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	var appointment *models.Appointment
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		if appointment, err = getAppointment(tx, objectID); err != nil {
+			return err
+		}
+		updated := *appointment
+		updated.TechnicianIDs = change(appointment.TechnicianIDs)
+		return putAppointment(tx, updated, appointment)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return appointment, nil
+}
+
+// GetAppointment - returns the appointment with the given id
+func (b *BoltStore) GetAppointment(ctx context.Context, appointmentID string) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	var appointment *models.Appointment
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		appointment, err = getAppointment(tx, objectID)
+		return err
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return appointment, nil
+}
+
+// GetAppointmentsWithinDateRange - walks the date index between start and end inclusive and returns the matching appointments ordered by date
+func (b *BoltStore) GetAppointmentsWithinDateRange(ctx context.Context, start, end time.Time) (*[]models.Appointment, error) {
+	results := []models.Appointment{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		last := dateIndexKey(end, primitive.ObjectID{})[:8]
+		cursor := tx.Bucket(appointmentsByDateBucket).Cursor()
+		for key, _ := cursor.Seek(dateIndexKey(start, primitive.ObjectID{})); key != nil && bytes.Compare(key[:8], last) <= 0; key, _ = cursor.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var id primitive.ObjectID
+			copy(id[:], key[8:])
+			appointment, err := getAppointment(tx, id)
+			if err != nil {
+				return err
+			}
+			if !appointment.Date.Before(start) && !appointment.Date.After(end) {
+				results = append(results, *appointment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &results, nil
+}
+
+// ListAppointments - returns one page of the appointments matching query.
+// Candidates are read from the status index when filtering by status, from the date index when filtering by date,
+// and from every appointment otherwise.
+func (b *BoltStore) ListAppointments(ctx context.Context, query models.AppointmentQuery) (*models.AppointmentPage, error) {
+	filter := query.Filter
+	candidates := []models.Appointment{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		var prefix, seek, last []byte
+		index := tx.Bucket(appointmentsBucket)
+		switch {
+		case filter.Status != "":
+			index = tx.Bucket(appointmentsByStatusBucket)
+			prefix = append([]byte(filter.Status), 0)
+			seek = prefix
+		case !filter.Start.IsZero() || !filter.End.IsZero():
+			index = tx.Bucket(appointmentsByDateBucket)
+			if !filter.Start.IsZero() {
+				seek = dateIndexKey(filter.Start, primitive.ObjectID{})[:8]
+			}
+			if !filter.End.IsZero() {
+				last = dateIndexKey(filter.End, primitive.ObjectID{})[:8]
+			}
+		}
+
+		cursor := index.Cursor()
+		key, _ := cursor.First()
+		if seek != nil {
+			key, _ = cursor.Seek(seek)
+		}
+		for ; key != nil; key, _ = cursor.Next() {
+			if prefix != nil && !bytes.HasPrefix(key, prefix) || last != nil && bytes.Compare(key[:8], last) > 0 {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var id primitive.ObjectID
+			copy(id[:], key[len(key)-len(id):])
+			appointment, err := getAppointment(tx, id)
+			if err != nil {
+				return err
+			}
+			candidates = append(candidates, *appointment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return pageAppointments(candidates, query)
+}
@@ -0,0 +1,331 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CatalogStore interface - service catalog storage, following the same error and context conventions as ClientInterface.
+// Codes are unique; storing a second item with the same code returns ErrConflict.
+type CatalogStore interface {
+	CreateCatalogItem(context.Context, models.CatalogItem) (*models.CatalogItem, error)
+	GetCatalogItem(context.Context, string) (*models.CatalogItem, error)
+	ListCatalogItems(context.Context) (*[]models.CatalogItem, error)
+	UpdateCatalogItem(context.Context, models.CatalogItem) (*models.CatalogItem, error)
+	DeleteCatalogItem(context.Context, string) error
+}
+
+// catalogIndexes - indexes backing code uniqueness and ordering
+var catalogIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+}
+
+// duplicateCode - the error returned when item's code is already taken by another catalog item
+func duplicateCode(item models.CatalogItem) error {
+	return fmt.Errorf("a catalog item with code %v already exists: %w", item.Code, ErrConflict)
+}
+
+// sortCatalogItems - orders items by code
+func sortCatalogItems(items []models.CatalogItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Code < items[j].Code
+	})
+}
+
+// CreateCatalogItem - writes item to its collection and returns the stored copy
+func (d *MongoStruct) CreateCatalogItem(ctx context.Context, item models.CatalogItem) (*models.CatalogItem, error) {
+	item.ID = primitive.NewObjectID()
+	if _, err := d.catalog().InsertOne(ctx, item); err != nil {
+		if err = mongoError(err, "catalog item"); errors.Is(err, ErrConflict) {
+			return nil, duplicateCode(item)
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetCatalogItem - returns the catalog item with the given id
+func (d *MongoStruct) GetCatalogItem(ctx context.Context, itemID string) (*models.CatalogItem, error) {
+	objectID, err := parseID(itemID)
+	if err != nil {
+		return nil, err
+	}
+	var item models.CatalogItem
+	if err := d.catalog().FindOne(ctx, bson.M{"_id": objectID}).Decode(&item); err != nil {
+		return nil, mongoError(err, "catalog item "+itemID)
+	}
+	return &item, nil
+}
+
+// ListCatalogItems - returns every catalog item ordered by code
+func (d *MongoStruct) ListCatalogItems(ctx context.Context) (*[]models.CatalogItem, error) {
+	cur, err := d.catalog().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "code", Value: 1}}))
+	if err != nil {
+		return nil, mongoError(err, "catalog items")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.CatalogItem{}
+	for cur.Next(ctx) {
+		var item models.CatalogItem
+		if err := cur.Decode(&item); err != nil {
+			return nil, mongoError(err, "catalog items")
+		}
+		results = append(results, item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "catalog items")
+	}
+	return &results, nil
+}
+
+// UpdateCatalogItem - replaces the stored catalog item with the same ID and returns the result
+func (d *MongoStruct) UpdateCatalogItem(ctx context.Context, item models.CatalogItem) (*models.CatalogItem, error) {
+	var result models.CatalogItem
+	err := d.catalog().FindOneAndReplace(
+		ctx,
+		bson.M{"_id": item.ID},
+		item,
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+	).Decode(&result)
+	if err != nil {
+		if err = mongoError(err, "catalog item "+item.ID.Hex()); errors.Is(err, ErrConflict) {
+			return nil, duplicateCode(item)
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteCatalogItem - deletes the catalog item with the given id
+func (d *MongoStruct) DeleteCatalogItem(ctx context.Context, itemID string) error {
+	objectID, err := parseID(itemID)
+	if err != nil {
+		return err
+	}
+	deleteResult, err := d.catalog().DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return mongoError(err, "catalog item "+itemID)
+	}
+	if deleteResult.DeletedCount == 0 {
+		return fmt.Errorf("catalog item %v %w", itemID, ErrNotFound)
+	}
+	return nil
+}
+
+// codeTaken - reports whether a catalog item other than item already has its code. Callers must hold m.mu.
+func (m *MemoryStore) codeTaken(item models.CatalogItem) bool {
+	for id, other := range m.catalog {
+		if id != item.ID && other.Code == item.Code {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateCatalogItem - stores item under a newly generated ID and returns the stored copy
+func (m *MemoryStore) CreateCatalogItem(ctx context.Context, item models.CatalogItem) (*models.CatalogItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item.ID = primitive.NewObjectID()
+	if m.codeTaken(item) {
+		return nil, duplicateCode(item)
+	}
+	m.catalog[item.ID] = item
+	return &item, nil
+}
+
+// GetCatalogItem - returns a copy of the catalog item with the given id
+func (m *MemoryStore) GetCatalogItem(ctx context.Context, itemID string) (*models.CatalogItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(itemID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.catalog[objectID]
+	if !ok {
+		return nil, fmt.Errorf("catalog item %v %w", itemID, ErrNotFound)
+	}
+	return &item, nil
+}
+
+// ListCatalogItems - returns every catalog item ordered by code
+func (m *MemoryStore) ListCatalogItems(ctx context.Context) (*[]models.CatalogItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make([]models.CatalogItem, 0, len(m.catalog))
+	for _, item := range m.catalog {
+		results = append(results, item)
+	}
+	sortCatalogItems(results)
+	return &results, nil
+}
+
+// UpdateCatalogItem - replaces the stored catalog item with the same ID
+func (m *MemoryStore) UpdateCatalogItem(ctx context.Context, item models.CatalogItem) (*models.CatalogItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.catalog[item.ID]; !ok {
+		return nil, fmt.Errorf("catalog item %v %w", item.ID.Hex(), ErrNotFound)
+	}
+	if m.codeTaken(item) {
+		return nil, duplicateCode(item)
+	}
+	m.catalog[item.ID] = item
+	return &item, nil
+}
+
+// DeleteCatalogItem - removes the catalog item with the given id
+func (m *MemoryStore) DeleteCatalogItem(ctx context.Context, itemID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(itemID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.catalog[objectID]; !ok {
+		return fmt.Errorf("catalog item %v %w", itemID, ErrNotFound)
+	}
+	delete(m.catalog, objectID)
+	return nil
+}
+
+// putCatalogItem - writes item and its code index entry, replacing the entry for previous if given
+func putCatalogItem(tx *bolt.Tx, item models.CatalogItem, previous *models.CatalogItem) error {
+	previousCode := ""
+	if previous != nil {
+		previousCode = previous.Code
+	}
+	if err := putUniqueKey(tx, catalogByCodeBucket, item.Code, previousCode, item.ID, duplicateCode(item)); err != nil {
+		return err
+	}
+	return putRecord(tx, catalogBucket, item.ID, item)
+}
+
+// CreateCatalogItem - stores item under a newly generated ID and returns the stored copy
+func (b *BoltStore) CreateCatalogItem(ctx context.Context, item models.CatalogItem) (*models.CatalogItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	item.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		return putCatalogItem(tx, item, nil)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &item, nil
+}
+
+// GetCatalogItem - returns the catalog item with the given id
+func (b *BoltStore) GetCatalogItem(ctx context.Context, itemID string) (*models.CatalogItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(itemID)
+	if err != nil {
+		return nil, err
+	}
+	var item models.CatalogItem
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, catalogBucket, objectID, "catalog item", &item)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &item, nil
+}
+
+// ListCatalogItems - walks the code index and returns every catalog item ordered by code
+func (b *BoltStore) ListCatalogItems(ctx context.Context) (*[]models.CatalogItem, error) {
+	results := []models.CatalogItem{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(catalogByCodeBucket).ForEach(func(_, value []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var id primitive.ObjectID
+			copy(id[:], value)
+			var item models.CatalogItem
+			if err := getRecord(tx, catalogBucket, id, "catalog item", &item); err != nil {
+				return err
+			}
+			results = append(results, item)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &results, nil
+}
+
+// UpdateCatalogItem - replaces the stored catalog item with the same ID
+func (b *BoltStore) UpdateCatalogItem(ctx context.Context, item models.CatalogItem) (*models.CatalogItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		var previous models.CatalogItem
+		if err := getRecord(tx, catalogBucket, item.ID, "catalog item", &previous); err != nil {
+			return err
+		}
+		return putCatalogItem(tx, item, &previous)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &item, nil
+}
+
+// DeleteCatalogItem - removes the catalog item with the given id and its code index entry
+func (b *BoltStore) DeleteCatalogItem(ctx context.Context, itemID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(itemID)
+	if err != nil {
+		return err
+	}
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		var item models.CatalogItem
+		if err := getRecord(tx, catalogBucket, objectID, "catalog item", &item); err != nil {
+			return err
+		}
+		if err := tx.Bucket(catalogByCodeBucket).Delete([]byte(item.Code)); err != nil {
+			return err
+		}
+		return tx.Bucket(catalogBucket).Delete(objectID[:])
+	})
+	return boltError(err)
+}
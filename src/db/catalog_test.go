@@ -0,0 +1,63 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStoreCatalog(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+
+		brakes, err := store.CreateCatalogItem(ctx, models.CatalogItem{Code: "brake_job", Name: "Brake job", LaborMinutes: 120, LaborRate: 9500,
+			Parts: []models.CatalogPart{{SKU: "BP-100", Quantity: 2, UnitPrice: 4500}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		oil, err := store.CreateCatalogItem(ctx, models.CatalogItem{Code: "oil_change", Name: "Oil change", LaborMinutes: 30, LaborRate: 9500})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateCatalogItem(ctx, models.CatalogItem{Code: "oil_change", Name: "Another oil change"}); !errors.Is(err, ErrConflict) {
+			t.Errorf("duplicate code: got %v want %v", err, ErrConflict)
+		}
+
+		items, err := store.ListCatalogItems(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(*items) != 2 || (*items)[0].Code != "brake_job" || (*items)[1].Code != "oil_change" {
+			t.Errorf("got %+v want items ordered by code", *items)
+		}
+		found, err := store.GetCatalogItem(ctx, brakes.ID.Hex())
+		if err != nil || len(found.Parts) != 1 || found.Parts[0].UnitPrice != 4500 || found.LaborRate != 9500 {
+			t.Errorf("got %+v, %v want %+v", found, err, brakes)
+		}
+
+		oil.Code = "brake_job"
+		if _, err := store.UpdateCatalogItem(ctx, *oil); !errors.Is(err, ErrConflict) {
+			t.Errorf("update to taken code: got %v want %v", err, ErrConflict)
+		}
+		oil.Code = "a_oil_change"
+		oil.LaborMinutes = 45
+		if _, err := store.UpdateCatalogItem(ctx, *oil); err != nil {
+			t.Fatal(err)
+		}
+		items, _ = store.ListCatalogItems(ctx)
+		if (*items)[0].ID != oil.ID || (*items)[0].LaborMinutes != 45 {
+			t.Errorf("got %+v want the renamed item first", *items)
+		}
+
+		if err := store.DeleteCatalogItem(ctx, brakes.ID.Hex()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetCatalogItem(ctx, brakes.ID.Hex()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want %v", err, ErrNotFound)
+		}
+		if _, err := store.CreateCatalogItem(ctx, models.CatalogItem{Code: "brake_job", Name: "Brake job", LaborMinutes: 90}); err != nil {
+			t.Errorf("code of a deleted item should be reusable: %v", err)
+		}
+	})
+}
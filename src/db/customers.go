@@ -0,0 +1,330 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CustomerStore interface - customer storage, following the same error and context conventions as ClientInterface
+type CustomerStore interface {
+	CreateCustomer(context.Context, models.Customer) (*models.Customer, error)
+	GetCustomer(context.Context, string) (*models.Customer, error)
+	ListCustomers(context.Context, models.CustomerFilter) (*[]models.Customer, error)
+	UpdateCustomer(context.Context, models.Customer) (*models.Customer, error)
+	DeleteCustomer(context.Context, string) error
+}
+
+// customerIndexes - indexes backing ListCustomers
+var customerIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "phones", Value: 1}}},
+	{Keys: bson.D{{Key: "emails", Value: 1}}},
+}
+
+// matchesCustomerFilter - reports whether customer satisfies every field set in filter
+func matchesCustomerFilter(customer models.Customer, filter models.CustomerFilter) bool {
+	if filter.Name != "" && !strings.Contains(strings.ToLower(customer.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	if filter.Phone != "" && !containsString(customer.Phones, filter.Phone, false) {
+		return false
+	}
+	if filter.Email != "" && !containsString(customer.Emails, filter.Email, true) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, target string, foldCase bool) bool {
+	for _, value := range values {
+		if value == target || foldCase && strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortCustomers - orders customers by name, then by ID so customers sharing a name keep a stable order
+func sortCustomers(customers []models.Customer) {
+	sort.Slice(customers, func(i, j int) bool {
+		if customers[i].Name == customers[j].Name {
+			return customers[i].ID.Hex() < customers[j].ID.Hex()
+		}
+		return customers[i].Name < customers[j].Name
+	})
+}
+
+// CreateCustomer - writes customer to its collection and returns the stored copy
+func (d *MongoStruct) CreateCustomer(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	customer.ID = primitive.NewObjectID()
+	if _, err := d.customers().InsertOne(ctx, customer); err != nil {
+		return nil, mongoError(err, "customer")
+	}
+	return &customer, nil
+}
+
+// GetCustomer - returns the customer with the given id
+func (d *MongoStruct) GetCustomer(ctx context.Context, customerID string) (*models.Customer, error) {
+	objectID, err := parseID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	var customer models.Customer
+	if err := d.customers().FindOne(ctx, bson.M{"_id": objectID}).Decode(&customer); err != nil {
+		return nil, mongoError(err, "customer "+customerID)
+	}
+	return &customer, nil
+}
+
+// ListCustomers - returns every customer matching filter ordered by name
+func (d *MongoStruct) ListCustomers(ctx context.Context, filter models.CustomerFilter) (*[]models.Customer, error) {
+	document := bson.M{}
+	if filter.Name != "" {
+		document["name"] = bson.M{"$regex": regexp.QuoteMeta(filter.Name), "$options": "i"}
+	}
+	if filter.Phone != "" {
+		document["phones"] = filter.Phone
+	}
+	if filter.Email != "" {
+		document["emails"] = bson.M{"$regex": "^" + regexp.QuoteMeta(filter.Email) + "$", "$options": "i"}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := d.customers().Find(ctx, document, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "customers")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.Customer{}
+	for cur.Next(ctx) {
+		var customer models.Customer
+		if err := cur.Decode(&customer); err != nil {
+			return nil, mongoError(err, "customers")
+		}
+		results = append(results, customer)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "customers")
+	}
+	return &results, nil
+}
+
+// UpdateCustomer - replaces the stored customer with the same ID and returns the result
+func (d *MongoStruct) UpdateCustomer(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	var result models.Customer
+	err := d.customers().FindOneAndReplace(
+		ctx,
+		bson.M{"_id": customer.ID},
+		customer,
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+	).Decode(&result)
+	if err != nil {
+		return nil, mongoError(err, "customer "+customer.ID.Hex())
+	}
+	return &result, nil
+}
+
+// DeleteCustomer - deletes the customer with the given id
+func (d *MongoStruct) DeleteCustomer(ctx context.Context, customerID string) error {
+	objectID, err := parseID(customerID)
+	if err != nil {
+		return err
+	}
+	deleteResult, err := d.customers().DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return mongoError(err, "customer "+customerID)
+	}
+	if deleteResult.DeletedCount == 0 {
+		return fmt.Errorf("customer %v %w", customerID, ErrNotFound)
+	}
+	return nil
+}
+
+// CreateCustomer - stores customer under a newly generated ID and returns the stored copy
+func (m *MemoryStore) CreateCustomer(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	customer.ID = primitive.NewObjectID()
+	m.customers[customer.ID] = customer
+	return &customer, nil
+}
+
+// GetCustomer - returns a copy of the customer with the given id
+func (m *MemoryStore) GetCustomer(ctx context.Context, customerID string) (*models.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	customer, ok := m.customers[objectID]
+	if !ok {
+		return nil, fmt.Errorf("customer %v %w", customerID, ErrNotFound)
+	}
+	return &customer, nil
+}
+
+// ListCustomers - returns every customer matching filter ordered by name
+func (m *MemoryStore) ListCustomers(ctx context.Context, filter models.CustomerFilter) (*[]models.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.Customer{}
+	for _, customer := range m.customers {
+		if matchesCustomerFilter(customer, filter) {
+			results = append(results, customer)
+		}
+	}
+	sortCustomers(results)
+	return &results, nil
+}
+
+// UpdateCustomer - replaces the stored customer with the same ID
+func (m *MemoryStore) UpdateCustomer(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.customers[customer.ID]; !ok {
+		return nil, fmt.Errorf("customer %v %w", customer.ID.Hex(), ErrNotFound)
+	}
+	m.customers[customer.ID] = customer
+	return &customer, nil
+}
+
+// DeleteCustomer - removes the customer with the given id
+func (m *MemoryStore) DeleteCustomer(ctx context.Context, customerID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(customerID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.customers[objectID]; !ok {
+		return fmt.Errorf("customer %v %w", customerID, ErrNotFound)
+	}
+	delete(m.customers, objectID)
+	return nil
+}
+
+// CreateCustomer - stores customer under a newly generated ID and returns the stored copy
+func (b *BoltStore) CreateCustomer(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	customer.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		return putRecord(tx, customersBucket, customer.ID, customer)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &customer, nil
+}
+
+// GetCustomer - returns the customer with the given id
+func (b *BoltStore) GetCustomer(ctx context.Context, customerID string) (*models.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	var customer models.Customer
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, customersBucket, objectID, "customer", &customer)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &customer, nil
+}
+
+// ListCustomers - scans every customer and returns those matching filter ordered by name
+func (b *BoltStore) ListCustomers(ctx context.Context, filter models.CustomerFilter) (*[]models.Customer, error) {
+	results := []models.Customer{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(customersBucket).ForEach(func(_, data []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var customer models.Customer
+			if err := bson.Unmarshal(data, &customer); err != nil {
+				return err
+			}
+			if matchesCustomerFilter(customer, filter) {
+				results = append(results, customer)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	sortCustomers(results)
+	return &results, nil
+}
+
+// UpdateCustomer - replaces the stored customer with the same ID
+func (b *BoltStore) UpdateCustomer(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		if err := getRecord(tx, customersBucket, customer.ID, "customer", &models.Customer{}); err != nil {
+			return err
+		}
+		return putRecord(tx, customersBucket, customer.ID, customer)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &customer, nil
+}
+
+// DeleteCustomer - removes the customer with the given id
+func (b *BoltStore) DeleteCustomer(ctx context.Context, customerID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(customerID)
+	if err != nil {
+		return err
+	}
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		if err := getRecord(tx, customersBucket, objectID, "customer", &models.Customer{}); err != nil {
+			return err
+		}
+		return tx.Bucket(customersBucket).Delete(objectID[:])
+	})
+	return boltError(err)
+}
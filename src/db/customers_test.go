@@ -0,0 +1,88 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStoreCustomers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+
+		created, err := store.CreateCustomer(ctx, models.Customer{
+			Name:             "Jane Doe",
+			Phones:           []string{"555-0100"},
+			Emails:           []string{"Jane@example.com"},
+			Address:          &models.Address{City: "Springfield"},
+			PreferredContact: models.ContactEmail,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateCustomer(ctx, models.Customer{Name: "Adam Smith", Phones: []string{"555-0199"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := store.GetCustomer(ctx, created.ID.Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Name != "Jane Doe" || found.Address == nil || found.Address.City != "Springfield" || len(found.Emails) != 1 {
+			t.Errorf("got %+v want %+v", found, created)
+		}
+
+		filters := map[string]struct {
+			filter   models.CustomerFilter
+			expected []string
+		}{
+			"everyone":      {models.CustomerFilter{}, []string{"Adam Smith", "Jane Doe"}},
+			"name":          {models.CustomerFilter{Name: "DOE"}, []string{"Jane Doe"}},
+			"phone":         {models.CustomerFilter{Phone: "555-0199"}, []string{"Adam Smith"}},
+			"email":         {models.CustomerFilter{Email: "jane@EXAMPLE.com"}, []string{"Jane Doe"}},
+			"partial email": {models.CustomerFilter{Email: "example.com"}, nil},
+		}
+		for name, test := range filters {
+			customers, err := store.ListCustomers(ctx, test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, customer := range *customers {
+				names = append(names, customer.Name)
+			}
+			if len(names) != len(test.expected) || len(names) > 0 && names[0] != test.expected[0] {
+				t.Errorf("%v: got %v want %v", name, names, test.expected)
+			}
+		}
+
+		found.Notes = "prefers mornings"
+		found.Address = nil
+		updated, err := store.UpdateCustomer(ctx, *found)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Notes != "prefers mornings" || updated.Address != nil {
+			t.Errorf("update not applied: %+v", updated)
+		}
+
+		if err := store.DeleteCustomer(ctx, created.ID.Hex()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetCustomer(ctx, created.ID.Hex()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want %v", err, ErrNotFound)
+		}
+		if err := store.DeleteCustomer(ctx, created.ID.Hex()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want %v", err, ErrNotFound)
+		}
+		if _, err := store.UpdateCustomer(ctx, models.Customer{ID: primitive.NewObjectID(), Name: "Nobody"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want %v", err, ErrNotFound)
+		}
+		if _, err := store.GetCustomer(ctx, "not-an-id"); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("got %v want %v", err, ErrInvalidID)
+		}
+	})
+}
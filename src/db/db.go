@@ -0,0 +1,61 @@
+package db
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ClientInterface interface - methods return the errors declared in errors.go and stop
+// as soon as the given context is canceled or its deadline passes
+type ClientInterface interface {
+	CreateAppointment(context.Context, models.Appointment) (*models.Appointment, error)
+	// DeleteAppointment - deletes the appointment with the given id and returns it as it was
+	DeleteAppointment(context.Context, string) (*models.Appointment, error)
+	GetAppointment(context.Context, string) (*models.Appointment, error)
+	GetAppointmentsWithinDateRange(context.Context, time.Time, time.Time) (*[]models.Appointment, error)
+	// UpdateAppointmentStatus - moves the appointment with the given id to a new status and returns it as it was before
+	UpdateAppointmentStatus(context.Context, string, string) (*models.Appointment, error)
+	// UpdateAppointment - edits the details of an appointment that isn't finished, leaving its status and technicians untouched
+	UpdateAppointment(context.Context, models.Appointment) (*models.Appointment, error)
+	// AssignAppointmentTechnician - adds a technician to the appointment with the given id, unless already assigned, and returns it as it was before
+	AssignAppointmentTechnician(context.Context, string, primitive.ObjectID) (*models.Appointment, error)
+	// UnassignAppointmentTechnician - removes a technician from the appointment with the given id and returns it as it was before
+	UnassignAppointmentTechnician(context.Context, string, primitive.ObjectID) (*models.Appointment, error)
+	ListAppointments(context.Context, models.AppointmentQuery) (*models.AppointmentPage, error)
+	Close() error
+}
+
+// Database - every store a backend provides
+type Database interface {
+	ClientInterface
+	CustomerStore
+	VehicleStore
+	CatalogStore
+	TechnicianStore
+	WorkOrderStore
+	InvoiceStore
+	PartStore
+	EstimateStore
+	UserStore
+	APIKeyStore
+	AuditStore
+}
+
+// Open - creates the Database implementation selected by cfg.Storage.Backend
+func Open(cfg *config.Config) (Database, error) {
+	switch cfg.Storage.Backend {
+	case config.BackendMongo:
+		return NewMongoStruct(cfg.Mongo)
+	case config.BackendMemory:
+		return NewMemoryStore(), nil
+	case config.BackendBolt:
+		return NewBoltStore(cfg.Bolt)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
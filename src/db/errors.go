@@ -0,0 +1,95 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors returned by ClientInterface implementations. Callers should compare
+// against them with errors.Is since they are usually wrapped with more detail.
+// Operations cut short by their context return the context's error instead.
+var (
+	// ErrNotFound - no document matches the given id
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidID - the given id is not a valid object id
+	ErrInvalidID = errors.New("invalid id")
+	// ErrConflict - the write conflicts with the current state of the document
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable - the database could not be reached or failed to complete the operation
+	ErrUnavailable = errors.New("database unavailable")
+	// ErrInvalidTransition - the requested status change is not allowed by the appointment lifecycle
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrInvalidQuery - a listing query has an unsupported sort field or page token
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrStale - the record was changed by another request since it was read; it wraps ErrConflict
+	ErrStale = fmt.Errorf("changed by another request: %w", ErrConflict)
+	// ErrInsufficientStock - a stock change would reserve or use more units than are on hand; it wraps ErrConflict
+	ErrInsufficientStock = fmt.Errorf("has too little stock: %w", ErrConflict)
+)
+
+const duplicateKeyCode = 11000
+
+// packageErrors - errors callers are expected to handle, which backends return unchanged
+var packageErrors = []error{
+	ErrNotFound, ErrInvalidID, ErrConflict, ErrUnavailable, ErrInvalidTransition, ErrInvalidQuery,
+	context.Canceled, context.DeadlineExceeded,
+}
+
+// isPackageError - reports whether err wraps one of packageErrors
+func isPackageError(err error) bool {
+	for _, target := range packageErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseID - converts a hex string to an object id, returning ErrInvalidID when it is malformed
+func parseID(id string) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w %q", ErrInvalidID, id)
+	}
+	return objectID, nil
+}
+
+// checkTransition - returns ErrInvalidTransition unless an appointment may move from status from to status to
+func checkTransition(appointmentID, from, to string) error {
+	if !models.CanTransition(from, to) {
+		return fmt.Errorf("%w: appointment %v cannot move from %q to %q", ErrInvalidTransition, appointmentID, from, to)
+	}
+	return nil
+}
+
+// checkEditable - returns ErrInvalidTransition if an appointment in status is finished and can no longer be edited
+func checkEditable(appointmentID, status string) error {
+	if models.Finished(status) {
+		return fmt.Errorf("%w: appointment %v is %q and can no longer be edited", ErrInvalidTransition, appointmentID, status)
+	}
+	return nil
+}
+
+// mongoError - translates a mongo driver error into one of the package errors
+func mongoError(err error, what string) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%s %w", what, ErrNotFound)
+	}
+	var writeException mongo.WriteException
+	if errors.As(err, &writeException) {
+		for _, writeError := range writeException.WriteErrors {
+			if writeError.Code == duplicateKeyCode {
+				return fmt.Errorf("%s: %w", what, ErrConflict)
+			}
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EstimateStore interface - estimate storage, following the same error and context conventions as ClientInterface.
+// An appointment may have several estimates, such as a revision of a declined one. Updates only succeed while the
+// stored Version still matches the given one, and return ErrStale otherwise.
+type EstimateStore interface {
+	CreateEstimate(context.Context, models.Estimate) (*models.Estimate, error)
+	GetEstimate(context.Context, string) (*models.Estimate, error)
+	ListEstimates(context.Context, models.EstimateFilter) (*[]models.Estimate, error)
+	UpdateEstimate(context.Context, models.Estimate) (*models.Estimate, error)
+}
+
+// estimateIndexes - indexes backing ListEstimates
+var estimateIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "appointment_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+}
+
+// staleEstimate - the error returned when estimate was changed since it was read
+func staleEstimate(estimate models.Estimate) error {
+	return fmt.Errorf("estimate %v %w", estimate.ID.Hex(), ErrStale)
+}
+
+// matchesEstimateFilter - reports whether estimate satisfies every field set in filter
+func matchesEstimateFilter(estimate models.Estimate, filter models.EstimateFilter) bool {
+	switch {
+	case filter.AppointmentID != nil && estimate.AppointmentID != *filter.AppointmentID:
+		return false
+	case filter.Status != "" && estimate.Status != filter.Status:
+		return false
+	}
+	return true
+}
+
+// sortEstimates - orders estimates by creation time, then by ID
+func sortEstimates(estimates []models.Estimate) {
+	sort.Slice(estimates, func(i, j int) bool {
+		if estimates[i].CreatedAt.Equal(estimates[j].CreatedAt) {
+			return estimates[i].ID.Hex() < estimates[j].ID.Hex()
+		}
+		return estimates[i].CreatedAt.Before(estimates[j].CreatedAt)
+	})
+}
+
+// CreateEstimate - writes estimate to its collection as version 1 and returns the stored copy
+func (d *MongoStruct) CreateEstimate(ctx context.Context, estimate models.Estimate) (*models.Estimate, error) {
+	estimate.ID = primitive.NewObjectID()
+	estimate.Version = 1
+	if _, err := d.estimates().InsertOne(ctx, estimate); err != nil {
+		return nil, mongoError(err, "estimate")
+	}
+	return &estimate, nil
+}
+
+// GetEstimate - returns the estimate with the given id
+func (d *MongoStruct) GetEstimate(ctx context.Context, estimateID string) (*models.Estimate, error) {
+	objectID, err := parseID(estimateID)
+	if err != nil {
+		return nil, err
+	}
+	var estimate models.Estimate
+	if err := d.estimates().FindOne(ctx, bson.M{"_id": objectID}).Decode(&estimate); err != nil {
+		return nil, mongoError(err, "estimate "+estimateID)
+	}
+	return &estimate, nil
+}
+
+// ListEstimates - returns every estimate matching filter, oldest first
+func (d *MongoStruct) ListEstimates(ctx context.Context, filter models.EstimateFilter) (*[]models.Estimate, error) {
+	document := bson.M{}
+	if filter.AppointmentID != nil {
+		document["appointment_id"] = *filter.AppointmentID
+	}
+	if filter.Status != "" {
+		document["status"] = filter.Status
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := d.estimates().Find(ctx, document, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "estimates")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.Estimate{}
+	for cur.Next(ctx) {
+		var estimate models.Estimate
+		if err := cur.Decode(&estimate); err != nil {
+			return nil, mongoError(err, "estimates")
+		}
+		results = append(results, estimate)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "estimates")
+	}
+	return &results, nil
+}
+
+// UpdateEstimate - replaces the stored estimate if it is still at estimate's version, and returns the result with the next version
+func (d *MongoStruct) UpdateEstimate(ctx context.Context, estimate models.Estimate) (*models.Estimate, error) {
+	version := estimate.Version
+	estimate.Version++
+	var result models.Estimate
+	err := d.estimates().FindOneAndReplace(
+		ctx,
+		bson.M{"_id": estimate.ID, "version": version},
+		estimate,
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+	).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// tell a missing estimate apart from one that moved on to another version
+		if _, err := d.GetEstimate(ctx, estimate.ID.Hex()); err != nil {
+			return nil, err
+		}
+		return nil, staleEstimate(estimate)
+	}
+	if err != nil {
+		return nil, mongoError(err, "estimate "+estimate.ID.Hex())
+	}
+	return &result, nil
+}
+
+// copyEstimate - estimate with lines of its own, so the memory store and its callers never share them
+func copyEstimate(estimate models.Estimate) models.Estimate {
+	if estimate.Lines != nil {
+		estimate.Lines = append(make([]models.EstimateLine, 0, len(estimate.Lines)), estimate.Lines...)
+	}
+	return estimate
+}
+
+// CreateEstimate - stores estimate under a newly generated ID as version 1 and returns the stored copy
+func (m *MemoryStore) CreateEstimate(ctx context.Context, estimate models.Estimate) (*models.Estimate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	estimate.ID = primitive.NewObjectID()
+	estimate.Version = 1
+	m.estimates[estimate.ID] = copyEstimate(estimate)
+	return &estimate, nil
+}
+
+// GetEstimate - returns a copy of the estimate with the given id
+func (m *MemoryStore) GetEstimate(ctx context.Context, estimateID string) (*models.Estimate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(estimateID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	estimate, ok := m.estimates[objectID]
+	if !ok {
+		return nil, fmt.Errorf("estimate %v %w", estimateID, ErrNotFound)
+	}
+	estimate = copyEstimate(estimate)
+	return &estimate, nil
+}
+
+// ListEstimates - returns every estimate matching filter, oldest first
+func (m *MemoryStore) ListEstimates(ctx context.Context, filter models.EstimateFilter) (*[]models.Estimate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.Estimate{}
+	for _, estimate := range m.estimates {
+		if matchesEstimateFilter(estimate, filter) {
+			results = append(results, copyEstimate(estimate))
+		}
+	}
+	sortEstimates(results)
+	return &results, nil
+}
+
+// UpdateEstimate - replaces the stored estimate if it is still at estimate's version
+func (m *MemoryStore) UpdateEstimate(ctx context.Context, estimate models.Estimate) (*models.Estimate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.estimates[estimate.ID]
+	if !ok {
+		return nil, fmt.Errorf("estimate %v %w", estimate.ID.Hex(), ErrNotFound)
+	}
+	if stored.Version != estimate.Version {
+		return nil, staleEstimate(estimate)
+	}
+	estimate.Version++
+	m.estimates[estimate.ID] = copyEstimate(estimate)
+	return &estimate, nil
+}
+
+// CreateEstimate - stores estimate under a newly generated ID as version 1 and returns the stored copy
+func (b *BoltStore) CreateEstimate(ctx context.Context, estimate models.Estimate) (*models.Estimate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	estimate.ID = primitive.NewObjectID()
+	estimate.Version = 1
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		return putRecord(tx, estimatesBucket, estimate.ID, estimate)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &estimate, nil
+}
+
+// GetEstimate - returns the estimate with the given id
+func (b *BoltStore) GetEstimate(ctx context.Context, estimateID string) (*models.Estimate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(estimateID)
+	if err != nil {
+		return nil, err
+	}
+	var estimate models.Estimate
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, estimatesBucket, objectID, "estimate", &estimate)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &estimate, nil
+}
+
+// ListEstimates - scans every estimate and returns those matching filter, oldest first
+func (b *BoltStore) ListEstimates(ctx context.Context, filter models.EstimateFilter) (*[]models.Estimate, error) {
+	results := []models.Estimate{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(estimatesBucket).ForEach(func(_, data []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var estimate models.Estimate
+			if err := bson.Unmarshal(data, &estimate); err != nil {
+				return err
+			}
+			if matchesEstimateFilter(estimate, filter) {
+				results = append(results, estimate)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	sortEstimates(results)
+	return &results, nil
+}
+
+// UpdateEstimate - replaces the stored estimate if it is still at estimate's version
+func (b *BoltStore) UpdateEstimate(ctx context.Context, estimate models.Estimate) (*models.Estimate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		var stored models.Estimate
+		if err := getRecord(tx, estimatesBucket, estimate.ID, "estimate", &stored); err != nil {
+			return err
+		}
+		if stored.Version != estimate.Version {
+			return staleEstimate(estimate)
+		}
+		estimate.Version++
+		return putRecord(tx, estimatesBucket, estimate.ID, estimate)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &estimate, nil
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStoreEstimates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		appointmentID := primitive.NewObjectID()
+		created := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+		line := models.EstimateLine{
+			WorkOrderLine: models.WorkOrderLine{ID: primitive.NewObjectID(), Type: models.LineLabor, Description: "Brake job", Minutes: 90, Rate: 9500},
+			Approval:      models.ApprovalPending,
+		}
+
+		estimate, err := store.CreateEstimate(ctx, models.Estimate{AppointmentID: appointmentID, Status: models.ApprovalPending,
+			Lines: []models.EstimateLine{line}, CreatedAt: created})
+		if err != nil || estimate.Version != 1 {
+			t.Fatalf("got %+v, %v want version 1", estimate, err)
+		}
+		if _, err := store.CreateEstimate(ctx, models.Estimate{AppointmentID: appointmentID, Status: models.ApprovalPending, CreatedAt: created.Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := store.GetEstimate(ctx, estimate.ID.Hex())
+		if err != nil || len(found.Lines) != 1 || found.Lines[0].Description != "Brake job" || found.Lines[0].Approval != models.ApprovalPending {
+			t.Fatalf("got %+v, %v want the stored lines with their approval", found, err)
+		}
+
+		decided := time.Date(2019, 8, 26, 10, 0, 0, 0, time.UTC)
+		found.Lines[0].Approval = models.ApprovalApproved
+		found.Status, found.Approver, found.DecidedAt = models.ApprovalApproved, "Pat Lee", &decided
+		updated, err := store.UpdateEstimate(ctx, *found)
+		if err != nil || updated.Version != 2 || updated.Approver != "Pat Lee" {
+			t.Fatalf("got %+v, %v want version 2", updated, err)
+		}
+		if _, err := store.UpdateEstimate(ctx, *found); !errors.Is(err, ErrStale) {
+			t.Errorf("got %v want ErrStale updating an old version", err)
+		}
+
+		all, err := store.ListEstimates(ctx, models.EstimateFilter{AppointmentID: &appointmentID})
+		if err != nil || len(*all) != 2 || (*all)[0].ID != estimate.ID {
+			t.Errorf("got %+v, %v want both estimates oldest first", all, err)
+		}
+		approved, err := store.ListEstimates(ctx, models.EstimateFilter{Status: models.ApprovalApproved})
+		if err != nil || len(*approved) != 1 || !(*approved)[0].DecidedAt.Equal(decided) {
+			t.Errorf("got %+v, %v want only the approved estimate", approved, err)
+		}
+	})
+}
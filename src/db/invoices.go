@@ -0,0 +1,284 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InvoiceStore interface - invoice storage, following the same error and context conventions as ClientInterface.
+// Invoices can't be changed once created, and a work order has at most one; creating a second returns ErrConflict.
+type InvoiceStore interface {
+	// CreateInvoice - numbers invoice with the next position in the invoice sequence, starting at 1 and formatted by
+	// number, and stores it. A position is only used up once its invoice is stored, so the sequence has no gaps.
+	CreateInvoice(ctx context.Context, invoice models.Invoice, number func(sequence int64) string) (*models.Invoice, error)
+	GetInvoice(context.Context, string) (*models.Invoice, error)
+	ListInvoices(context.Context, models.InvoiceFilter) (*[]models.Invoice, error)
+}
+
+// invoiceCounter - the counters document holding the invoice sequence
+const invoiceCounter = "invoices"
+
+// invoiceIndexes - indexes backing work order and number uniqueness and ListInvoices
+var invoiceIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "work_order_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "number", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "customer_id", Value: 1}, {Key: "issued_at", Value: 1}}},
+}
+
+// duplicateInvoice - the error returned when invoice's work order already has an invoice
+func duplicateInvoice(invoice models.Invoice) error {
+	return fmt.Errorf("work order %v has already been invoiced: %w", invoice.WorkOrderID.Hex(), ErrConflict)
+}
+
+// matchesInvoiceFilter - reports whether invoice satisfies every field set in filter
+func matchesInvoiceFilter(invoice models.Invoice, filter models.InvoiceFilter) bool {
+	switch {
+	case filter.WorkOrderID != nil && invoice.WorkOrderID != *filter.WorkOrderID:
+		return false
+	case filter.CustomerID != nil && (invoice.CustomerID == nil || *invoice.CustomerID != *filter.CustomerID):
+		return false
+	}
+	return true
+}
+
+// sortInvoices - orders invoices by issue date, then by number
+func sortInvoices(invoices []models.Invoice) {
+	sort.Slice(invoices, func(i, j int) bool {
+		if invoices[i].IssuedAt.Equal(invoices[j].IssuedAt) {
+			return invoices[i].Number < invoices[j].Number
+		}
+		return invoices[i].IssuedAt.Before(invoices[j].IssuedAt)
+	})
+}
+
+// invoiceSequence - atomically adds change to the invoice counter and returns its new value
+func (d *MongoStruct) invoiceSequence(ctx context.Context, filter bson.M, change int64) (int64, error) {
+	var counter struct {
+		Sequence int64 `bson:"sequence"`
+	}
+	err := d.counters().FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$inc": bson.M{"sequence": change}},
+		options.FindOneAndUpdate().SetUpsert(change > 0).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, mongoError(err, "invoice counter")
+	}
+	return counter.Sequence, nil
+}
+
+// CreateInvoice - takes the next number from the invoice counter and writes invoice to its collection. The counter and
+// the invoices live in separate documents, so work orders that are already invoiced are refused before a number is
+// taken, and the number is handed back if the insert fails and no later number was taken in the meantime.
+func (d *MongoStruct) CreateInvoice(ctx context.Context, invoice models.Invoice, number func(int64) string) (*models.Invoice, error) {
+	if err := d.invoices().FindOne(ctx, bson.M{"work_order_id": invoice.WorkOrderID}).Err(); err == nil {
+		return nil, duplicateInvoice(invoice)
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, mongoError(err, "invoice")
+	}
+	sequence, err := d.invoiceSequence(ctx, bson.M{"_id": invoiceCounter}, 1)
+	if err != nil {
+		return nil, err
+	}
+	invoice.ID = primitive.NewObjectID()
+	invoice.Number = number(sequence)
+	if _, err := d.invoices().InsertOne(ctx, invoice); err != nil {
+		if _, err := d.invoiceSequence(context.Background(), bson.M{"_id": invoiceCounter, "sequence": sequence}, -1); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Println("error handing back invoice number", invoice.Number, err)
+		}
+		// numbers come from a counter, so a duplicate key is a second invoice for the work order
+		if err = mongoError(err, "invoice"); errors.Is(err, ErrConflict) {
+			return nil, duplicateInvoice(invoice)
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetInvoice - returns the invoice with the given id
+func (d *MongoStruct) GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	objectID, err := parseID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	var invoice models.Invoice
+	if err := d.invoices().FindOne(ctx, bson.M{"_id": objectID}).Decode(&invoice); err != nil {
+		return nil, mongoError(err, "invoice "+invoiceID)
+	}
+	return &invoice, nil
+}
+
+// ListInvoices - returns every invoice matching filter, oldest first
+func (d *MongoStruct) ListInvoices(ctx context.Context, filter models.InvoiceFilter) (*[]models.Invoice, error) {
+	document := bson.M{}
+	if filter.WorkOrderID != nil {
+		document["work_order_id"] = *filter.WorkOrderID
+	}
+	if filter.CustomerID != nil {
+		document["customer_id"] = *filter.CustomerID
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "issued_at", Value: 1}, {Key: "number", Value: 1}})
+	cur, err := d.invoices().Find(ctx, document, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "invoices")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.Invoice{}
+	for cur.Next(ctx) {
+		var invoice models.Invoice
+		if err := cur.Decode(&invoice); err != nil {
+			return nil, mongoError(err, "invoices")
+		}
+		results = append(results, invoice)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "invoices")
+	}
+	return &results, nil
+}
+
+// CreateInvoice - numbers invoice and stores it under a newly generated ID while holding the store's lock,
+// and returns the stored copy
+func (m *MemoryStore) CreateInvoice(ctx context.Context, invoice models.Invoice, number func(int64) string) (*models.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invoice.Number = number(m.invoiceSequence + 1)
+	for _, other := range m.invoices {
+		if other.WorkOrderID == invoice.WorkOrderID {
+			return nil, duplicateInvoice(invoice)
+		}
+		if other.Number == invoice.Number {
+			return nil, fmt.Errorf("invoice number %v is already taken: %w", invoice.Number, ErrConflict)
+		}
+	}
+	invoice.ID = primitive.NewObjectID()
+	m.invoiceSequence++
+	m.invoices[invoice.ID] = invoice
+	return &invoice, nil
+}
+
+// GetInvoice - returns a copy of the invoice with the given id
+func (m *MemoryStore) GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	invoice, ok := m.invoices[objectID]
+	if !ok {
+		return nil, fmt.Errorf("invoice %v %w", invoiceID, ErrNotFound)
+	}
+	return &invoice, nil
+}
+
+// ListInvoices - returns every invoice matching filter, oldest first
+func (m *MemoryStore) ListInvoices(ctx context.Context, filter models.InvoiceFilter) (*[]models.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.Invoice{}
+	for _, invoice := range m.invoices {
+		if matchesInvoiceFilter(invoice, filter) {
+			results = append(results, invoice)
+		}
+	}
+	sortInvoices(results)
+	return &results, nil
+}
+
+// CreateInvoice - numbers invoice from the invoices bucket's sequence and stores it under a newly generated ID in the
+// same write transaction, so a failed write leaves the sequence as it was, and returns the stored copy
+func (b *BoltStore) CreateInvoice(ctx context.Context, invoice models.Invoice, number func(int64) string) (*models.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	invoice.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		sequence, err := tx.Bucket(invoicesBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		invoice.Number = number(int64(sequence))
+		if err := putUniqueKey(tx, invoicesByWorkOrderBucket, invoice.WorkOrderID.Hex(), "", invoice.ID, duplicateInvoice(invoice)); err != nil {
+			return err
+		}
+		numberTaken := fmt.Errorf("invoice number %v is already taken: %w", invoice.Number, ErrConflict)
+		if err := putUniqueKey(tx, invoicesByNumberBucket, invoice.Number, "", invoice.ID, numberTaken); err != nil {
+			return err
+		}
+		return putRecord(tx, invoicesBucket, invoice.ID, invoice)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &invoice, nil
+}
+
+// GetInvoice - returns the invoice with the given id
+func (b *BoltStore) GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	var invoice models.Invoice
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, invoicesBucket, objectID, "invoice", &invoice)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &invoice, nil
+}
+
+// ListInvoices - scans every invoice and returns those matching filter, oldest first
+func (b *BoltStore) ListInvoices(ctx context.Context, filter models.InvoiceFilter) (*[]models.Invoice, error) {
+	results := []models.Invoice{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(invoicesBucket).ForEach(func(_, data []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var invoice models.Invoice
+			if err := bson.Unmarshal(data, &invoice); err != nil {
+				return err
+			}
+			if matchesInvoiceFilter(invoice, filter) {
+				results = append(results, invoice)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	sortInvoices(results)
+	return &results, nil
+}
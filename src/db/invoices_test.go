@@ -0,0 +1,55 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStoreInvoices(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		issued := time.Date(2019, 8, 28, 17, 0, 0, 0, time.UTC)
+		customerID := primitive.NewObjectID()
+
+		number := func(sequence int64) string {
+			return fmt.Sprintf("INV-%06d", sequence)
+		}
+
+		invoice, err := store.CreateInvoice(ctx, models.Invoice{
+			WorkOrderID: primitive.NewObjectID(),
+			CustomerID:  &customerID,
+			IssuedAt:    issued,
+			Taxes:       []models.Tax{{LineType: models.LinePart, Rate: 88750, Taxable: 9100, Amount: 808}},
+			Total:       24934,
+		}, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateInvoice(ctx, models.Invoice{WorkOrderID: invoice.WorkOrderID}, number); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict for a second invoice on one work order", err)
+		}
+		second, err := store.CreateInvoice(ctx, models.Invoice{WorkOrderID: primitive.NewObjectID(), IssuedAt: issued.Add(time.Hour)}, number)
+		if err != nil || second.Number != "INV-000002" {
+			t.Fatalf("got %+v, %v want the refused invoice to leave no gap", second, err)
+		}
+
+		found, err := store.GetInvoice(ctx, invoice.ID.Hex())
+		if err != nil || found.Number != "INV-000001" || found.Taxes[0].Rate != 88750 || found.Total != 24934 {
+			t.Errorf("got %+v, %v want %+v", found, err, invoice)
+		}
+		all, err := store.ListInvoices(ctx, models.InvoiceFilter{})
+		if err != nil || len(*all) != 2 || (*all)[0].ID != invoice.ID {
+			t.Errorf("got %+v, %v want invoices oldest first", all, err)
+		}
+		byCustomer, err := store.ListInvoices(ctx, models.InvoiceFilter{CustomerID: &customerID})
+		if err != nil || len(*byCustomer) != 1 || (*byCustomer)[0].ID != invoice.ID {
+			t.Errorf("got %+v, %v want only %v", byCustomer, err, invoice.Number)
+		}
+	})
+}
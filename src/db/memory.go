@@ -0,0 +1,258 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore - thread-safe in-memory implementation of ClientInterface for local development and tests.
+// Nothing is persisted once the process exits.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	appointments map[primitive.ObjectID]models.Appointment
+	customers    map[primitive.ObjectID]models.Customer
+	vehicles     map[primitive.ObjectID]models.Vehicle
+	catalog      map[primitive.ObjectID]models.CatalogItem
+	technicians  map[primitive.ObjectID]models.Technician
+	workOrders   map[primitive.ObjectID]models.WorkOrder
+	invoices     map[primitive.ObjectID]models.Invoice
+	parts        map[primitive.ObjectID]models.Part
+	estimates    map[primitive.ObjectID]models.Estimate
+	users        map[primitive.ObjectID]models.User
+	apiKeys      map[primitive.ObjectID]models.APIKey
+	// audit - append-only, in the order entries were written
+	audit []models.AuditEntry
+
+	invoiceSequence int64
+}
+
+// NewMemoryStore - returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		appointments: make(map[primitive.ObjectID]models.Appointment),
+		customers:    make(map[primitive.ObjectID]models.Customer),
+		vehicles:     make(map[primitive.ObjectID]models.Vehicle),
+		catalog:      make(map[primitive.ObjectID]models.CatalogItem),
+		technicians:  make(map[primitive.ObjectID]models.Technician),
+		workOrders:   make(map[primitive.ObjectID]models.WorkOrder),
+		invoices:     make(map[primitive.ObjectID]models.Invoice),
+		parts:        make(map[primitive.ObjectID]models.Part),
+		estimates:    make(map[primitive.ObjectID]models.Estimate),
+		users:        make(map[primitive.ObjectID]models.User),
+		apiKeys:      make(map[primitive.ObjectID]models.APIKey),
+	}
+}
+
+// Close - nothing to release for the in-memory store
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// copyAppointment - returns appointment with its own copies of the slices and ids it points to, so callers
+// can't change a stored appointment without holding the lock
+func copyAppointment(appointment models.Appointment) models.Appointment {
+	if appointment.CustomerID != nil {
+		customerID := *appointment.CustomerID
+		appointment.CustomerID = &customerID
+	}
+	if appointment.VehicleID != nil {
+		vehicleID := *appointment.VehicleID
+		appointment.VehicleID = &vehicleID
+	}
+	if appointment.ServiceIDs != nil {
+		appointment.ServiceIDs = append(make([]primitive.ObjectID, 0, len(appointment.ServiceIDs)), appointment.ServiceIDs...)
+	}
+	if appointment.TechnicianIDs != nil {
+		appointment.TechnicianIDs = append(make([]primitive.ObjectID, 0, len(appointment.TechnicianIDs)), appointment.TechnicianIDs...)
+	}
+	return appointment
+}
+
+// CreateAppointment - stores appointment under a newly generated ID and returns the stored copy
+func (m *MemoryStore) CreateAppointment(ctx context.Context, appointment models.Appointment) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	appointment.ID = primitive.NewObjectID()
+	m.appointments[appointment.ID] = copyAppointment(appointment)
+	appointment = copyAppointment(appointment)
+	return &appointment, nil
+}
+
+// DeleteAppointment - removes the appointment with the given id and returns it
+func (m *MemoryStore) DeleteAppointment(ctx context.Context, appointmentID string) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	appointment, ok := m.appointments[objectID]
+	if !ok {
+		return nil, fmt.Errorf("appointment %v %w", appointmentID, ErrNotFound)
+	}
+	delete(m.appointments, objectID)
+	return &appointment, nil
+}
+
+// UpdateAppointmentStatus - moves the appointment with the given id to newStatus if the lifecycle allows it
+// and returns it as it was before
+func (m *MemoryStore) UpdateAppointmentStatus(ctx context.Context, appointmentID, newStatus string) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	appointment, ok := m.appointments[objectID]
+	if !ok {
+		return nil, fmt.Errorf("appointment %v %w", appointmentID, ErrNotFound)
+	}
+	if err := checkTransition(appointmentID, appointment.Status, newStatus); err != nil {
+		return nil, err
+	}
+	previous := copyAppointment(appointment)
+	appointment.Status = newStatus
+	m.appointments[objectID] = appointment
+	return &previous, nil
+}
+
+// UpdateAppointment - replaces the name, description, date, service and catalog items of the stored appointment with the same ID,
+// leaving its status and technicians untouched; finished appointments return ErrInvalidTransition
+func (m *MemoryStore) UpdateAppointment(ctx context.Context, appointment models.Appointment) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.appointments[appointment.ID]
+	if !ok {
+		return nil, fmt.Errorf("appointment %v %w", appointment.ID.Hex(), ErrNotFound)
+	}
+	if err := checkEditable(appointment.ID.Hex(), stored.Status); err != nil {
+		return nil, err
+	}
+	stored.Name = appointment.Name
+	stored.Description = appointment.Description
+	stored.Date = appointment.Date
+	stored.Service = appointment.Service
+	stored.ServiceIDs = appointment.ServiceIDs
+	stored.DurationMinutes = appointment.DurationMinutes
+	m.appointments[appointment.ID] = copyAppointment(stored)
+	stored = copyAppointment(stored)
+	return &stored, nil
+}
+
+// AssignAppointmentTechnician - adds technicianID to the technicians of the appointment with the given id, unless it is
+// already one of them, and returns the appointment as it was before
+func (m *MemoryStore) AssignAppointmentTechnician(ctx context.Context, appointmentID string, technicianID primitive.ObjectID) (*models.Appointment, error) {
+	return m.changeTechnicians(ctx, appointmentID, func(ids []primitive.ObjectID) []primitive.ObjectID {
+		return models.WithTechnician(ids, technicianID)
+	})
+}
+
+// UnassignAppointmentTechnician - removes technicianID from the technicians of the appointment with the given id
+// and returns the appointment as it was before
+func (m *MemoryStore) UnassignAppointmentTechnician(ctx context.Context, appointmentID string, technicianID primitive.ObjectID) (*models.Appointment, error) {
+	return m.changeTechnicians(ctx, appointmentID, func(ids []primitive.ObjectID) []primitive.ObjectID {
+		return models.WithoutTechnician(ids, technicianID)
+	})
+}
+
+// changeTechnicians - replaces the technicians of the appointment with the given id by change applied to them, under the lock
+func (m *MemoryStore) changeTechnicians(ctx context.Context, appointmentID string, change func([]primitive.ObjectID) []primitive.ObjectID) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	appointment, ok := m.appointments[objectID]
+	if !ok {
+		return nil, fmt.Errorf("appointment %v %w", appointmentID, ErrNotFound)
+	}
+	previous := copyAppointment(appointment)
+	appointment.TechnicianIDs = change(appointment.TechnicianIDs)
+	m.appointments[objectID] = appointment
+	return &previous, nil
+}
+
+// GetAppointment - returns a copy of the appointment with the given id
+func (m *MemoryStore) GetAppointment(ctx context.Context, appointmentID string) (*models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	appointment, ok := m.appointments[objectID]
+	if !ok {
+		return nil, fmt.Errorf("appointment %v %w", appointmentID, ErrNotFound)
+	}
+	appointment = copyAppointment(appointment)
+	return &appointment, nil
+}
+
+// GetAppointmentsWithinDateRange - returns every appointment dated between start and end inclusive, ordered by date
+func (m *MemoryStore) GetAppointmentsWithinDateRange(ctx context.Context, start, end time.Time) (*[]models.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.Appointment{}
+	for _, appointment := range m.appointments {
+		if !appointment.Date.Before(start) && !appointment.Date.After(end) {
+			results = append(results, copyAppointment(appointment))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Date.Equal(results[j].Date) {
+			return results[i].ID.Hex() < results[j].ID.Hex()
+		}
+		return results[i].Date.Before(results[j].Date)
+	})
+	return &results, nil
+}
+
+// ListAppointments - returns one page of the appointments matching query
+func (m *MemoryStore) ListAppointments(ctx context.Context, query models.AppointmentQuery) (*models.AppointmentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := make([]models.Appointment, 0, len(m.appointments))
+	for _, appointment := range m.appointments {
+		candidates = append(candidates, copyAppointment(appointment))
+	}
+	return pageAppointments(candidates, query)
+}
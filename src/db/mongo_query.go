@@ -0,0 +1,130 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// appointmentIndexes - indexes backing ListAppointments and GetAppointmentsWithinDateRange
+var appointmentIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "date", Value: 1}}},
+	{Keys: bson.D{{Key: "customer_id", Value: 1}, {Key: "date", Value: 1}}},
+	{Keys: bson.D{{Key: "vehicle_id", Value: 1}, {Key: "date", Value: 1}}},
+	{Keys: bson.D{{Key: "technician_ids", Value: 1}, {Key: "date", Value: 1}}},
+}
+
+// EnsureIndexes - creates any missing indexes on every collection; existing indexes are left as they are
+func (d *MongoStruct) EnsureIndexes(ctx context.Context) error {
+	indexes := map[*mongo.Collection][]mongo.IndexModel{
+		d.appointments(): appointmentIndexes,
+		d.customers():    customerIndexes,
+		d.vehicles():     vehicleIndexes,
+		d.catalog():      catalogIndexes,
+		d.technicians():  technicianIndexes,
+		d.workOrders():   workOrderIndexes,
+		d.invoices():     invoiceIndexes,
+		d.parts():        partIndexes,
+		d.estimates():    estimateIndexes,
+		d.users():        userIndexes,
+		d.apiKeys():      apiKeyIndexes,
+		d.audit():        auditIndexes,
+	}
+	for collection, models := range indexes {
+		if _, err := collection.Indexes().CreateMany(ctx, models); err != nil {
+			return mongoError(err, collection.Name()+" indexes")
+		}
+	}
+	return nil
+}
+
+// appointmentFilterDocument - translates filter into a mongo query document
+func appointmentFilterDocument(filter models.AppointmentFilter) bson.M {
+	document := bson.M{}
+	if filter.Status != "" {
+		document["status"] = filter.Status
+	}
+	if filter.Name != "" {
+		document["name"] = bson.M{"$regex": regexp.QuoteMeta(filter.Name), "$options": "i"}
+	}
+	if !filter.Start.IsZero() || !filter.End.IsZero() {
+		dateRange := bson.M{}
+		if !filter.Start.IsZero() {
+			dateRange["$gte"] = filter.Start
+		}
+		if !filter.End.IsZero() {
+			dateRange["$lte"] = filter.End
+		}
+		document["date"] = dateRange
+	}
+	if filter.CustomerID != nil {
+		document["customer_id"] = *filter.CustomerID
+	}
+	if filter.VehicleID != nil {
+		document["vehicle_id"] = *filter.VehicleID
+	}
+	if filter.TechnicianID != nil {
+		document["technician_ids"] = *filter.TechnicianID
+	}
+	return document
+}
+
+// ListAppointments - returns one page of the appointments matching query, using the sort field and ID as a keyset cursor
+func (d *MongoStruct) ListAppointments(ctx context.Context, query models.AppointmentQuery) (*models.AppointmentPage, error) {
+	if err := normalizeQuery(&query); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(query)
+	if err != nil {
+		return nil, err
+	}
+
+	direction, comparison := 1, "$gt"
+	if query.Descending {
+		direction, comparison = -1, "$lt"
+	}
+	filter := appointmentFilterDocument(query.Filter)
+	if cursor != nil {
+		var value interface{} = cursor.Text
+		if query.SortBy == models.SortByDate {
+			value = cursor.Date
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{query.SortBy: bson.M{comparison: value}},
+			bson.M{query.SortBy: value, "_id": bson.M{comparison: cursor.ID}},
+		}}}}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: query.SortBy, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(query.Limit + 1))
+	cur, err := d.appointments().Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "appointments")
+	}
+	defer cur.Close(context.Background())
+
+	page := &models.AppointmentPage{Appointments: []models.Appointment{}}
+	for cur.Next(ctx) {
+		var appointment models.Appointment
+		if err := cur.Decode(&appointment); err != nil {
+			return nil, mongoError(err, "appointments")
+		}
+		page.Appointments = append(page.Appointments, appointment)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "appointments")
+	}
+
+	if len(page.Appointments) > query.Limit {
+		page.Appointments = page.Appointments[:query.Limit]
+		page.Next = encodeCursor(query, page.Appointments[query.Limit-1])
+	}
+	return page, nil
+}
@@ -1,8 +1,10 @@
 package db
 
 import (
+	"CarServiceCenter/src/config"
 	"CarServiceCenter/src/models"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -13,178 +15,304 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
-// ClientInterface interface
-type ClientInterface interface {
-	OpenConnection() *mongo.Client
-	CreateAppointment(models.Appointment) *models.Appointment
-	DeleteAppointment(string) bool
-	GetAppointment(string) (*models.Appointment, error)
-	GetAppointmentsWithinDateRange(time.Time, time.Time) *[]models.Appointment
-	UpdateAppointmentStatus(string, string) bool
+// MongoStruct - implements ClientInterface on top of a single long-lived, pooled mongo client
+type MongoStruct struct {
+	Client      *mongo.Client
+	Database    string
+	Collections config.MongoCollections
 }
 
-// MongoStruct - implements ClientInterface
-type MongoStruct struct{}
-
-// OpenConnection - connects to local mongodb instance
-func (d *MongoStruct) OpenConnection() *mongo.Client {
-	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+// NewMongoStruct - connects to the mongodb instance described by cfg and keeps at most cfg.PoolSize connections open
+func NewMongoStruct(cfg config.MongoConfig) (*MongoStruct, error) {
+	clientOptions := options.Client().
+		ApplyURI(cfg.URI).
+		SetMaxPoolSize(cfg.PoolSize).
+		SetConnectTimeout(time.Duration(cfg.ConnectTimeout))
+	if cfg.Username != "" {
+		clientOptions.SetAuth(options.Credential{
+			Username:   cfg.Username,
+			Password:   cfg.Password,
+			AuthSource: cfg.AuthSource,
+		})
+	}
+	client, err := mongo.NewClient(clientOptions)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ConnectTimeout))
 	defer cancel()
 	err = client.Connect(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	err = client.Ping(context.TODO(), nil)
+	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatal(err)
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	log.Printf("Connected to MongoDB database %s with a pool of up to %d connections\n", cfg.Database, cfg.PoolSize)
+	mongoStruct := &MongoStruct{Client: client, Database: cfg.Database, Collections: cfg.Collections}
+	if err := mongoStruct.EnsureIndexes(ctx); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
 	}
+	return mongoStruct, nil
+}
+
+// Close - disconnects the pooled client, waiting for in-use connections to be returned
+func (d *MongoStruct) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	err := d.Client.Disconnect(ctx)
+	if err != nil {
+		return err
+	}
+	log.Println("Connection to MongoDB closed.")
+	return nil
+}
+
+func (d *MongoStruct) appointments() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Appointments)
+}
+
+func (d *MongoStruct) customers() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Customers)
+}
+
+func (d *MongoStruct) vehicles() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Vehicles)
+}
+
+func (d *MongoStruct) catalog() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Catalog)
+}
+
+func (d *MongoStruct) technicians() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Technicians)
+}
+
+func (d *MongoStruct) workOrders() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.WorkOrders)
+}
+
+func (d *MongoStruct) invoices() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Invoices)
+}
+
+func (d *MongoStruct) estimates() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Estimates)
+}
+
+func (d *MongoStruct) parts() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Parts)
+}
+
+func (d *MongoStruct) users() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Users)
+}
+
+func (d *MongoStruct) apiKeys() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.APIKeys)
+}
+
+func (d *MongoStruct) audit() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Audit)
+}
 
-	fmt.Println("Connected to MongoDB!")
-	return client
+func (d *MongoStruct) counters() *mongo.Collection {
+	return d.Client.Database(d.Database).Collection(d.Collections.Counters)
 }
 
 // CreateAppointment - writes to db to store appointment and returns the created appointment
-func (d *MongoStruct) CreateAppointment(appointment models.Appointment) *models.Appointment {
-	client := d.OpenConnection()
-	collection := client.Database("test").Collection("appointments")
+func (d *MongoStruct) CreateAppointment(ctx context.Context, appointment models.Appointment) (*models.Appointment, error) {
+	collection := d.appointments()
 
-	insertResult, err := collection.InsertOne(context.TODO(), appointment)
+	insertResult, err := collection.InsertOne(ctx, appointment)
 	if err != nil {
-		log.Fatal(err)
+		return nil, mongoError(err, "appointment")
 	}
 	log.Println("Inserted a single document: ", insertResult.InsertedID)
 	appointment.ID = insertResult.InsertedID.(primitive.ObjectID)
+	return &appointment, nil
+}
+
+// DeleteAppointment - deletes an appointment by given id and returns the deleted document
+func (d *MongoStruct) DeleteAppointment(ctx context.Context, appointmentID string) (*models.Appointment, error) {
+	collection := d.appointments()
 
-	err = client.Disconnect(context.TODO())
+	objectID, err := parseID(appointmentID)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return &appointment
+	var appointment models.Appointment
+	if err := collection.FindOneAndDelete(ctx, bson.M{"_id": objectID}).Decode(&appointment); err != nil {
+		return nil, mongoError(err, "appointment "+appointmentID)
+	}
+	return &appointment, nil
 }
 
-// DeleteAppointment - deletes an appointment by given id and returns true if successful
-func (d *MongoStruct) DeleteAppointment(appointmentID string) bool {
-	response := true
-	client := d.OpenConnection()
-	collection := client.Database("test").Collection("appointments")
+// UpdateAppointmentStatus - moves the specified appointment to newStatus if the lifecycle allows it and returns it as it was before.
+// The update only applies if the status is unchanged since it was read, so concurrent changes return ErrConflict.
+func (d *MongoStruct) UpdateAppointmentStatus(ctx context.Context, appointmentID, newStatus string) (*models.Appointment, error) {
+	collection := d.appointments()
 
-	objectID, err := primitive.ObjectIDFromHex(appointmentID)
+	objectID, err := parseID(appointmentID)
 	if err != nil {
-		log.Println("DeleteAppointment: couldn't convert appointment ID from input:", err)
-		response = false
+		return nil, err
 	}
-	documentID := bson.M{"_id": objectID}
-	_, err = collection.DeleteOne(context.TODO(), documentID)
+	var appointment models.Appointment
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&appointment)
 	if err != nil {
-		log.Println("DeleteAppointment: couldn't delete appointment from db:", err)
-		response = false
+		return nil, mongoError(err, "appointment "+appointmentID)
 	}
-	err = client.Disconnect(context.TODO())
-	if err != nil {
-		log.Fatal(err)
+	if err := checkTransition(appointmentID, appointment.Status, newStatus); err != nil {
+		return nil, err
 	}
-	log.Println("Connection to MongoDB closed.")
-	return response
-}
 
-// UpdateAppointmentStatus - writes to db to update specified appointment with new status
-func (d *MongoStruct) UpdateAppointmentStatus(appointmentID, newStatus string) bool {
-	client := d.OpenConnection()
-	collection := client.Database("test").Collection("appointments")
-	response := true
-
-	objectID, err := primitive.ObjectIDFromHex(appointmentID)
-	if err != nil {
-		log.Println("UpdateAppointmentStatus: couldn't convert appointment ID from input:", err)
-		response = false
+	// the document returned is the one the update applied to, so it is accurate even if other fields changed since the read
+	var previous models.Appointment
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID, "status": appointment.Status},
+		bson.M{
+			"$set": bson.M{"status": newStatus},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("appointment %v was modified concurrently: %w", appointmentID, ErrConflict)
 	}
-	var appointment models.Appointment
-	documentID := bson.M{"_id": objectID}
-	err = collection.FindOne(context.TODO(), documentID).Decode(&appointment)
 	if err != nil {
-		log.Println("UpdateAppointmentStatus: couldn't decode appointment from db:", err)
-		response = false
+		return nil, mongoError(err, "appointment "+appointmentID)
 	}
-	appointment.Status = newStatus
+	return &previous, nil
+}
+
+// UpdateAppointment - writes the name, description, date, service and catalog items of appointment to the stored appointment with the same ID and returns the result.
+// The status and technicians are left untouched; they only change through UpdateAppointmentStatus and (Un)AssignAppointmentTechnician,
+// so an edit can't undo a concurrent change to them. Finished appointments return ErrInvalidTransition.
+func (d *MongoStruct) UpdateAppointment(ctx context.Context, appointment models.Appointment) (*models.Appointment, error) {
+	collection := d.appointments()
 
-	_, err = collection.UpdateOne(
-		context.TODO(),
-		documentID,
+	var result models.Appointment
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": appointment.ID, "status": bson.M{"$nin": models.FinishedStatuses()}},
 		bson.M{
-			"$set": bson.M{"status": appointment.Status},
+			"$set": bson.M{
+				"name":             appointment.Name,
+				"description":      appointment.Description,
+				"date":             appointment.Date,
+				"service":          appointment.Service,
+				"service_ids":      appointment.ServiceIDs,
+				"duration_minutes": appointment.DurationMinutes,
+			},
 		},
-	)
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// either there is no such appointment or it is finished; tell the two apart for the caller
+		stored, err := d.GetAppointment(ctx, appointment.ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		if err := checkEditable(appointment.ID.Hex(), stored.Status); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("appointment %v was modified concurrently: %w", appointment.ID.Hex(), ErrConflict)
+	}
 	if err != nil {
-		log.Println("UpdateAppointmentStatus: unable to update status:", err)
-		response = false
+		return nil, mongoError(err, "appointment "+appointment.ID.Hex())
 	}
+	return &result, nil
+}
 
-	err = client.Disconnect(context.TODO())
+// AssignAppointmentTechnician - adds technicianID to the technicians of the appointment with the given id, unless it is
+// already one of them, and returns the appointment as it was before. The list is changed in place on the server, so
+// technicians assigned concurrently are all kept.
+func (d *MongoStruct) AssignAppointmentTechnician(ctx context.Context, appointmentID string, technicianID primitive.ObjectID) (*models.Appointment, error) {
+	// technician_ids may be missing or null, which $addToSet refuses, so the list is rebuilt by an update pipeline
+	assigned := bson.M{"$ifNull": []interface{}{"$technician_ids", []interface{}{}}}
+	return d.changeTechnicians(ctx, appointmentID, bson.M{"$cond": []interface{}{
+		bson.M{"$in": []interface{}{technicianID, assigned}},
+		assigned,
+		bson.M{"$concatArrays": []interface{}{assigned, []interface{}{technicianID}}},
+	}})
+}
+
+// UnassignAppointmentTechnician - removes technicianID from the technicians of the appointment with the given id
+// and returns the appointment as it was before
+func (d *MongoStruct) UnassignAppointmentTechnician(ctx context.Context, appointmentID string, technicianID primitive.ObjectID) (*models.Appointment, error) {
+	return d.changeTechnicians(ctx, appointmentID, bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": []interface{}{"$technician_ids", []interface{}{}}},
+		"cond":  bson.M{"$ne": []interface{}{"$$this", technicianID}},
+	}})
+}
+
+// changeTechnicians - sets the technicians of the appointment with the given id to the result of the aggregation
+// expression technicians and returns the appointment as it was before
+func (d *MongoStruct) changeTechnicians(ctx context.Context, appointmentID string, technicians bson.M) (*models.Appointment, error) {
+	collection := d.appointments()
+
+	objectID, err := parseID(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	var previous models.Appointment
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID},
+		[]bson.M{{"$set": bson.M{"technician_ids": technicians}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
 	if err != nil {
-		log.Fatal(err)
+		return nil, mongoError(err, "appointment "+appointmentID)
 	}
-	return response
+	return &previous, nil
 }
 
 // GetAppointment - returns appointment by provided ID
-func (d *MongoStruct) GetAppointment(appointmentID string) (*models.Appointment, error) {
-	client := d.OpenConnection()
-	collection := client.Database("test").Collection("appointments")
-	objectID, err := primitive.ObjectIDFromHex(appointmentID)
+func (d *MongoStruct) GetAppointment(ctx context.Context, appointmentID string) (*models.Appointment, error) {
+	collection := d.appointments()
+
+	objectID, err := parseID(appointmentID)
 	if err != nil {
-		log.Println("GetAppointment: couldn't convert appointment ID from input:", err)
+		return nil, err
 	}
-
 	var result models.Appointment
-	dbErr := collection.FindOne(context.TODO(), bson.M{"_id": objectID}).Decode(&result)
-	if dbErr != nil {
-		dbErr = fmt.Errorf("Unable to retrieve appointment with ID %v", appointmentID)
-		log.Println("GetAppointment:", dbErr)
-	}
-
-	err = client.Disconnect(context.TODO())
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&result)
 	if err != nil {
-		log.Fatal(err)
+		return nil, mongoError(err, "appointment "+appointmentID)
 	}
-
-	return &result, dbErr
+	return &result, nil
 }
 
 // GetAppointmentsWithinDateRange - queries database for all appointments with dates that fall between given start and end dates and returns as list
-func (d *MongoStruct) GetAppointmentsWithinDateRange(start, end time.Time) *[]models.Appointment {
-	client := d.OpenConnection()
-	collection := client.Database("test").Collection("appointments")
+func (d *MongoStruct) GetAppointmentsWithinDateRange(ctx context.Context, start, end time.Time) (*[]models.Appointment, error) {
+	collection := d.appointments()
 
-	var results []models.Appointment
-	cur, err := collection.Find(context.TODO(), bson.M{"date": bson.M{"$gte": start, "$lte": end}})
+	cur, err := collection.Find(ctx, bson.M{"date": bson.M{"$gte": start, "$lte": end}})
 	if err != nil {
-		log.Println(err)
+		return nil, mongoError(err, "appointments")
 	}
+	// close with a fresh context so the server-side cursor is released even after ctx is canceled
+	defer cur.Close(context.Background())
 
-	for cur.Next(context.TODO()) {
+	results := []models.Appointment{}
+	for cur.Next(ctx) {
 		var appointment models.Appointment
 		err := cur.Decode(&appointment)
 		if err != nil {
-			log.Fatal(err)
+			return nil, mongoError(err, "appointments")
 		}
 
 		results = append(results, appointment)
 	}
 
 	if err := cur.Err(); err != nil {
-		log.Fatal(err)
+		return nil, mongoError(err, "appointments")
 	}
-	cur.Close(context.TODO())
-
-	err = client.Disconnect(context.TODO())
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	return &results
+	return &results, nil
 }
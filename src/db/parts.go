@@ -0,0 +1,524 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PartStore interface - parts inventory storage, following the same error and context conventions as ClientInterface.
+// SKUs are unique; storing a second part with the same SKU returns ErrConflict. Stock quantities only change through
+// AdjustStock, which applies each change atomically so concurrent requests can't reserve the same unit twice.
+type PartStore interface {
+	CreatePart(context.Context, models.Part) (*models.Part, error)
+	GetPart(context.Context, string) (*models.Part, error)
+	ListParts(context.Context, models.PartFilter) (*[]models.Part, error)
+	// UpdatePart - replaces everything about the stored part but its on-hand and reserved quantities. Work orders
+	// reserve parts by SKU, so changing the SKU of a part with reserved units returns ErrConflict.
+	UpdatePart(context.Context, models.Part) (*models.Part, error)
+	// DeletePart - deletes the part unless some of it is reserved, which returns ErrConflict
+	DeletePart(context.Context, string) error
+	// AdjustStock - adds onHand and reserved to the quantities of the part with the given SKU and returns the result.
+	// Returns ErrInsufficientStock, leaving the part unchanged, if either would drop below zero or more would be reserved than is on hand.
+	AdjustStock(ctx context.Context, sku string, onHand, reserved int) (*models.Part, error)
+}
+
+// partIndexes - indexes backing SKU uniqueness and ListParts
+var partIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "sku", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "bin", Value: 1}}},
+}
+
+// duplicateSKU - the error returned when part's SKU is already taken by another part
+func duplicateSKU(part models.Part) error {
+	return fmt.Errorf("a part with sku %v already exists: %w", part.SKU, ErrConflict)
+}
+
+// partReserved - the error returned when deleting a part that is reserved for work orders
+func partReserved(part models.Part) error {
+	return fmt.Errorf("part %v has %d units reserved: %w", part.SKU, part.Reserved, ErrConflict)
+}
+
+// skuReserved - the error returned when changing the SKU of a part that is reserved for work orders
+func skuReserved(part models.Part) error {
+	return fmt.Errorf("part %v has %d units reserved, so its sku can't change: %w", part.SKU, part.Reserved, ErrConflict)
+}
+
+// checkSKUChange - returns skuReserved if part changes the SKU of stored while some of it is reserved
+func checkSKUChange(part, stored models.Part) error {
+	if part.SKU != stored.SKU && stored.Reserved != 0 {
+		return skuReserved(stored)
+	}
+	return nil
+}
+
+// stockRetries - how many times a conditional Mongo update is tried again when the part changed between
+// the update and the read that explains why it didn't match
+const stockRetries = 5
+
+// errStockChanged - returned when a part kept changing under a conditional update for stockRetries attempts
+func errStockChanged(sku string) error {
+	return fmt.Errorf("part %v is being changed by other requests, try again: %w", sku, ErrConflict)
+}
+
+// adjustStock - applies a stock change to part, or returns ErrInsufficientStock if the change isn't possible
+func adjustStock(part *models.Part, onHand, reserved int) error {
+	if part.OnHand+onHand < 0 || part.Reserved+reserved < 0 || part.OnHand+onHand < part.Reserved+reserved {
+		return fmt.Errorf("part %v %w: %d on hand, %d reserved", part.SKU, ErrInsufficientStock, part.OnHand, part.Reserved)
+	}
+	part.OnHand += onHand
+	part.Reserved += reserved
+	return nil
+}
+
+// matchesPartFilter - reports whether part satisfies every field set in filter
+func matchesPartFilter(part models.Part, filter models.PartFilter) bool {
+	switch {
+	case filter.SKU != "" && part.SKU != filter.SKU:
+		return false
+	case filter.Bin != "" && part.Bin != filter.Bin:
+		return false
+	case filter.Reorder && !part.NeedsReorder():
+		return false
+	}
+	return true
+}
+
+// sortParts - orders parts by SKU
+func sortParts(parts []models.Part) {
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].SKU < parts[j].SKU
+	})
+}
+
+// CreatePart - writes part to its collection and returns the stored copy
+func (d *MongoStruct) CreatePart(ctx context.Context, part models.Part) (*models.Part, error) {
+	part.ID = primitive.NewObjectID()
+	if _, err := d.parts().InsertOne(ctx, part); err != nil {
+		if err = mongoError(err, "part"); errors.Is(err, ErrConflict) {
+			return nil, duplicateSKU(part)
+		}
+		return nil, err
+	}
+	return &part, nil
+}
+
+// GetPart - returns the part with the given id
+func (d *MongoStruct) GetPart(ctx context.Context, partID string) (*models.Part, error) {
+	objectID, err := parseID(partID)
+	if err != nil {
+		return nil, err
+	}
+	var part models.Part
+	if err := d.parts().FindOne(ctx, bson.M{"_id": objectID}).Decode(&part); err != nil {
+		return nil, mongoError(err, "part "+partID)
+	}
+	return &part, nil
+}
+
+// ListParts - returns every part matching filter ordered by SKU
+func (d *MongoStruct) ListParts(ctx context.Context, filter models.PartFilter) (*[]models.Part, error) {
+	document := bson.M{}
+	if filter.SKU != "" {
+		document["sku"] = filter.SKU
+	}
+	if filter.Bin != "" {
+		document["bin"] = filter.Bin
+	}
+	if filter.Reorder {
+		document["$expr"] = bson.M{"$lte": bson.A{bson.M{"$subtract": bson.A{"$on_hand", "$reserved"}}, "$reorder_point"}}
+	}
+
+	cur, err := d.parts().Find(ctx, document, options.Find().SetSort(bson.D{{Key: "sku", Value: 1}}))
+	if err != nil {
+		return nil, mongoError(err, "parts")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.Part{}
+	for cur.Next(ctx) {
+		var part models.Part
+		if err := cur.Decode(&part); err != nil {
+			return nil, mongoError(err, "parts")
+		}
+		results = append(results, part)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "parts")
+	}
+	return &results, nil
+}
+
+// UpdatePart - sets everything but the quantities of the stored part with the same ID and returns the result. The
+// update only matches while the SKU is unchanged or nothing is reserved, so a reservation can't slip in between.
+func (d *MongoStruct) UpdatePart(ctx context.Context, part models.Part) (*models.Part, error) {
+	filter := bson.M{"_id": part.ID, "$or": bson.A{bson.M{"sku": part.SKU}, bson.M{"reserved": 0}}}
+	for attempt := 0; attempt < stockRetries; attempt++ {
+		var result models.Part
+		err := d.parts().FindOneAndUpdate(
+			ctx,
+			filter,
+			bson.M{"$set": bson.M{"sku": part.SKU, "name": part.Name, "bin": part.Bin, "reorder_point": part.ReorderPoint}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&result)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// tell a missing part apart from a reserved one, and try again if it changed in the meantime
+			stored, err := d.GetPart(ctx, part.ID.Hex())
+			if err != nil {
+				return nil, err
+			}
+			if err := checkSKUChange(part, *stored); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			if err = mongoError(err, "part "+part.ID.Hex()); errors.Is(err, ErrConflict) {
+				return nil, duplicateSKU(part)
+			}
+			return nil, err
+		}
+		return &result, nil
+	}
+	return nil, errStockChanged(part.SKU)
+}
+
+// DeletePart - deletes the part with the given id if none of it is reserved
+func (d *MongoStruct) DeletePart(ctx context.Context, partID string) error {
+	objectID, err := parseID(partID)
+	if err != nil {
+		return err
+	}
+	deleteResult, err := d.parts().DeleteOne(ctx, bson.M{"_id": objectID, "reserved": 0})
+	if err != nil {
+		return mongoError(err, "part "+partID)
+	}
+	if deleteResult.DeletedCount == 0 {
+		part, err := d.GetPart(ctx, partID)
+		if err != nil {
+			return err
+		}
+		return partReserved(*part)
+	}
+	return nil
+}
+
+// AdjustStock - changes the part's quantities in a single update whose filter only matches while the change is possible
+func (d *MongoStruct) AdjustStock(ctx context.Context, sku string, onHand, reserved int) (*models.Part, error) {
+	filter := bson.M{
+		"sku":      sku,
+		"on_hand":  bson.M{"$gte": -onHand},
+		"reserved": bson.M{"$gte": -reserved},
+		// on_hand + onHand >= reserved + reserved, rearranged so it compares stored fields with a constant
+		"$expr": bson.M{"$gte": bson.A{bson.M{"$subtract": bson.A{"$on_hand", "$reserved"}}, reserved - onHand}},
+	}
+	for attempt := 0; attempt < stockRetries; attempt++ {
+		var result models.Part
+		err := d.parts().FindOneAndUpdate(
+			ctx,
+			filter,
+			bson.M{"$inc": bson.M{"on_hand": onHand, "reserved": reserved}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&result)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// tell a missing part apart from one without enough stock; if stock freed up since the update, try it again
+			var part models.Part
+			if err := d.parts().FindOne(ctx, bson.M{"sku": sku}).Decode(&part); err != nil {
+				return nil, mongoError(err, "part "+sku)
+			}
+			if err := adjustStock(&part, onHand, reserved); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, mongoError(err, "part "+sku)
+		}
+		return &result, nil
+	}
+	return nil, errStockChanged(sku)
+}
+
+// skuTaken - reports whether a part other than part already has its SKU. Callers must hold m.mu.
+func (m *MemoryStore) skuTaken(part models.Part) bool {
+	for id, other := range m.parts {
+		if id != part.ID && other.SKU == part.SKU {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatePart - stores part under a newly generated ID and returns the stored copy
+func (m *MemoryStore) CreatePart(ctx context.Context, part models.Part) (*models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	part.ID = primitive.NewObjectID()
+	if m.skuTaken(part) {
+		return nil, duplicateSKU(part)
+	}
+	m.parts[part.ID] = part
+	return &part, nil
+}
+
+// GetPart - returns a copy of the part with the given id
+func (m *MemoryStore) GetPart(ctx context.Context, partID string) (*models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(partID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	part, ok := m.parts[objectID]
+	if !ok {
+		return nil, fmt.Errorf("part %v %w", partID, ErrNotFound)
+	}
+	return &part, nil
+}
+
+// ListParts - returns every part matching filter ordered by SKU
+func (m *MemoryStore) ListParts(ctx context.Context, filter models.PartFilter) (*[]models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.Part{}
+	for _, part := range m.parts {
+		if matchesPartFilter(part, filter) {
+			results = append(results, part)
+		}
+	}
+	sortParts(results)
+	return &results, nil
+}
+
+// UpdatePart - replaces the stored part with the same ID, keeping its quantities
+func (m *MemoryStore) UpdatePart(ctx context.Context, part models.Part) (*models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.parts[part.ID]
+	if !ok {
+		return nil, fmt.Errorf("part %v %w", part.ID.Hex(), ErrNotFound)
+	}
+	if m.skuTaken(part) {
+		return nil, duplicateSKU(part)
+	}
+	if err := checkSKUChange(part, stored); err != nil {
+		return nil, err
+	}
+	part.OnHand, part.Reserved = stored.OnHand, stored.Reserved
+	m.parts[part.ID] = part
+	return &part, nil
+}
+
+// DeletePart - removes the part with the given id if none of it is reserved
+func (m *MemoryStore) DeletePart(ctx context.Context, partID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(partID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	part, ok := m.parts[objectID]
+	if !ok {
+		return fmt.Errorf("part %v %w", partID, ErrNotFound)
+	}
+	if part.Reserved != 0 {
+		return partReserved(part)
+	}
+	delete(m.parts, objectID)
+	return nil
+}
+
+// AdjustStock - changes the quantities of the part with the given SKU while holding the store's lock
+func (m *MemoryStore) AdjustStock(ctx context.Context, sku string, onHand, reserved int) (*models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, part := range m.parts {
+		if part.SKU != sku {
+			continue
+		}
+		if err := adjustStock(&part, onHand, reserved); err != nil {
+			return nil, err
+		}
+		m.parts[id] = part
+		return &part, nil
+	}
+	return nil, fmt.Errorf("part %v %w", sku, ErrNotFound)
+}
+
+// putPart - writes part and its SKU index entry, replacing the entry for previous if given
+func putPart(tx *bolt.Tx, part models.Part, previous *models.Part) error {
+	previousSKU := ""
+	if previous != nil {
+		previousSKU = previous.SKU
+	}
+	if err := putUniqueKey(tx, partsBySKUBucket, part.SKU, previousSKU, part.ID, duplicateSKU(part)); err != nil {
+		return err
+	}
+	return putRecord(tx, partsBucket, part.ID, part)
+}
+
+// CreatePart - stores part under a newly generated ID and returns the stored copy
+func (b *BoltStore) CreatePart(ctx context.Context, part models.Part) (*models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	part.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		return putPart(tx, part, nil)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &part, nil
+}
+
+// GetPart - returns the part with the given id
+func (b *BoltStore) GetPart(ctx context.Context, partID string) (*models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(partID)
+	if err != nil {
+		return nil, err
+	}
+	var part models.Part
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, partsBucket, objectID, "part", &part)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &part, nil
+}
+
+// ListParts - walks the SKU index and returns every part matching filter ordered by SKU
+func (b *BoltStore) ListParts(ctx context.Context, filter models.PartFilter) (*[]models.Part, error) {
+	results := []models.Part{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(partsBySKUBucket).ForEach(func(_, value []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var id primitive.ObjectID
+			copy(id[:], value)
+			var part models.Part
+			if err := getRecord(tx, partsBucket, id, "part", &part); err != nil {
+				return err
+			}
+			if matchesPartFilter(part, filter) {
+				results = append(results, part)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &results, nil
+}
+
+// UpdatePart - replaces the stored part with the same ID, keeping its quantities
+func (b *BoltStore) UpdatePart(ctx context.Context, part models.Part) (*models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		var previous models.Part
+		if err := getRecord(tx, partsBucket, part.ID, "part", &previous); err != nil {
+			return err
+		}
+		if err := checkSKUChange(part, previous); err != nil {
+			return err
+		}
+		part.OnHand, part.Reserved = previous.OnHand, previous.Reserved
+		return putPart(tx, part, &previous)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &part, nil
+}
+
+// DeletePart - removes the part with the given id and its SKU index entry if none of it is reserved
+func (b *BoltStore) DeletePart(ctx context.Context, partID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(partID)
+	if err != nil {
+		return err
+	}
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		var part models.Part
+		if err := getRecord(tx, partsBucket, objectID, "part", &part); err != nil {
+			return err
+		}
+		if part.Reserved != 0 {
+			return partReserved(part)
+		}
+		if err := tx.Bucket(partsBySKUBucket).Delete([]byte(part.SKU)); err != nil {
+			return err
+		}
+		return tx.Bucket(partsBucket).Delete(objectID[:])
+	})
+	return boltError(err)
+}
+
+// AdjustStock - changes the quantities of the part with the given SKU within a single write transaction
+func (b *BoltStore) AdjustStock(ctx context.Context, sku string, onHand, reserved int) (*models.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var part models.Part
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		value := tx.Bucket(partsBySKUBucket).Get([]byte(sku))
+		if value == nil {
+			return fmt.Errorf("part %v %w", sku, ErrNotFound)
+		}
+		var id primitive.ObjectID
+		copy(id[:], value)
+		if err := getRecord(tx, partsBucket, id, "part", &part); err != nil {
+			return err
+		}
+		if err := adjustStock(&part, onHand, reserved); err != nil {
+			return err
+		}
+		return putRecord(tx, partsBucket, part.ID, part)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &part, nil
+}
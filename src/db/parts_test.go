@@ -0,0 +1,111 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestStoreParts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+
+		pads, err := store.CreatePart(ctx, models.Part{SKU: "BP-100", Name: "Brake pads", Bin: "A1", OnHand: 4, ReorderPoint: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreatePart(ctx, models.Part{SKU: "BP-100", Name: "Other pads"}); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict for a duplicate sku", err)
+		}
+		filter, err := store.CreatePart(ctx, models.Part{SKU: "F-1", Name: "Oil filter", Bin: "B2", OnHand: 10, ReorderPoint: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if part, err := store.AdjustStock(ctx, "BP-100", 0, 3); err != nil || part.OnHand != 4 || part.Reserved != 3 {
+			t.Fatalf("got %+v, %v want 3 of 4 reserved", part, err)
+		}
+		if _, err := store.AdjustStock(ctx, "BP-100", 0, 2); !errors.Is(err, ErrInsufficientStock) || !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrInsufficientStock reserving more than is available", err)
+		}
+		if _, err := store.AdjustStock(ctx, "BP-100", -2, 0); !errors.Is(err, ErrInsufficientStock) {
+			t.Errorf("got %v want ErrInsufficientStock writing off reserved stock", err)
+		}
+		if _, err := store.AdjustStock(ctx, "NOPE", 1, 0); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound for an unknown sku", err)
+		}
+		if part, err := store.AdjustStock(ctx, "BP-100", -2, -2); err != nil || part.OnHand != 2 || part.Reserved != 1 {
+			t.Errorf("got %+v, %v want 2 on hand and 1 reserved after consuming 2", part, err)
+		}
+
+		reorder, err := store.ListParts(ctx, models.PartFilter{Reorder: true})
+		if err != nil || len(*reorder) != 1 || (*reorder)[0].ID != pads.ID {
+			t.Errorf("got %+v, %v want only the brake pads to reorder", reorder, err)
+		}
+		all, err := store.ListParts(ctx, models.PartFilter{})
+		if err != nil || len(*all) != 2 || (*all)[1].ID != filter.ID {
+			t.Errorf("got %+v, %v want every part ordered by sku", all, err)
+		}
+
+		pads.Name, pads.OnHand = "Ceramic brake pads", 100
+		updated, err := store.UpdatePart(ctx, *pads)
+		if err != nil || updated.Name != "Ceramic brake pads" || updated.OnHand != 2 || updated.Reserved != 1 {
+			t.Errorf("got %+v, %v want the name changed and quantities kept", updated, err)
+		}
+		pads.SKU = "BP-101"
+		if _, err := store.UpdatePart(ctx, *pads); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict changing the sku of a reserved part", err)
+		}
+		filter.SKU = "F-2"
+		if _, err := store.UpdatePart(ctx, *filter); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AdjustStock(ctx, "F-2", 1, 0); err != nil {
+			t.Errorf("got %v want the part found under its new sku", err)
+		}
+		filter.SKU = "BP-100"
+		if _, err := store.UpdatePart(ctx, *filter); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict taking another part's sku", err)
+		}
+
+		if err := store.DeletePart(ctx, pads.ID.Hex()); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict deleting a reserved part", err)
+		}
+		if err := store.DeletePart(ctx, filter.ID.Hex()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetPart(ctx, filter.ID.Hex()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want the deleted part gone", err)
+		}
+	})
+}
+
+func TestStoreConcurrentReservations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		if _, err := store.CreatePart(ctx, models.Part{SKU: "BP-100", Name: "Brake pads", OnHand: 5}); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		reserved := 0
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := store.AdjustStock(ctx, "BP-100", 0, 1); err == nil {
+					mu.Lock()
+					reserved++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if reserved != 5 {
+			t.Errorf("got %v reservations want exactly the 5 on hand", reserved)
+		}
+	})
+}
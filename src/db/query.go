@@ -0,0 +1,158 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pageCursor - position of the last appointment on a page, encoded into AppointmentPage.Next.
+// It holds the value of the sort field and the ID used to break ties.
+type pageCursor struct {
+	SortBy     string             `json:"s"`
+	Descending bool               `json:"o,omitempty"`
+	Date       time.Time          `json:"d,omitempty"`
+	Text       string             `json:"t,omitempty"`
+	ID         primitive.ObjectID `json:"i"`
+}
+
+// normalizeQuery - fills in the default sort field and page size and rejects unsupported values
+func normalizeQuery(query *models.AppointmentQuery) error {
+	switch query.SortBy {
+	case "":
+		query.SortBy = models.SortByDate
+	case models.SortByDate, models.SortByName, models.SortByStatus:
+	default:
+		return fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, query.SortBy)
+	}
+	if query.Limit <= 0 {
+		query.Limit = models.DefaultPageSize
+	}
+	if query.Limit > models.MaxPageSize {
+		query.Limit = models.MaxPageSize
+	}
+	return nil
+}
+
+// encodeCursor - builds the Next token pointing just past appointment
+func encodeCursor(query models.AppointmentQuery, appointment models.Appointment) string {
+	cursor := pageCursor{SortBy: query.SortBy, Descending: query.Descending, ID: appointment.ID}
+	switch query.SortBy {
+	case models.SortByDate:
+		cursor.Date = appointment.Date
+	case models.SortByName:
+		cursor.Text = appointment.Name
+	case models.SortByStatus:
+		cursor.Text = appointment.Status
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor - parses query.After, which must have been issued for the same sort order
+func decodeCursor(query models.AppointmentQuery) (*pageCursor, error) {
+	if query.After == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(query.After)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed page token", ErrInvalidQuery)
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed page token", ErrInvalidQuery)
+	}
+	if cursor.SortBy != query.SortBy || cursor.Descending != query.Descending {
+		return nil, fmt.Errorf("%w: page token was issued for a different sort order", ErrInvalidQuery)
+	}
+	return &cursor, nil
+}
+
+// matchesFilter - reports whether appointment satisfies every criterion of filter
+func matchesFilter(appointment models.Appointment, filter models.AppointmentFilter) bool {
+	switch {
+	case filter.Status != "" && appointment.Status != filter.Status:
+		return false
+	case filter.Name != "" && !strings.Contains(strings.ToLower(appointment.Name), strings.ToLower(filter.Name)):
+		return false
+	case !filter.Start.IsZero() && appointment.Date.Before(filter.Start):
+		return false
+	case !filter.End.IsZero() && appointment.Date.After(filter.End):
+		return false
+	case filter.CustomerID != nil && (appointment.CustomerID == nil || *appointment.CustomerID != *filter.CustomerID):
+		return false
+	case filter.VehicleID != nil && (appointment.VehicleID == nil || *appointment.VehicleID != *filter.VehicleID):
+		return false
+	case filter.TechnicianID != nil && !containsID(appointment.TechnicianIDs, *filter.TechnicianID):
+		return false
+	}
+	return true
+}
+
+// compareAppointments - orders two appointments by the sort field and then by ID, ascending
+func compareAppointments(a, b models.Appointment, sortBy string) int {
+	result := 0
+	switch sortBy {
+	case models.SortByDate:
+		if a.Date.Before(b.Date) {
+			result = -1
+		} else if a.Date.After(b.Date) {
+			result = 1
+		}
+	case models.SortByName:
+		result = strings.Compare(a.Name, b.Name)
+	case models.SortByStatus:
+		result = strings.Compare(a.Status, b.Status)
+	}
+	if result == 0 {
+		result = bytes.Compare(a.ID[:], b.ID[:])
+	}
+	return result
+}
+
+// pageAppointments - filters, sorts and paginates candidates in memory for the embedded stores.
+// candidates may include appointments that don't match the filter.
+func pageAppointments(candidates []models.Appointment, query models.AppointmentQuery) (*models.AppointmentPage, error) {
+	if err := normalizeQuery(&query); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(query)
+	if err != nil {
+		return nil, err
+	}
+
+	order := func(a, b models.Appointment) int {
+		if query.Descending {
+			return compareAppointments(b, a, query.SortBy)
+		}
+		return compareAppointments(a, b, query.SortBy)
+	}
+	var position models.Appointment
+	if cursor != nil {
+		position = models.Appointment{ID: cursor.ID, Date: cursor.Date, Name: cursor.Text, Status: cursor.Text}
+	}
+
+	matches := []models.Appointment{}
+	for _, appointment := range candidates {
+		if matchesFilter(appointment, query.Filter) && (cursor == nil || order(appointment, position) > 0) {
+			matches = append(matches, appointment)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return order(matches[i], matches[j]) < 0
+	})
+
+	page := &models.AppointmentPage{Appointments: matches}
+	if len(matches) > query.Limit {
+		page.Appointments = matches[:query.Limit]
+		page.Next = encodeCursor(query, page.Appointments[query.Limit-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,393 @@
+package db
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testStores - every Database implementation that can run without external services
+func testStores(t *testing.T) map[string]Database {
+	boltStore, err := NewBoltStore(config.BoltConfig{
+		Path:        filepath.Join(t.TempDir(), "test.db"),
+		OpenTimeout: config.Duration(time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { boltStore.Close() })
+	return map[string]Database{
+		"memory": NewMemoryStore(),
+		"bolt":   boltStore,
+	}
+}
+
+// forEachStore - runs test as a subtest against each of testStores
+func forEachStore(t *testing.T, test func(t *testing.T, store Database)) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) { test(t, store) })
+	}
+}
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestStoreCreateAndGet(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+
+		created, err := store.CreateAppointment(ctx, models.Appointment{Name: "Oil change", Status: "open"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.ID.IsZero() {
+			t.Fatal("expected a generated ID")
+		}
+
+		found, err := store.GetAppointment(ctx, created.ID.Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Name != "Oil change" {
+			t.Errorf("got name %v want Oil change", found.Name)
+		}
+	})
+}
+
+func TestStoreErrors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		missing := "5d66c4d5a9a5b2d6b7e1f000"
+
+		if _, err := store.GetAppointment(ctx, "not-an-id"); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("got %v want ErrInvalidID", err)
+		}
+		if _, err := store.GetAppointment(ctx, missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound", err)
+		}
+		if _, err := store.DeleteAppointment(ctx, missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound", err)
+		}
+		if _, err := store.UpdateAppointmentStatus(ctx, missing, "confirmed"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound", err)
+		}
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := store.CreateAppointment(canceled, models.Appointment{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v want context.Canceled", err)
+		}
+	})
+}
+
+func TestStoreUpdateAndDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		created, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Status: "open"})
+
+		if _, err := store.UpdateAppointmentStatus(ctx, created.ID.Hex(), "confirmed"); err != nil {
+			t.Fatal(err)
+		}
+		found, _ := store.GetAppointment(ctx, created.ID.Hex())
+		if found.Status != "confirmed" {
+			t.Errorf("got status %v want confirmed", found.Status)
+		}
+
+		if _, err := store.DeleteAppointment(ctx, created.ID.Hex()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetAppointment(ctx, created.ID.Hex()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound after delete", err)
+		}
+	})
+}
+
+func TestStoreStatusTransitions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		created, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Status: models.StatusOpen})
+		id := created.ID.Hex()
+
+		for _, status := range []string{models.StatusCheckedIn, models.StatusInProgress, models.StatusCompleted} {
+			if _, err := store.UpdateAppointmentStatus(ctx, id, status); err != nil {
+				t.Fatalf("moving to %v: %v", status, err)
+			}
+		}
+		if _, err := store.UpdateAppointmentStatus(ctx, id, models.StatusOpen); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("got %v want ErrInvalidTransition", err)
+		}
+		found, _ := store.GetAppointment(ctx, id)
+		if found.Status != models.StatusCompleted {
+			t.Errorf("got status %v want %v", found.Status, models.StatusCompleted)
+		}
+
+		abandoned, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Engine", Status: models.StatusInProgress})
+		if _, err := store.UpdateAppointmentStatus(ctx, abandoned.ID.Hex(), models.StatusCancelled); err != nil {
+			t.Errorf("got %v want an appointment in progress cancelled", err)
+		}
+
+		legacy, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Tires", Status: models.StatusClosed})
+		if _, err := store.UpdateAppointmentStatus(ctx, legacy.ID.Hex(), models.StatusPickedUp); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("got %v want ErrInvalidTransition moving a closed appointment", err)
+		}
+		page, err := store.ListAppointments(ctx, models.AppointmentQuery{Filter: models.AppointmentFilter{Status: models.StatusClosed}, Limit: 10})
+		if err != nil || len(page.Appointments) != 1 || page.Appointments[0].ID != legacy.ID {
+			t.Errorf("got %+v, %v want the closed appointment", page, err)
+		}
+	})
+}
+
+func TestStoreUpdateAppointment(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		oldDate := mustParseTime(t, "2019-08-28T09:00:00Z")
+		newDate := mustParseTime(t, "2019-09-02T14:00:00Z")
+		created, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "front pads", Status: models.StatusConfirmed, Date: oldDate})
+
+		update := *created
+		update.Description = "front and rear pads"
+		update.Date = newDate
+		update.Status = models.StatusCompleted
+		updated, err := store.UpdateAppointment(ctx, update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Description != "front and rear pads" || !updated.Date.Equal(newDate) {
+			t.Errorf("unexpected updated appointment %+v", updated)
+		}
+		if updated.Status != models.StatusConfirmed {
+			t.Errorf("got status %v want %v", updated.Status, models.StatusConfirmed)
+		}
+
+		old, _ := store.GetAppointmentsWithinDateRange(ctx, oldDate, oldDate)
+		moved, _ := store.GetAppointmentsWithinDateRange(ctx, newDate, newDate)
+		if len(*old) != 0 || len(*moved) != 1 {
+			t.Errorf("got %v appointments at the old date and %v at the new date", len(*old), len(*moved))
+		}
+
+		for _, status := range models.FinishedStatuses() {
+			finished, _ := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "front pads", Status: status, Date: oldDate})
+			edit := *finished
+			edit.Date = newDate
+			if _, err := store.UpdateAppointment(ctx, edit); !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("got %v want ErrInvalidTransition editing a %v appointment", err, status)
+			}
+			if stored, _ := store.GetAppointment(ctx, finished.ID.Hex()); !stored.Date.Equal(oldDate) {
+				t.Errorf("got %+v want the %v appointment left as it was", stored, status)
+			}
+		}
+
+		update.ID = primitive.NewObjectID()
+		if _, err := store.UpdateAppointment(ctx, update); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreAppointmentCopies(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		service, technician, customer := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		date := mustParseTime(t, "2019-08-28T09:00:00Z")
+		customerID := customer
+		appointment := models.Appointment{Name: "Brakes", Description: "front pads", Status: models.StatusOpen, Date: date,
+			CustomerID: &customerID, ServiceIDs: []primitive.ObjectID{service}, TechnicianIDs: []primitive.ObjectID{technician}}
+		created, err := store.CreateAppointment(ctx, appointment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := created.ID.Hex()
+
+		// change everything callers were handed, without going through the store
+		appointment.ServiceIDs[0], appointment.TechnicianIDs[0] = primitive.NewObjectID(), primitive.NewObjectID()
+		created.ServiceIDs[0], created.TechnicianIDs[0], *created.CustomerID = primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		found, _ := store.GetAppointment(ctx, id)
+		found.ServiceIDs[0], found.TechnicianIDs[0] = primitive.NewObjectID(), primitive.NewObjectID()
+		inRange, _ := store.GetAppointmentsWithinDateRange(ctx, date, date)
+		(*inRange)[0].TechnicianIDs[0] = primitive.NewObjectID()
+		page, _ := store.ListAppointments(ctx, models.AppointmentQuery{Limit: 10})
+		page.Appointments[0].ServiceIDs[0] = primitive.NewObjectID()
+		before, _ := store.UpdateAppointmentStatus(ctx, id, models.StatusConfirmed)
+		before.TechnicianIDs[0] = primitive.NewObjectID()
+
+		stored, err := store.GetAppointment(ctx, id)
+		if err != nil || stored.ServiceIDs[0] != service || stored.TechnicianIDs[0] != technician || *stored.CustomerID != customer {
+			t.Errorf("got %+v, %v want the stored appointment unchanged by its callers", stored, err)
+		}
+	})
+}
+
+func TestStoreDateRange(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		for _, date := range []string{"2019-08-30T09:00:00Z", "2019-08-01T09:00:00Z", "2019-08-15T09:00:00Z", "2019-09-15T09:00:00Z"} {
+			store.CreateAppointment(ctx, models.Appointment{Name: date, Date: mustParseTime(t, date)})
+		}
+
+		results, err := store.GetAppointmentsWithinDateRange(ctx, mustParseTime(t, "2019-08-01T09:00:00Z"), mustParseTime(t, "2019-08-30T09:00:00Z"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, appointment := range *results {
+			names = append(names, appointment.Name)
+		}
+		expected := []string{"2019-08-01T09:00:00Z", "2019-08-15T09:00:00Z", "2019-08-30T09:00:00Z"}
+		if len(names) != len(expected) {
+			t.Fatalf("got %v want %v", names, expected)
+		}
+		for i := range expected {
+			if names[i] != expected[i] {
+				t.Errorf("got %v want %v", names, expected)
+			}
+		}
+	})
+}
+
+func TestStoreConcurrentCreates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		date := mustParseTime(t, "2019-08-28T09:00:00Z")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				store.CreateAppointment(ctx, models.Appointment{Date: date})
+			}()
+		}
+		wg.Wait()
+
+		results, _ := store.GetAppointmentsWithinDateRange(ctx, date, date)
+		if len(*results) != 50 {
+			t.Errorf("got %v appointments want 50", len(*results))
+		}
+	})
+}
+
+func TestBoltStorePersistsAcrossReopen(t *testing.T) {
+	cfg := config.BoltConfig{
+		Path:        filepath.Join(t.TempDir(), "persist.db"),
+		OpenTimeout: config.Duration(time.Second),
+	}
+	ctx := context.Background()
+	date := mustParseTime(t, "2019-08-28T09:00:00Z")
+
+	store, err := NewBoltStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := store.CreateAppointment(ctx, models.Appointment{Name: "Tires", Status: "open", Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.UpdateAppointmentStatus(ctx, created.ID.Hex(), "confirmed")
+	store.Close()
+
+	store, err = NewBoltStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	results, err := store.GetAppointmentsWithinDateRange(ctx, date, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*results) != 1 || (*results)[0].ID != created.ID || (*results)[0].Status != "confirmed" {
+		t.Errorf("unexpected appointments after reopen %+v", *results)
+	}
+}
+
+func TestStoreListAppointments(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		customer := primitive.NewObjectID()
+		technician := primitive.NewObjectID()
+		seed := []models.Appointment{
+			{Name: "Oil change", Status: models.StatusOpen, Date: mustParseTime(t, "2019-08-01T09:00:00Z"), CustomerID: &customer},
+			{Name: "Brake job", Status: models.StatusConfirmed, Date: mustParseTime(t, "2019-08-02T09:00:00Z"), TechnicianIDs: []primitive.ObjectID{primitive.NewObjectID(), technician}},
+			{Name: "OIL CHANGE", Status: models.StatusOpen, Date: mustParseTime(t, "2019-08-03T09:00:00Z")},
+			{Name: "Tire rotation", Status: models.StatusOpen, Date: mustParseTime(t, "2019-08-04T09:00:00Z"), CustomerID: &customer},
+			{Name: "Inspection", Status: models.StatusOpen, Date: mustParseTime(t, "2019-08-05T09:00:00Z"), TechnicianIDs: []primitive.ObjectID{technician}},
+		}
+		for _, appointment := range seed {
+			if _, err := store.CreateAppointment(ctx, appointment); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		names := func(page *models.AppointmentPage) []string {
+			var result []string
+			for _, appointment := range page.Appointments {
+				result = append(result, appointment.Name)
+			}
+			return result
+		}
+		tests := map[string]struct {
+			query    models.AppointmentQuery
+			expected []string
+		}{
+			"status":      {models.AppointmentQuery{Filter: models.AppointmentFilter{Status: models.StatusConfirmed}}, []string{"Brake job"}},
+			"name":        {models.AppointmentQuery{Filter: models.AppointmentFilter{Name: "oil"}}, []string{"Oil change", "OIL CHANGE"}},
+			"customer":    {models.AppointmentQuery{Filter: models.AppointmentFilter{CustomerID: &customer}}, []string{"Oil change", "Tire rotation"}},
+			"technician":  {models.AppointmentQuery{Filter: models.AppointmentFilter{TechnicianID: &technician}}, []string{"Brake job", "Inspection"}},
+			"date range":  {models.AppointmentQuery{Filter: models.AppointmentFilter{Start: mustParseTime(t, "2019-08-02T09:00:00Z"), End: mustParseTime(t, "2019-08-03T09:00:00Z")}}, []string{"Brake job", "OIL CHANGE"}},
+			"sort name":   {models.AppointmentQuery{SortBy: models.SortByName}, []string{"Brake job", "Inspection", "OIL CHANGE", "Oil change", "Tire rotation"}},
+			"descending":  {models.AppointmentQuery{Filter: models.AppointmentFilter{Status: models.StatusOpen}, Descending: true}, []string{"Inspection", "Tire rotation", "OIL CHANGE", "Oil change"}},
+			"status sort": {models.AppointmentQuery{SortBy: models.SortByStatus, Limit: 1}, []string{"Brake job"}},
+		}
+		for name, test := range tests {
+			page, err := store.ListAppointments(ctx, test.query)
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+			if got := names(page); strings.Join(got, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("%v: got %v want %v", name, got, test.expected)
+			}
+		}
+
+		var all []string
+		query := models.AppointmentQuery{Limit: 2, Descending: true}
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatal("pagination did not terminate")
+			}
+			page, err := store.ListAppointments(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, names(page)...)
+			if page.Next == "" {
+				break
+			}
+			query.After = page.Next
+		}
+		expected := []string{"Inspection", "Tire rotation", "OIL CHANGE", "Brake job", "Oil change"}
+		if strings.Join(all, ",") != strings.Join(expected, ",") {
+			t.Errorf("got pages %v want %v", all, expected)
+		}
+
+		if _, err := store.ListAppointments(ctx, models.AppointmentQuery{After: "garbage"}); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("got %v want ErrInvalidQuery", err)
+		}
+		query.Descending = false
+		if _, err := store.ListAppointments(ctx, query); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("got %v want ErrInvalidQuery for a token from another sort order", err)
+		}
+	})
+}
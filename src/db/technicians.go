@@ -0,0 +1,314 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TechnicianStore interface - technician roster storage, following the same error and context conventions as ClientInterface
+type TechnicianStore interface {
+	CreateTechnician(context.Context, models.Technician) (*models.Technician, error)
+	GetTechnician(context.Context, string) (*models.Technician, error)
+	ListTechnicians(context.Context, models.TechnicianFilter) (*[]models.Technician, error)
+	UpdateTechnician(context.Context, models.Technician) (*models.Technician, error)
+	DeleteTechnician(context.Context, string) error
+}
+
+// technicianIndexes - indexes backing ListTechnicians
+var technicianIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "skills", Value: 1}}},
+}
+
+// matchesTechnicianFilter - reports whether technician satisfies every field set in filter
+func matchesTechnicianFilter(technician models.Technician, filter models.TechnicianFilter) bool {
+	return filter.Skill == "" || containsString(technician.Skills, filter.Skill, true)
+}
+
+// containsID - reports whether ids includes id
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, value := range ids {
+		if value == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTechnicians - orders technicians by name, then by ID so technicians sharing a name keep a stable order
+func sortTechnicians(technicians []models.Technician) {
+	sort.Slice(technicians, func(i, j int) bool {
+		if technicians[i].Name == technicians[j].Name {
+			return technicians[i].ID.Hex() < technicians[j].ID.Hex()
+		}
+		return technicians[i].Name < technicians[j].Name
+	})
+}
+
+// CreateTechnician - writes technician to its collection and returns the stored copy
+func (d *MongoStruct) CreateTechnician(ctx context.Context, technician models.Technician) (*models.Technician, error) {
+	technician.ID = primitive.NewObjectID()
+	if _, err := d.technicians().InsertOne(ctx, technician); err != nil {
+		return nil, mongoError(err, "technician")
+	}
+	return &technician, nil
+}
+
+// GetTechnician - returns the technician with the given id
+func (d *MongoStruct) GetTechnician(ctx context.Context, technicianID string) (*models.Technician, error) {
+	objectID, err := parseID(technicianID)
+	if err != nil {
+		return nil, err
+	}
+	var technician models.Technician
+	if err := d.technicians().FindOne(ctx, bson.M{"_id": objectID}).Decode(&technician); err != nil {
+		return nil, mongoError(err, "technician "+technicianID)
+	}
+	return &technician, nil
+}
+
+// ListTechnicians - returns every technician matching filter ordered by name
+func (d *MongoStruct) ListTechnicians(ctx context.Context, filter models.TechnicianFilter) (*[]models.Technician, error) {
+	document := bson.M{}
+	if filter.Skill != "" {
+		document["skills"] = bson.M{"$regex": "^" + regexp.QuoteMeta(filter.Skill) + "$", "$options": "i"}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := d.technicians().Find(ctx, document, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "technicians")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.Technician{}
+	for cur.Next(ctx) {
+		var technician models.Technician
+		if err := cur.Decode(&technician); err != nil {
+			return nil, mongoError(err, "technicians")
+		}
+		results = append(results, technician)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "technicians")
+	}
+	return &results, nil
+}
+
+// UpdateTechnician - replaces the stored technician with the same ID and returns the result
+func (d *MongoStruct) UpdateTechnician(ctx context.Context, technician models.Technician) (*models.Technician, error) {
+	var result models.Technician
+	err := d.technicians().FindOneAndReplace(
+		ctx,
+		bson.M{"_id": technician.ID},
+		technician,
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+	).Decode(&result)
+	if err != nil {
+		return nil, mongoError(err, "technician "+technician.ID.Hex())
+	}
+	return &result, nil
+}
+
+// DeleteTechnician - deletes the technician with the given id
+func (d *MongoStruct) DeleteTechnician(ctx context.Context, technicianID string) error {
+	objectID, err := parseID(technicianID)
+	if err != nil {
+		return err
+	}
+	deleteResult, err := d.technicians().DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return mongoError(err, "technician "+technicianID)
+	}
+	if deleteResult.DeletedCount == 0 {
+		return fmt.Errorf("technician %v %w", technicianID, ErrNotFound)
+	}
+	return nil
+}
+
+// CreateTechnician - stores technician under a newly generated ID and returns the stored copy
+func (m *MemoryStore) CreateTechnician(ctx context.Context, technician models.Technician) (*models.Technician, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	technician.ID = primitive.NewObjectID()
+	m.technicians[technician.ID] = technician
+	return &technician, nil
+}
+
+// GetTechnician - returns a copy of the technician with the given id
+func (m *MemoryStore) GetTechnician(ctx context.Context, technicianID string) (*models.Technician, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(technicianID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	technician, ok := m.technicians[objectID]
+	if !ok {
+		return nil, fmt.Errorf("technician %v %w", technicianID, ErrNotFound)
+	}
+	return &technician, nil
+}
+
+// ListTechnicians - returns every technician matching filter ordered by name
+func (m *MemoryStore) ListTechnicians(ctx context.Context, filter models.TechnicianFilter) (*[]models.Technician, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.Technician{}
+	for _, technician := range m.technicians {
+		if matchesTechnicianFilter(technician, filter) {
+			results = append(results, technician)
+		}
+	}
+	sortTechnicians(results)
+	return &results, nil
+}
+
+// UpdateTechnician - replaces the stored technician with the same ID
+func (m *MemoryStore) UpdateTechnician(ctx context.Context, technician models.Technician) (*models.Technician, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.technicians[technician.ID]; !ok {
+		return nil, fmt.Errorf("technician %v %w", technician.ID.Hex(), ErrNotFound)
+	}
+	m.technicians[technician.ID] = technician
+	return &technician, nil
+}
+
+// DeleteTechnician - removes the technician with the given id
+func (m *MemoryStore) DeleteTechnician(ctx context.Context, technicianID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(technicianID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.technicians[objectID]; !ok {
+		return fmt.Errorf("technician %v %w", technicianID, ErrNotFound)
+	}
+	delete(m.technicians, objectID)
+	return nil
+}
+
+// CreateTechnician - stores technician under a newly generated ID and returns the stored copy
+func (b *BoltStore) CreateTechnician(ctx context.Context, technician models.Technician) (*models.Technician, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	technician.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		return putRecord(tx, techniciansBucket, technician.ID, technician)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &technician, nil
+}
+
+// GetTechnician - returns the technician with the given id
+func (b *BoltStore) GetTechnician(ctx context.Context, technicianID string) (*models.Technician, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(technicianID)
+	if err != nil {
+		return nil, err
+	}
+	var technician models.Technician
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, techniciansBucket, objectID, "technician", &technician)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &technician, nil
+}
+
+// ListTechnicians - scans every technician and returns those matching filter ordered by name
+func (b *BoltStore) ListTechnicians(ctx context.Context, filter models.TechnicianFilter) (*[]models.Technician, error) {
+	results := []models.Technician{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(techniciansBucket).ForEach(func(_, data []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var technician models.Technician
+			if err := bson.Unmarshal(data, &technician); err != nil {
+				return err
+			}
+			if matchesTechnicianFilter(technician, filter) {
+				results = append(results, technician)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	sortTechnicians(results)
+	return &results, nil
+}
+
+// UpdateTechnician - replaces the stored technician with the same ID
+func (b *BoltStore) UpdateTechnician(ctx context.Context, technician models.Technician) (*models.Technician, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		if err := getRecord(tx, techniciansBucket, technician.ID, "technician", &models.Technician{}); err != nil {
+			return err
+		}
+		return putRecord(tx, techniciansBucket, technician.ID, technician)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &technician, nil
+}
+
+// DeleteTechnician - removes the technician with the given id
+func (b *BoltStore) DeleteTechnician(ctx context.Context, technicianID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(technicianID)
+	if err != nil {
+		return err
+	}
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		if err := getRecord(tx, techniciansBucket, objectID, "technician", &models.Technician{}); err != nil {
+			return err
+		}
+		return tx.Bucket(techniciansBucket).Delete(objectID[:])
+	})
+	return boltError(err)
+}
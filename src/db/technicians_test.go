@@ -0,0 +1,90 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStoreTechnicians(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		expires := time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC)
+
+		sam, err := store.CreateTechnician(ctx, models.Technician{
+			Name:           "Sam Rivera",
+			Skills:         []string{"Brakes", "alignment"},
+			Certifications: []models.Certification{{Name: "ASE A5", Expires: &expires}},
+			WorkingHours:   map[string]models.Shift{"monday": {Start: "08:00", End: "16:00"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateTechnician(ctx, models.Technician{Name: "Alex Chen", Skills: []string{"electrical"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := store.ListTechnicians(ctx, models.TechnicianFilter{})
+		if err != nil || len(*all) != 2 || (*all)[0].Name != "Alex Chen" {
+			t.Errorf("got %+v, %v want technicians ordered by name", all, err)
+		}
+		brakes, err := store.ListTechnicians(ctx, models.TechnicianFilter{Skill: "brakes"})
+		if err != nil || len(*brakes) != 1 || (*brakes)[0].ID != sam.ID {
+			t.Errorf("got %+v, %v want only %v", brakes, err, sam.Name)
+		}
+
+		found, err := store.GetTechnician(ctx, sam.ID.Hex())
+		if err != nil || found.WorkingHours["monday"].End != "16:00" || !found.Certifications[0].Expires.Equal(expires) {
+			t.Errorf("got %+v, %v want %+v", found, err, sam)
+		}
+		sam.Skills = append(sam.Skills, "suspension")
+		if _, err := store.UpdateTechnician(ctx, *sam); err != nil {
+			t.Fatal(err)
+		}
+		if found, _ := store.GetTechnician(ctx, sam.ID.Hex()); len(found.Skills) != 3 {
+			t.Errorf("got skills %v want 3", found.Skills)
+		}
+
+		appointment, err := store.CreateAppointment(ctx, models.Appointment{Name: "Brakes", Description: "squeal", Status: models.StatusOpen, Date: expires})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := store.AssignAppointmentTechnician(ctx, appointment.ID.Hex(), sam.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if stored, _ := store.GetAppointment(ctx, appointment.ID.Hex()); len(stored.TechnicianIDs) != 1 || stored.TechnicianIDs[0] != sam.ID {
+			t.Errorf("got technicians %v want %v once", stored.TechnicianIDs, sam.ID)
+		}
+		// an edit made from a copy read before the technician was assigned keeps the technician
+		appointment.Description = "squeal when braking"
+		if _, err := store.UpdateAppointment(ctx, *appointment); err != nil {
+			t.Fatal(err)
+		}
+		if stored, _ := store.GetAppointment(ctx, appointment.ID.Hex()); len(stored.TechnicianIDs) != 1 || stored.Description != "squeal when braking" {
+			t.Errorf("got %+v want the edit saved and the technician kept", stored)
+		}
+		before, err := store.UnassignAppointmentTechnician(ctx, appointment.ID.Hex(), sam.ID)
+		if err != nil || len(before.TechnicianIDs) != 1 {
+			t.Fatalf("got %+v, %v want the appointment as it was before", before, err)
+		}
+		if stored, _ := store.GetAppointment(ctx, appointment.ID.Hex()); len(stored.TechnicianIDs) != 0 {
+			t.Errorf("got technicians %v want none", stored.TechnicianIDs)
+		}
+		if _, err := store.AssignAppointmentTechnician(ctx, primitive.NewObjectID().Hex(), sam.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want %v for an unknown appointment", err, ErrNotFound)
+		}
+
+		if err := store.DeleteTechnician(ctx, sam.ID.Hex()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetTechnician(ctx, sam.ID.Hex()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want %v", err, ErrNotFound)
+		}
+	})
+}
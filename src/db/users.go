@@ -0,0 +1,378 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserStore interface - staff account storage, following the same error and context conventions as ClientInterface.
+// Usernames are unique; storing a second user with the same username returns ErrConflict.
+type UserStore interface {
+	CreateUser(context.Context, models.User) (*models.User, error)
+	GetUser(context.Context, string) (*models.User, error)
+	GetUserByUsername(context.Context, string) (*models.User, error)
+	ListUsers(context.Context) (*[]models.User, error)
+	UpdateUser(context.Context, models.User) (*models.User, error)
+	DeleteUser(context.Context, string) error
+}
+
+// userIndexes - indexes backing username uniqueness and lookups
+var userIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+}
+
+// duplicateUsername - the error returned when user's username is already taken by another user
+func duplicateUsername(user models.User) error {
+	return fmt.Errorf("username %v is already taken: %w", user.Username, ErrConflict)
+}
+
+// sortUsers - orders users by username
+func sortUsers(users []models.User) {
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+}
+
+// CreateUser - writes user to its collection and returns the stored copy
+func (d *MongoStruct) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	user.ID = primitive.NewObjectID()
+	if _, err := d.users().InsertOne(ctx, user); err != nil {
+		if err = mongoError(err, "user"); errors.Is(err, ErrConflict) {
+			return nil, duplicateUsername(user)
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser - returns the user with the given id
+func (d *MongoStruct) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	objectID, err := parseID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var user models.User
+	if err := d.users().FindOne(ctx, bson.M{"_id": objectID}).Decode(&user); err != nil {
+		return nil, mongoError(err, "user "+userID)
+	}
+	return &user, nil
+}
+
+// GetUserByUsername - returns the user with the given username
+func (d *MongoStruct) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := d.users().FindOne(ctx, bson.M{"username": username}).Decode(&user); err != nil {
+		return nil, mongoError(err, "user "+username)
+	}
+	return &user, nil
+}
+
+// ListUsers - returns every user ordered by username
+func (d *MongoStruct) ListUsers(ctx context.Context) (*[]models.User, error) {
+	cur, err := d.users().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "username", Value: 1}}))
+	if err != nil {
+		return nil, mongoError(err, "users")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.User{}
+	for cur.Next(ctx) {
+		var user models.User
+		if err := cur.Decode(&user); err != nil {
+			return nil, mongoError(err, "users")
+		}
+		results = append(results, user)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "users")
+	}
+	return &results, nil
+}
+
+// UpdateUser - replaces the stored user with the same ID and returns the result
+func (d *MongoStruct) UpdateUser(ctx context.Context, user models.User) (*models.User, error) {
+	var result models.User
+	err := d.users().FindOneAndReplace(
+		ctx,
+		bson.M{"_id": user.ID},
+		user,
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+	).Decode(&result)
+	if err != nil {
+		if err = mongoError(err, "user "+user.ID.Hex()); errors.Is(err, ErrConflict) {
+			return nil, duplicateUsername(user)
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteUser - deletes the user with the given id
+func (d *MongoStruct) DeleteUser(ctx context.Context, userID string) error {
+	objectID, err := parseID(userID)
+	if err != nil {
+		return err
+	}
+	deleteResult, err := d.users().DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return mongoError(err, "user "+userID)
+	}
+	if deleteResult.DeletedCount == 0 {
+		return fmt.Errorf("user %v %w", userID, ErrNotFound)
+	}
+	return nil
+}
+
+// usernameTaken - reports whether a user other than user already has its username. Callers must hold m.mu.
+func (m *MemoryStore) usernameTaken(user models.User) bool {
+	for id, other := range m.users {
+		if id != user.ID && other.Username == user.Username {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateUser - stores user under a newly generated ID and returns the stored copy
+func (m *MemoryStore) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user.ID = primitive.NewObjectID()
+	if m.usernameTaken(user) {
+		return nil, duplicateUsername(user)
+	}
+	m.users[user.ID] = user
+	return &user, nil
+}
+
+// GetUser - returns a copy of the user with the given id
+func (m *MemoryStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(userID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[objectID]
+	if !ok {
+		return nil, fmt.Errorf("user %v %w", userID, ErrNotFound)
+	}
+	return &user, nil
+}
+
+// GetUserByUsername - returns a copy of the user with the given username
+func (m *MemoryStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("user %v %w", username, ErrNotFound)
+}
+
+// ListUsers - returns every user ordered by username
+func (m *MemoryStore) ListUsers(ctx context.Context) (*[]models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.User{}
+	for _, user := range m.users {
+		results = append(results, user)
+	}
+	sortUsers(results)
+	return &results, nil
+}
+
+// UpdateUser - replaces the stored user with the same ID
+func (m *MemoryStore) UpdateUser(ctx context.Context, user models.User) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.ID]; !ok {
+		return nil, fmt.Errorf("user %v %w", user.ID.Hex(), ErrNotFound)
+	}
+	if m.usernameTaken(user) {
+		return nil, duplicateUsername(user)
+	}
+	m.users[user.ID] = user
+	return &user, nil
+}
+
+// DeleteUser - removes the user with the given id
+func (m *MemoryStore) DeleteUser(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(userID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[objectID]; !ok {
+		return fmt.Errorf("user %v %w", userID, ErrNotFound)
+	}
+	delete(m.users, objectID)
+	return nil
+}
+
+// putUser - writes user and its username index entry, replacing the entry for previous if given
+func putUser(tx *bolt.Tx, user models.User, previous *models.User) error {
+	previousUsername := ""
+	if previous != nil {
+		previousUsername = previous.Username
+	}
+	if err := putUniqueKey(tx, usersByUsernameBucket, user.Username, previousUsername, user.ID, duplicateUsername(user)); err != nil {
+		return err
+	}
+	return putRecord(tx, usersBucket, user.ID, user)
+}
+
+// CreateUser - stores user under a newly generated ID and returns the stored copy
+func (b *BoltStore) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	user.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		return putUser(tx, user, nil)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &user, nil
+}
+
+// GetUser - returns the user with the given id
+func (b *BoltStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var user models.User
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, usersBucket, objectID, "user", &user)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &user, nil
+}
+
+// GetUserByUsername - looks the username up in the username index and returns its user
+func (b *BoltStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var user models.User
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(usersByUsernameBucket).Get([]byte(username))
+		if value == nil {
+			return fmt.Errorf("user %v %w", username, ErrNotFound)
+		}
+		var id primitive.ObjectID
+		copy(id[:], value)
+		return getRecord(tx, usersBucket, id, "user", &user)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &user, nil
+}
+
+// ListUsers - walks the username index and returns every user ordered by username
+func (b *BoltStore) ListUsers(ctx context.Context) (*[]models.User, error) {
+	results := []models.User{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersByUsernameBucket).ForEach(func(_, value []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var id primitive.ObjectID
+			copy(id[:], value)
+			var user models.User
+			if err := getRecord(tx, usersBucket, id, "user", &user); err != nil {
+				return err
+			}
+			results = append(results, user)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &results, nil
+}
+
+// UpdateUser - replaces the stored user with the same ID
+func (b *BoltStore) UpdateUser(ctx context.Context, user models.User) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		var previous models.User
+		if err := getRecord(tx, usersBucket, user.ID, "user", &previous); err != nil {
+			return err
+		}
+		return putUser(tx, user, &previous)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &user, nil
+}
+
+// DeleteUser - removes the user with the given id and its username index entry
+func (b *BoltStore) DeleteUser(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(userID)
+	if err != nil {
+		return err
+	}
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		var user models.User
+		if err := getRecord(tx, usersBucket, objectID, "user", &user); err != nil {
+			return err
+		}
+		if err := tx.Bucket(usersByUsernameBucket).Delete([]byte(user.Username)); err != nil {
+			return err
+		}
+		return tx.Bucket(usersBucket).Delete(objectID[:])
+	})
+	return boltError(err)
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStoreUsers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+
+		user, err := store.CreateUser(ctx, models.User{Username: "advisor", Name: "Sam Advisor", PasswordHash: "hash"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateUser(ctx, models.User{Username: "advisor", Name: "Other"}); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict for a taken username", err)
+		}
+		if _, err := store.CreateUser(ctx, models.User{Username: "admin", Name: "Alex Admin"}); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := store.GetUserByUsername(ctx, "advisor")
+		if err != nil || found.ID != user.ID || found.PasswordHash != "hash" {
+			t.Fatalf("got %+v, %v want %+v", found, err, user)
+		}
+		users, err := store.ListUsers(ctx)
+		if err != nil || len(*users) != 2 || (*users)[0].Username != "admin" {
+			t.Fatalf("got %+v, %v want both users ordered by username", users, err)
+		}
+
+		found.Username = "admin"
+		if _, err := store.UpdateUser(ctx, *found); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict renaming to a taken username", err)
+		}
+		found.Username, found.TokenVersion = "service", 1
+		if _, err := store.UpdateUser(ctx, *found); err != nil {
+			t.Fatal(err)
+		}
+		if renamed, err := store.GetUser(ctx, user.ID.Hex()); err != nil || renamed.TokenVersion != 1 {
+			t.Errorf("got %+v, %v want the token version kept", renamed, err)
+		}
+		if _, err := store.GetUserByUsername(ctx, "advisor"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want the old username released", err)
+		}
+		if _, err := store.CreateUser(ctx, models.User{Username: "advisor", Name: "New Advisor"}); err != nil {
+			t.Errorf("got %v want the old username reusable", err)
+		}
+
+		if err := store.DeleteUser(ctx, user.ID.Hex()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetUser(ctx, user.ID.Hex()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound after delete", err)
+		}
+	})
+}
@@ -0,0 +1,355 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VehicleStore interface - vehicle storage, following the same error and context conventions as ClientInterface.
+// VINs are unique; storing a second vehicle with the same VIN returns ErrConflict.
+type VehicleStore interface {
+	CreateVehicle(context.Context, models.Vehicle) (*models.Vehicle, error)
+	GetVehicle(context.Context, string) (*models.Vehicle, error)
+	ListVehicles(context.Context, models.VehicleFilter) (*[]models.Vehicle, error)
+	UpdateVehicle(context.Context, models.Vehicle) (*models.Vehicle, error)
+	DeleteVehicle(context.Context, string) error
+}
+
+// vehicleIndexes - indexes backing ListVehicles and VIN uniqueness
+var vehicleIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "vin", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "customer_id", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "plate", Value: 1}}},
+}
+
+// duplicateVIN - the error returned when vehicle's VIN is already taken by another vehicle
+func duplicateVIN(vehicle models.Vehicle) error {
+	return fmt.Errorf("a vehicle with VIN %v already exists: %w", vehicle.VIN, ErrConflict)
+}
+
+// matchesVehicleFilter - reports whether vehicle satisfies every field set in filter
+func matchesVehicleFilter(vehicle models.Vehicle, filter models.VehicleFilter) bool {
+	return (filter.CustomerID == nil || vehicle.CustomerID == *filter.CustomerID) &&
+		(filter.VIN == "" || vehicle.VIN == filter.VIN) &&
+		(filter.Plate == "" || vehicle.Plate == filter.Plate)
+}
+
+// sortVehicles - orders vehicles by ID, which is the order they were added in
+func sortVehicles(vehicles []models.Vehicle) {
+	sort.Slice(vehicles, func(i, j int) bool {
+		return bytes.Compare(vehicles[i].ID[:], vehicles[j].ID[:]) < 0
+	})
+}
+
+// CreateVehicle - writes vehicle to its collection and returns the stored copy
+func (d *MongoStruct) CreateVehicle(ctx context.Context, vehicle models.Vehicle) (*models.Vehicle, error) {
+	vehicle.ID = primitive.NewObjectID()
+	if _, err := d.vehicles().InsertOne(ctx, vehicle); err != nil {
+		if err = mongoError(err, "vehicle"); errors.Is(err, ErrConflict) {
+			return nil, duplicateVIN(vehicle)
+		}
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// GetVehicle - returns the vehicle with the given id
+func (d *MongoStruct) GetVehicle(ctx context.Context, vehicleID string) (*models.Vehicle, error) {
+	objectID, err := parseID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	var vehicle models.Vehicle
+	if err := d.vehicles().FindOne(ctx, bson.M{"_id": objectID}).Decode(&vehicle); err != nil {
+		return nil, mongoError(err, "vehicle "+vehicleID)
+	}
+	return &vehicle, nil
+}
+
+// ListVehicles - returns every vehicle matching filter in the order they were added
+func (d *MongoStruct) ListVehicles(ctx context.Context, filter models.VehicleFilter) (*[]models.Vehicle, error) {
+	document := bson.M{}
+	if filter.CustomerID != nil {
+		document["customer_id"] = *filter.CustomerID
+	}
+	if filter.VIN != "" {
+		document["vin"] = filter.VIN
+	}
+	if filter.Plate != "" {
+		document["plate"] = filter.Plate
+	}
+
+	cur, err := d.vehicles().Find(ctx, document, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, mongoError(err, "vehicles")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.Vehicle{}
+	for cur.Next(ctx) {
+		var vehicle models.Vehicle
+		if err := cur.Decode(&vehicle); err != nil {
+			return nil, mongoError(err, "vehicles")
+		}
+		results = append(results, vehicle)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "vehicles")
+	}
+	return &results, nil
+}
+
+// UpdateVehicle - replaces the stored vehicle with the same ID and returns the result
+func (d *MongoStruct) UpdateVehicle(ctx context.Context, vehicle models.Vehicle) (*models.Vehicle, error) {
+	var result models.Vehicle
+	err := d.vehicles().FindOneAndReplace(
+		ctx,
+		bson.M{"_id": vehicle.ID},
+		vehicle,
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+	).Decode(&result)
+	if err != nil {
+		if err = mongoError(err, "vehicle "+vehicle.ID.Hex()); errors.Is(err, ErrConflict) {
+			return nil, duplicateVIN(vehicle)
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteVehicle - deletes the vehicle with the given id
+func (d *MongoStruct) DeleteVehicle(ctx context.Context, vehicleID string) error {
+	objectID, err := parseID(vehicleID)
+	if err != nil {
+		return err
+	}
+	deleteResult, err := d.vehicles().DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return mongoError(err, "vehicle "+vehicleID)
+	}
+	if deleteResult.DeletedCount == 0 {
+		return fmt.Errorf("vehicle %v %w", vehicleID, ErrNotFound)
+	}
+	return nil
+}
+
+// vinTaken - reports whether a vehicle other than vehicle already has its VIN. Callers must hold m.mu.
+func (m *MemoryStore) vinTaken(vehicle models.Vehicle) bool {
+	for id, other := range m.vehicles {
+		if id != vehicle.ID && other.VIN == vehicle.VIN {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateVehicle - stores vehicle under a newly generated ID and returns the stored copy
+func (m *MemoryStore) CreateVehicle(ctx context.Context, vehicle models.Vehicle) (*models.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vehicle.ID = primitive.NewObjectID()
+	if m.vinTaken(vehicle) {
+		return nil, duplicateVIN(vehicle)
+	}
+	m.vehicles[vehicle.ID] = vehicle
+	return &vehicle, nil
+}
+
+// GetVehicle - returns a copy of the vehicle with the given id
+func (m *MemoryStore) GetVehicle(ctx context.Context, vehicleID string) (*models.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	vehicle, ok := m.vehicles[objectID]
+	if !ok {
+		return nil, fmt.Errorf("vehicle %v %w", vehicleID, ErrNotFound)
+	}
+	return &vehicle, nil
+}
+
+// ListVehicles - returns every vehicle matching filter in the order they were added
+func (m *MemoryStore) ListVehicles(ctx context.Context, filter models.VehicleFilter) (*[]models.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.Vehicle{}
+	for _, vehicle := range m.vehicles {
+		if matchesVehicleFilter(vehicle, filter) {
+			results = append(results, vehicle)
+		}
+	}
+	sortVehicles(results)
+	return &results, nil
+}
+
+// UpdateVehicle - replaces the stored vehicle with the same ID
+func (m *MemoryStore) UpdateVehicle(ctx context.Context, vehicle models.Vehicle) (*models.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.vehicles[vehicle.ID]; !ok {
+		return nil, fmt.Errorf("vehicle %v %w", vehicle.ID.Hex(), ErrNotFound)
+	}
+	if m.vinTaken(vehicle) {
+		return nil, duplicateVIN(vehicle)
+	}
+	m.vehicles[vehicle.ID] = vehicle
+	return &vehicle, nil
+}
+
+// DeleteVehicle - removes the vehicle with the given id
+func (m *MemoryStore) DeleteVehicle(ctx context.Context, vehicleID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(vehicleID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.vehicles[objectID]; !ok {
+		return fmt.Errorf("vehicle %v %w", vehicleID, ErrNotFound)
+	}
+	delete(m.vehicles, objectID)
+	return nil
+}
+
+// putVehicle - writes vehicle and its VIN index entry, replacing the entry for previous if given
+func putVehicle(tx *bolt.Tx, vehicle models.Vehicle, previous *models.Vehicle) error {
+	previousVIN := ""
+	if previous != nil {
+		previousVIN = previous.VIN
+	}
+	if err := putUniqueKey(tx, vehiclesByVINBucket, vehicle.VIN, previousVIN, vehicle.ID, duplicateVIN(vehicle)); err != nil {
+		return err
+	}
+	return putRecord(tx, vehiclesBucket, vehicle.ID, vehicle)
+}
+
+// CreateVehicle - stores vehicle under a newly generated ID and returns the stored copy
+func (b *BoltStore) CreateVehicle(ctx context.Context, vehicle models.Vehicle) (*models.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vehicle.ID = primitive.NewObjectID()
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		return putVehicle(tx, vehicle, nil)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &vehicle, nil
+}
+
+// GetVehicle - returns the vehicle with the given id
+func (b *BoltStore) GetVehicle(ctx context.Context, vehicleID string) (*models.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	var vehicle models.Vehicle
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, vehiclesBucket, objectID, "vehicle", &vehicle)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &vehicle, nil
+}
+
+// ListVehicles - scans every vehicle and returns those matching filter in the order they were added
+func (b *BoltStore) ListVehicles(ctx context.Context, filter models.VehicleFilter) (*[]models.Vehicle, error) {
+	results := []models.Vehicle{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		// keys are object ids, so the bucket is already in the order vehicles were added
+		return tx.Bucket(vehiclesBucket).ForEach(func(_, data []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var vehicle models.Vehicle
+			if err := bson.Unmarshal(data, &vehicle); err != nil {
+				return err
+			}
+			if matchesVehicleFilter(vehicle, filter) {
+				results = append(results, vehicle)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &results, nil
+}
+
+// UpdateVehicle - replaces the stored vehicle with the same ID
+func (b *BoltStore) UpdateVehicle(ctx context.Context, vehicle models.Vehicle) (*models.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		var previous models.Vehicle
+		if err := getRecord(tx, vehiclesBucket, vehicle.ID, "vehicle", &previous); err != nil {
+			return err
+		}
+		return putVehicle(tx, vehicle, &previous)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &vehicle, nil
+}
+
+// DeleteVehicle - removes the vehicle with the given id and its VIN index entry
+func (b *BoltStore) DeleteVehicle(ctx context.Context, vehicleID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectID, err := parseID(vehicleID)
+	if err != nil {
+		return err
+	}
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		var vehicle models.Vehicle
+		if err := getRecord(tx, vehiclesBucket, objectID, "vehicle", &vehicle); err != nil {
+			return err
+		}
+		if err := tx.Bucket(vehiclesByVINBucket).Delete([]byte(vehicle.VIN)); err != nil {
+			return err
+		}
+		return tx.Bucket(vehiclesBucket).Delete(objectID[:])
+	})
+	return boltError(err)
+}
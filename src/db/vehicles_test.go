@@ -0,0 +1,78 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStoreVehicles(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		owner := primitive.NewObjectID()
+
+		first, err := store.CreateVehicle(ctx, models.Vehicle{CustomerID: owner, VIN: "1HGCM82633A004352", Make: "Honda", Model: "Accord", Year: 2003, Plate: "ABC123"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := store.CreateVehicle(ctx, models.Vehicle{CustomerID: primitive.NewObjectID(), VIN: "JH4KA7561PC008269", Make: "Acura", Model: "Legend", Year: 1993})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateVehicle(ctx, models.Vehicle{CustomerID: owner, VIN: first.VIN}); !errors.Is(err, ErrConflict) {
+			t.Errorf("duplicate VIN: got %v want %v", err, ErrConflict)
+		}
+
+		filters := map[string]struct {
+			filter   models.VehicleFilter
+			expected int
+		}{
+			"everything": {models.VehicleFilter{}, 2},
+			"owner":      {models.VehicleFilter{CustomerID: &owner}, 1},
+			"vin":        {models.VehicleFilter{VIN: second.VIN}, 1},
+			"plate":      {models.VehicleFilter{Plate: "ABC123"}, 1},
+			"no match":   {models.VehicleFilter{Plate: "XYZ"}, 0},
+		}
+		for name, test := range filters {
+			vehicles, err := store.ListVehicles(ctx, test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*vehicles) != test.expected {
+				t.Errorf("%v: got %v want %v vehicles", name, len(*vehicles), test.expected)
+			}
+		}
+
+		second.VIN = first.VIN
+		if _, err := store.UpdateVehicle(ctx, *second); !errors.Is(err, ErrConflict) {
+			t.Errorf("update to taken VIN: got %v want %v", err, ErrConflict)
+		}
+		first.VIN = "1HGCM82633A004353"
+		first.Mileage = 120000
+		if _, err := store.UpdateVehicle(ctx, *first); err != nil {
+			t.Fatal(err)
+		}
+		found, err := store.GetVehicle(ctx, first.ID.Hex())
+		if err != nil || found.VIN != first.VIN || found.Mileage != 120000 {
+			t.Errorf("got %+v, %v want %+v", found, err, first)
+		}
+		// the old VIN is free again once the vehicle holding it changed
+		second.VIN = "1HGCM82633A004352"
+		if _, err := store.UpdateVehicle(ctx, *second); err != nil {
+			t.Errorf("update to released VIN: %v", err)
+		}
+
+		if err := store.DeleteVehicle(ctx, first.ID.Hex()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetVehicle(ctx, first.ID.Hex()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want %v", err, ErrNotFound)
+		}
+		if _, err := store.CreateVehicle(ctx, models.Vehicle{CustomerID: owner, VIN: first.VIN}); err != nil {
+			t.Errorf("VIN of a deleted vehicle should be reusable: %v", err)
+		}
+	})
+}
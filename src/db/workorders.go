@@ -0,0 +1,319 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WorkOrderStore interface - work order storage, following the same error and context conventions as ClientInterface.
+// An appointment has at most one work order; creating a second returns ErrConflict. Updates only succeed while the
+// stored Version still matches the given one, and return ErrStale otherwise.
+type WorkOrderStore interface {
+	CreateWorkOrder(context.Context, models.WorkOrder) (*models.WorkOrder, error)
+	GetWorkOrder(context.Context, string) (*models.WorkOrder, error)
+	ListWorkOrders(context.Context, models.WorkOrderFilter) (*[]models.WorkOrder, error)
+	UpdateWorkOrder(context.Context, models.WorkOrder) (*models.WorkOrder, error)
+}
+
+// workOrderIndexes - indexes backing appointment uniqueness and ListWorkOrders
+var workOrderIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "appointment_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+}
+
+// duplicateWorkOrder - the error returned when order's appointment already has a work order
+func duplicateWorkOrder(order models.WorkOrder) error {
+	return fmt.Errorf("appointment %v already has a work order: %w", order.AppointmentID.Hex(), ErrConflict)
+}
+
+// staleWorkOrder - the error returned when order was changed since it was read
+func staleWorkOrder(order models.WorkOrder) error {
+	return fmt.Errorf("work order %v %w", order.ID.Hex(), ErrStale)
+}
+
+// matchesWorkOrderFilter - reports whether order satisfies every field set in filter
+func matchesWorkOrderFilter(order models.WorkOrder, filter models.WorkOrderFilter) bool {
+	switch {
+	case filter.AppointmentID != nil && order.AppointmentID != *filter.AppointmentID:
+		return false
+	case filter.Status != "" && order.Status != filter.Status:
+		return false
+	}
+	return true
+}
+
+// sortWorkOrders - orders work orders by creation time, then by ID
+func sortWorkOrders(orders []models.WorkOrder) {
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].ID.Hex() < orders[j].ID.Hex()
+		}
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+}
+
+// CreateWorkOrder - writes order to its collection as version 1 and returns the stored copy
+func (d *MongoStruct) CreateWorkOrder(ctx context.Context, order models.WorkOrder) (*models.WorkOrder, error) {
+	order.ID = primitive.NewObjectID()
+	order.Version = 1
+	if _, err := d.workOrders().InsertOne(ctx, order); err != nil {
+		if err = mongoError(err, "work order"); errors.Is(err, ErrConflict) {
+			return nil, duplicateWorkOrder(order)
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetWorkOrder - returns the work order with the given id
+func (d *MongoStruct) GetWorkOrder(ctx context.Context, orderID string) (*models.WorkOrder, error) {
+	objectID, err := parseID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	var order models.WorkOrder
+	if err := d.workOrders().FindOne(ctx, bson.M{"_id": objectID}).Decode(&order); err != nil {
+		return nil, mongoError(err, "work order "+orderID)
+	}
+	return &order, nil
+}
+
+// ListWorkOrders - returns every work order matching filter, oldest first
+func (d *MongoStruct) ListWorkOrders(ctx context.Context, filter models.WorkOrderFilter) (*[]models.WorkOrder, error) {
+	document := bson.M{}
+	if filter.AppointmentID != nil {
+		document["appointment_id"] = *filter.AppointmentID
+	}
+	if filter.Status != "" {
+		document["status"] = filter.Status
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := d.workOrders().Find(ctx, document, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "work orders")
+	}
+	defer cur.Close(context.Background())
+
+	results := []models.WorkOrder{}
+	for cur.Next(ctx) {
+		var order models.WorkOrder
+		if err := cur.Decode(&order); err != nil {
+			return nil, mongoError(err, "work orders")
+		}
+		results = append(results, order)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, mongoError(err, "work orders")
+	}
+	return &results, nil
+}
+
+// UpdateWorkOrder - replaces the stored work order if it is still at order's version, and returns the result with the next version
+func (d *MongoStruct) UpdateWorkOrder(ctx context.Context, order models.WorkOrder) (*models.WorkOrder, error) {
+	version := order.Version
+	order.Version++
+	var result models.WorkOrder
+	err := d.workOrders().FindOneAndReplace(
+		ctx,
+		bson.M{"_id": order.ID, "version": version},
+		order,
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+	).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// tell a missing work order apart from one that moved on to another version
+		if _, err := d.GetWorkOrder(ctx, order.ID.Hex()); err != nil {
+			return nil, err
+		}
+		return nil, staleWorkOrder(order)
+	}
+	if err != nil {
+		return nil, mongoError(err, "work order "+order.ID.Hex())
+	}
+	return &result, nil
+}
+
+// copyWorkOrder - order with a history, lines and time entries of its own, so the memory store and its callers never share them
+func copyWorkOrder(order models.WorkOrder) models.WorkOrder {
+	if order.StatusHistory != nil {
+		order.StatusHistory = append(make([]models.StatusChange, 0, len(order.StatusHistory)), order.StatusHistory...)
+	}
+	if order.Lines != nil {
+		order.Lines = append(make([]models.WorkOrderLine, 0, len(order.Lines)), order.Lines...)
+	}
+	if order.TimeEntries != nil {
+		order.TimeEntries = append(make([]models.TimeEntry, 0, len(order.TimeEntries)), order.TimeEntries...)
+	}
+	return order
+}
+
+// CreateWorkOrder - stores order under a newly generated ID as version 1 and returns the stored copy
+func (m *MemoryStore) CreateWorkOrder(ctx context.Context, order models.WorkOrder) (*models.WorkOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, other := range m.workOrders {
+		if other.AppointmentID == order.AppointmentID {
+			return nil, duplicateWorkOrder(order)
+		}
+	}
+	order.ID = primitive.NewObjectID()
+	order.Version = 1
+	m.workOrders[order.ID] = copyWorkOrder(order)
+	return &order, nil
+}
+
+// GetWorkOrder - returns a copy of the work order with the given id
+func (m *MemoryStore) GetWorkOrder(ctx context.Context, orderID string) (*models.WorkOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	order, ok := m.workOrders[objectID]
+	if !ok {
+		return nil, fmt.Errorf("work order %v %w", orderID, ErrNotFound)
+	}
+	order = copyWorkOrder(order)
+	return &order, nil
+}
+
+// ListWorkOrders - returns every work order matching filter, oldest first
+func (m *MemoryStore) ListWorkOrders(ctx context.Context, filter models.WorkOrderFilter) (*[]models.WorkOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []models.WorkOrder{}
+	for _, order := range m.workOrders {
+		if matchesWorkOrderFilter(order, filter) {
+			results = append(results, copyWorkOrder(order))
+		}
+	}
+	sortWorkOrders(results)
+	return &results, nil
+}
+
+// UpdateWorkOrder - replaces the stored work order if it is still at order's version
+func (m *MemoryStore) UpdateWorkOrder(ctx context.Context, order models.WorkOrder) (*models.WorkOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.workOrders[order.ID]
+	if !ok {
+		return nil, fmt.Errorf("work order %v %w", order.ID.Hex(), ErrNotFound)
+	}
+	if stored.Version != order.Version {
+		return nil, staleWorkOrder(order)
+	}
+	order.Version++
+	m.workOrders[order.ID] = copyWorkOrder(order)
+	return &order, nil
+}
+
+// CreateWorkOrder - stores order under a newly generated ID as version 1 and returns the stored copy
+func (b *BoltStore) CreateWorkOrder(ctx context.Context, order models.WorkOrder) (*models.WorkOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	order.ID = primitive.NewObjectID()
+	order.Version = 1
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		if err := putUniqueKey(tx, workOrdersByAppointmentBucket, order.AppointmentID.Hex(), "", order.ID, duplicateWorkOrder(order)); err != nil {
+			return err
+		}
+		return putRecord(tx, workOrdersBucket, order.ID, order)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &order, nil
+}
+
+// GetWorkOrder - returns the work order with the given id
+func (b *BoltStore) GetWorkOrder(ctx context.Context, orderID string) (*models.WorkOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectID, err := parseID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	var order models.WorkOrder
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		return getRecord(tx, workOrdersBucket, objectID, "work order", &order)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &order, nil
+}
+
+// ListWorkOrders - scans every work order and returns those matching filter, oldest first
+func (b *BoltStore) ListWorkOrders(ctx context.Context, filter models.WorkOrderFilter) (*[]models.WorkOrder, error) {
+	results := []models.WorkOrder{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(workOrdersBucket).ForEach(func(_, data []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var order models.WorkOrder
+			if err := bson.Unmarshal(data, &order); err != nil {
+				return err
+			}
+			if matchesWorkOrderFilter(order, filter) {
+				results = append(results, order)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	sortWorkOrders(results)
+	return &results, nil
+}
+
+// UpdateWorkOrder - replaces the stored work order if it is still at order's version
+func (b *BoltStore) UpdateWorkOrder(ctx context.Context, order models.WorkOrder) (*models.WorkOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		var stored models.WorkOrder
+		if err := getRecord(tx, workOrdersBucket, order.ID, "work order", &stored); err != nil {
+			return err
+		}
+		if stored.Version != order.Version {
+			return staleWorkOrder(order)
+		}
+		order.Version++
+		return putRecord(tx, workOrdersBucket, order.ID, order)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+	return &order, nil
+}
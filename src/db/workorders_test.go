@@ -0,0 +1,65 @@
+package db
+
+import (
+	"CarServiceCenter/src/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStoreWorkOrders(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Database) {
+		ctx := context.Background()
+		now := time.Date(2019, 8, 26, 9, 0, 0, 0, time.UTC)
+		appointmentID := primitive.NewObjectID()
+
+		order, err := store.CreateWorkOrder(ctx, models.WorkOrder{
+			AppointmentID: appointmentID,
+			Status:        models.StatusCheckedIn,
+			Lines:         []models.WorkOrderLine{{ID: primitive.NewObjectID(), Type: models.LineLabor, Description: "Brake job", Minutes: 90, Rate: 9500}},
+			CreatedAt:     now,
+		})
+		if err != nil || order.Version != 1 {
+			t.Fatalf("got %+v, %v want version 1", order, err)
+		}
+		if _, err := store.CreateWorkOrder(ctx, models.WorkOrder{AppointmentID: appointmentID}); !errors.Is(err, ErrConflict) {
+			t.Errorf("got %v want ErrConflict for a second work order on one appointment", err)
+		}
+		if _, err := store.CreateWorkOrder(ctx, models.WorkOrder{AppointmentID: primitive.NewObjectID(), Status: models.StatusInProgress, CreatedAt: now.Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := store.ListWorkOrders(ctx, models.WorkOrderFilter{})
+		if err != nil || len(*all) != 2 || (*all)[0].ID != order.ID {
+			t.Errorf("got %+v, %v want work orders oldest first", all, err)
+		}
+		byAppointment, err := store.ListWorkOrders(ctx, models.WorkOrderFilter{AppointmentID: &appointmentID})
+		if err != nil || len(*byAppointment) != 1 || (*byAppointment)[0].ID != order.ID {
+			t.Errorf("got %+v, %v want only %v", byAppointment, err, order.ID)
+		}
+		inProgress, err := store.ListWorkOrders(ctx, models.WorkOrderFilter{Status: models.StatusInProgress})
+		if err != nil || len(*inProgress) != 1 || (*inProgress)[0].ID == order.ID {
+			t.Errorf("got %+v, %v want only the in progress work order", inProgress, err)
+		}
+
+		found, err := store.GetWorkOrder(ctx, order.ID.Hex())
+		if err != nil || found.Total("") != 14250 {
+			t.Errorf("got %+v, %v want total 142.50", found, err)
+		}
+		found.Status = models.StatusInProgress
+		updated, err := store.UpdateWorkOrder(ctx, *found)
+		if err != nil || updated.Version != 2 {
+			t.Fatalf("got %+v, %v want version 2", updated, err)
+		}
+		if _, err := store.UpdateWorkOrder(ctx, *found); !errors.Is(err, ErrStale) {
+			t.Errorf("got %v want ErrStale when updating an old version", err)
+		}
+		missing := models.WorkOrder{ID: primitive.NewObjectID(), Version: 1}
+		if _, err := store.UpdateWorkOrder(ctx, missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ScopeResources - the resources API keys can be given access to; each is granted as "<resource>:read" or
+// "<resource>:write". Users and API keys themselves can only be managed by signed in admins.
+func ScopeResources() []string {
+	return []string{"appointments", "catalog", "customers", "estimates", "invoices", "parts", "technicians", "vehicles", "workorders"}
+}
+
+// Scope access levels; write doesn't include read, so a key that needs both is given both
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// ValidScope - reports whether scope names a resource API keys can access and either read or write
+func ValidScope(scope string) bool {
+	resource, access, found := strings.Cut(scope, ":")
+	if !found || (access != ScopeRead && access != ScopeWrite) {
+		return false
+	}
+	for _, valid := range ScopeResources() {
+		if resource == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey - a key machine integrations such as a kiosk or supplier portal call the API with instead of signing in.
+// Only the SHA-256 hash of the key is kept; the key itself is returned once, when it is created.
+type APIKey struct {
+	ID   primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name string             `json:"name" bson:"name"`
+	// Prefix - the start of the key, so it can be recognised in a list without being stored
+	Prefix  string   `json:"prefix" bson:"prefix"`
+	KeyHash string   `json:"-" bson:"key_hash"`
+	Scopes  []string `json:"scopes" bson:"scopes"`
+	// CreatedBy - username of the admin who created the key
+	CreatedBy  string     `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	// RevokedAt - revoked keys are kept so their use stays on record, but are no longer accepted
+	RevokedAt *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
@@ -6,13 +6,21 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Appointment - type that represents a users appointment
+// Appointment - type that represents a users appointment.
+// ServiceIDs are the catalog items booked for it and DurationMinutes their total labor time when they were booked;
+// TechnicianIDs are the technicians assigned to work on it.
 type Appointment struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name        string             `json:"name" bson:"name"`
-	Description string             `json:"description" bson:"description"`
-	Status      string             `json:"status" bson:"status"`
-	Date        time.Time          `json:"date" bson:"date"`
+	ID              primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Name            string               `json:"name" bson:"name"`
+	Description     string               `json:"description" bson:"description"`
+	Status          string               `json:"status" bson:"status"`
+	Date            time.Time            `json:"date" bson:"date"`
+	Service         string               `json:"service,omitempty" bson:"service,omitempty"`
+	CustomerID      *primitive.ObjectID  `json:"customer_id,omitempty" bson:"customer_id,omitempty"`
+	VehicleID       *primitive.ObjectID  `json:"vehicle_id,omitempty" bson:"vehicle_id,omitempty"`
+	ServiceIDs      []primitive.ObjectID `json:"service_ids,omitempty" bson:"service_ids,omitempty"`
+	DurationMinutes int                  `json:"duration_minutes,omitempty" bson:"duration_minutes,omitempty"`
+	TechnicianIDs   []primitive.ObjectID `json:"technician_ids,omitempty" bson:"technician_ids,omitempty"`
 }
 
 // Status - status for appointment in update
@@ -25,3 +33,25 @@ type AppointmentUpdate struct {
 	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Status string             `json:"status" bson:"status"`
 }
+
+// WithTechnician - returns a copy of ids with id added at the end, unless it is already one of them
+func WithTechnician(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	result := append([]primitive.ObjectID{}, ids...)
+	for _, assigned := range ids {
+		if assigned == id {
+			return result
+		}
+	}
+	return append(result, id)
+}
+
+// WithoutTechnician - returns a copy of ids with id left out
+func WithoutTechnician(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	result := []primitive.ObjectID{}
+	for _, assigned := range ids {
+		if assigned != id {
+			result = append(result, assigned)
+		}
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audited appointment changes
+const (
+	AuditCreated       = "created"
+	AuditUpdated       = "updated"
+	AuditStatusChanged = "status_changed"
+	AuditDeleted       = "deleted"
+)
+
+// Kinds of actor that change appointments
+const (
+	ActorUser     = "user"
+	ActorAPIKey   = "api_key"
+	ActorCustomer = "customer"
+	// ActorSystem - changes made without an authenticated caller, such as by tests or maintenance code
+	ActorSystem = "system"
+)
+
+// Actor - who made an audited change
+type Actor struct {
+	Type string `json:"type" bson:"type"`
+	// ID - the id of the user, API key or customer
+	ID   string `json:"id,omitempty" bson:"id,omitempty"`
+	Name string `json:"name,omitempty" bson:"name,omitempty"`
+}
+
+// FieldChange - one field of an appointment that an audited change set, changed or removed. Values are written
+// as they appear in the appointment's JSON, with strings unquoted; a field that wasn't set has no value.
+type FieldChange struct {
+	Field  string `json:"field" bson:"field"`
+	Before string `json:"before,omitempty" bson:"before,omitempty"`
+	After  string `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditEntry - a record of one change to an appointment. Entries are only ever appended, and outlive the
+// appointment they describe.
+type AuditEntry struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	AppointmentID primitive.ObjectID `json:"appointment_id" bson:"appointment_id"`
+	Action        string             `json:"action" bson:"action"`
+	Actor         Actor              `json:"actor" bson:"actor"`
+	// RequestID - the id the request was logged under, to find it in the server logs
+	RequestID string        `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	At        time.Time     `json:"at" bson:"at"`
+	Changes   []FieldChange `json:"changes" bson:"changes"`
+}
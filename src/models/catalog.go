@@ -0,0 +1,41 @@
+package models
+
+import (
+	"CarServiceCenter/src/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CatalogItem - a standard job the shop offers, identified by a short unique code such as "oil_change".
+// LaborMinutes is the standard labor time, which is also how long the job occupies a bay, and LaborRate the price of an hour of labor.
+type CatalogItem struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Code         string             `json:"code" bson:"code"`
+	Name         string             `json:"name" bson:"name"`
+	Description  string             `json:"description,omitempty" bson:"description,omitempty"`
+	LaborMinutes int                `json:"labor_minutes" bson:"labor_minutes"`
+	LaborRate    money.Amount       `json:"labor_rate" bson:"labor_rate"`
+	Parts        []CatalogPart      `json:"parts,omitempty" bson:"parts,omitempty"`
+}
+
+// CatalogPart - a part used by default for a catalog item
+type CatalogPart struct {
+	SKU         string       `json:"sku" bson:"sku"`
+	Description string       `json:"description,omitempty" bson:"description,omitempty"`
+	Quantity    int          `json:"quantity" bson:"quantity"`
+	UnitPrice   money.Amount `json:"unit_price" bson:"unit_price"`
+}
+
+// LaborPrice - the standard labor time charged at the labor rate
+func (c CatalogItem) LaborPrice() money.Amount {
+	return c.LaborRate.MulRatio(int64(c.LaborMinutes), 60)
+}
+
+// PartsPrice - the total price of the default parts
+func (c CatalogItem) PartsPrice() money.Amount {
+	var total money.Amount
+	for _, part := range c.Parts {
+		total += part.UnitPrice.Mul(int64(part.Quantity))
+	}
+	return total
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Contact channels a customer can prefer to be reached on
+const (
+	ContactPhone = "phone"
+	ContactSMS   = "sms"
+	ContactEmail = "email"
+	ContactMail  = "mail"
+)
+
+// Customer - a person or business that books appointments
+type Customer struct {
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name             string             `json:"name" bson:"name"`
+	Phones           []string           `json:"phones,omitempty" bson:"phones,omitempty"`
+	Emails           []string           `json:"emails,omitempty" bson:"emails,omitempty"`
+	Address          *Address           `json:"address,omitempty" bson:"address,omitempty"`
+	PreferredContact string             `json:"preferred_contact,omitempty" bson:"preferred_contact,omitempty"`
+	Notes            string             `json:"notes,omitempty" bson:"notes,omitempty"`
+}
+
+// Address - postal address of a customer
+type Address struct {
+	Street     string `json:"street,omitempty" bson:"street,omitempty"`
+	City       string `json:"city,omitempty" bson:"city,omitempty"`
+	State      string `json:"state,omitempty" bson:"state,omitempty"`
+	PostalCode string `json:"postal_code,omitempty" bson:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty" bson:"country,omitempty"`
+}
+
+// CustomerFilter - narrows a customer listing; empty fields match every customer
+type CustomerFilter struct {
+	// Name - case-insensitive substring of the customer's name
+	Name string
+	// Phone - one of the customer's phone numbers, matched exactly
+	Phone string
+	// Email - one of the customer's email addresses, matched case-insensitively
+	Email string
+}
+
+// ContactChannels - every preferred contact channel, in a stable order
+func ContactChannels() []string {
+	return []string{ContactPhone, ContactSMS, ContactEmail, ContactMail}
+}
+
+// ValidContactChannel - reports whether channel is one of ContactChannels
+func ValidContactChannel(channel string) bool {
+	for _, known := range ContactChannels() {
+		if channel == known {
+			return true
+		}
+	}
+	return false
+}
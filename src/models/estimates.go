@@ -0,0 +1,83 @@
+package models
+
+import (
+	"CarServiceCenter/src/money"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Estimate approval states. Lines are pending, approved or declined; an estimate is partially approved when the
+// customer approved some of its lines and declined the rest.
+const (
+	ApprovalPending           = "pending"
+	ApprovalApproved          = "approved"
+	ApprovalDeclined          = "declined"
+	ApprovalPartiallyApproved = "partially_approved"
+)
+
+// Estimate - the quoted price of the work on an appointment, for the customer to approve before it starts. Approver and
+// DecidedAt record who made the decision on the customer's behalf and when. Version is incremented by every update.
+type Estimate struct {
+	ID            primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	AppointmentID primitive.ObjectID  `json:"appointment_id" bson:"appointment_id"`
+	CustomerID    *primitive.ObjectID `json:"customer_id,omitempty" bson:"customer_id,omitempty"`
+	VehicleID     *primitive.ObjectID `json:"vehicle_id,omitempty" bson:"vehicle_id,omitempty"`
+	Status        string              `json:"status" bson:"status"`
+	Lines         []EstimateLine      `json:"lines" bson:"lines"`
+	Approver      string              `json:"approver,omitempty" bson:"approver,omitempty"`
+	DecidedAt     *time.Time          `json:"decided_at,omitempty" bson:"decided_at,omitempty"`
+	CreatedAt     time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at" bson:"updated_at"`
+	Version       int                 `json:"version" bson:"version"`
+}
+
+// EstimateLine - a quoted labor or part line and whether the customer approved it
+type EstimateLine struct {
+	WorkOrderLine `bson:",inline"`
+	Approval      string `json:"approval" bson:"approval"`
+}
+
+// EstimateFilter - narrows an estimate listing; empty fields match every estimate
+type EstimateFilter struct {
+	AppointmentID *primitive.ObjectID
+	Status        string
+}
+
+// Total - the price of every line of the given type with the given approval state; empty arguments match every line
+func (e Estimate) Total(lineType, approval string) money.Amount {
+	var total money.Amount
+	for _, line := range e.Lines {
+		if (lineType == "" || line.Type == lineType) && (approval == "" || line.Approval == approval) {
+			total += line.Total()
+		}
+	}
+	return total
+}
+
+// Approved - reports whether the customer approved at least some of the estimate, so work may start
+func (e Estimate) Approved() bool {
+	return e.Status == ApprovalApproved || e.Status == ApprovalPartiallyApproved
+}
+
+// ApprovalStatus - the estimate status that follows from the approval states of its lines
+func (e Estimate) ApprovalStatus() string {
+	approved, declined := 0, 0
+	for _, line := range e.Lines {
+		switch line.Approval {
+		case ApprovalApproved:
+			approved++
+		case ApprovalDeclined:
+			declined++
+		}
+	}
+	switch {
+	case approved+declined < len(e.Lines):
+		return ApprovalPending
+	case declined == 0:
+		return ApprovalApproved
+	case approved == 0:
+		return ApprovalDeclined
+	}
+	return ApprovalPartiallyApproved
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"CarServiceCenter/src/money"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LineFee - the line type shop fees are taxed as
+const LineFee = "fee"
+
+// Invoice - the bill for a finished work order. Lines are copied from the work order when the invoice is issued,
+// and every amount is worked out then, so later changes to the work order or to billing settings don't alter it.
+type Invoice struct {
+	ID            primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Number        string              `json:"number" bson:"number"`
+	WorkOrderID   primitive.ObjectID  `json:"work_order_id" bson:"work_order_id"`
+	AppointmentID primitive.ObjectID  `json:"appointment_id" bson:"appointment_id"`
+	CustomerID    *primitive.ObjectID `json:"customer_id,omitempty" bson:"customer_id,omitempty"`
+	VehicleID     *primitive.ObjectID `json:"vehicle_id,omitempty" bson:"vehicle_id,omitempty"`
+	IssuedAt      time.Time           `json:"issued_at" bson:"issued_at"`
+	Lines         []WorkOrderLine     `json:"lines" bson:"lines"`
+	Discounts     []Adjustment        `json:"discounts" bson:"discounts"`
+	Fees          []Adjustment        `json:"fees" bson:"fees"`
+	Taxes         []Tax               `json:"taxes" bson:"taxes"`
+	LaborTotal    money.Amount        `json:"labor_total" bson:"labor_total"`
+	PartsTotal    money.Amount        `json:"parts_total" bson:"parts_total"`
+	DiscountTotal money.Amount        `json:"discount_total" bson:"discount_total"`
+	FeeTotal      money.Amount        `json:"fee_total" bson:"fee_total"`
+	TaxTotal      money.Amount        `json:"tax_total" bson:"tax_total"`
+	Total         money.Amount        `json:"total" bson:"total"`
+}
+
+// Adjustment - a discount taken off or a fee added to an invoice. LineType is set when it only applies to labor or to parts.
+type Adjustment struct {
+	Description string       `json:"description" bson:"description"`
+	Code        string       `json:"code,omitempty" bson:"code,omitempty"`
+	LineType    string       `json:"line_type,omitempty" bson:"line_type,omitempty"`
+	Amount      money.Amount `json:"amount" bson:"amount"`
+}
+
+// Tax - the tax charged on one line type
+type Tax struct {
+	LineType string       `json:"line_type" bson:"line_type"`
+	Rate     money.Rate   `json:"rate" bson:"rate"`
+	Taxable  money.Amount `json:"taxable" bson:"taxable"`
+	Amount   money.Amount `json:"amount" bson:"amount"`
+}
+
+// InvoiceFilter - narrows an invoice listing; empty fields match every invoice
+type InvoiceFilter struct {
+	WorkOrderID *primitive.ObjectID
+	CustomerID  *primitive.ObjectID
+}
@@ -0,0 +1,33 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Part - a stocked part, identified by its unique SKU. Reserved counts the units set aside for open work orders,
+// so only OnHand less Reserved can still be promised; a part at or below ReorderPoint available units should be reordered.
+type Part struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	SKU          string             `json:"sku" bson:"sku"`
+	Name         string             `json:"name" bson:"name"`
+	Bin          string             `json:"bin,omitempty" bson:"bin,omitempty"`
+	OnHand       int                `json:"on_hand" bson:"on_hand"`
+	Reserved     int                `json:"reserved" bson:"reserved"`
+	ReorderPoint int                `json:"reorder_point" bson:"reorder_point"`
+}
+
+// PartFilter - narrows a part listing; empty fields match every part
+type PartFilter struct {
+	SKU string
+	Bin string
+	// Reorder - only parts whose available quantity is at or below their reorder point
+	Reorder bool
+}
+
+// Available - the units that aren't reserved
+func (p Part) Available() int {
+	return p.OnHand - p.Reserved
+}
+
+// NeedsReorder - reports whether the available units have fallen to the reorder point
+func (p Part) NeedsReorder() bool {
+	return p.Available() <= p.ReorderPoint
+}
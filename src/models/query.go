@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Fields appointments can be sorted by when listing
+const (
+	SortByDate   = "date"
+	SortByName   = "name"
+	SortByStatus = "status"
+)
+
+// Page sizes for appointment listings
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// AppointmentFilter - criteria an appointment must match to be listed; zero values match everything
+type AppointmentFilter struct {
+	Status       string
+	Name         string // case-insensitive substring of the appointment name
+	Start        time.Time
+	End          time.Time
+	CustomerID   *primitive.ObjectID
+	VehicleID    *primitive.ObjectID
+	TechnicianID *primitive.ObjectID // one of the technicians assigned to the appointment
+}
+
+// AppointmentQuery - a filtered, sorted request for one page of appointments
+type AppointmentQuery struct {
+	Filter     AppointmentFilter
+	SortBy     string
+	Descending bool
+	Limit      int
+	After      string // Next token from the previous page, empty for the first page
+}
+
+// AppointmentPage - one page of listed appointments and the token for the page after it, if any
+type AppointmentPage struct {
+	Appointments []Appointment `json:"appointments"`
+	Next         string        `json:"next,omitempty"`
+}
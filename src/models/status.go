@@ -0,0 +1,69 @@
+package models
+
+// Appointment statuses, in the order an appointment normally moves through them
+const (
+	StatusOpen         = "open"
+	StatusConfirmed    = "confirmed"
+	StatusCheckedIn    = "checked_in"
+	StatusInProgress   = "in_progress"
+	StatusWaitingParts = "waiting_parts"
+	StatusCompleted    = "completed"
+	StatusPickedUp     = "picked_up"
+	StatusCancelled    = "cancelled"
+	StatusNoShow       = "no_show"
+	// StatusClosed - the only status before the lifecycle existed; appointments still stored with it are finished and can't move
+	StatusClosed = "closed"
+)
+
+// statusTransitions - statuses an appointment may move to from each status; terminal statuses have none
+var statusTransitions = map[string][]string{
+	StatusOpen:         {StatusConfirmed, StatusCheckedIn, StatusCancelled, StatusNoShow},
+	StatusConfirmed:    {StatusCheckedIn, StatusCancelled, StatusNoShow},
+	StatusCheckedIn:    {StatusInProgress, StatusCancelled},
+	StatusInProgress:   {StatusWaitingParts, StatusCompleted, StatusCancelled},
+	StatusWaitingParts: {StatusInProgress, StatusCancelled},
+	StatusCompleted:    {StatusPickedUp},
+	StatusPickedUp:     {},
+	StatusCancelled:    {},
+	StatusNoShow:       {},
+	StatusClosed:       {},
+}
+
+// Statuses - every valid appointment status
+func Statuses() []string {
+	return []string{
+		StatusOpen, StatusConfirmed, StatusCheckedIn, StatusInProgress, StatusWaitingParts,
+		StatusCompleted, StatusPickedUp, StatusCancelled, StatusNoShow, StatusClosed,
+	}
+}
+
+// FinishedStatuses - statuses of appointments whose work is done or abandoned; their details can no longer be edited
+func FinishedStatuses() []string {
+	return []string{StatusCompleted, StatusPickedUp, StatusCancelled, StatusNoShow, StatusClosed}
+}
+
+// Finished - reports whether status is one of FinishedStatuses
+func Finished(status string) bool {
+	for _, finished := range FinishedStatuses() {
+		if status == finished {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidStatus - reports whether status is part of the appointment lifecycle
+func ValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// CanTransition - reports whether an appointment in status from may move to status to
+func CanTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
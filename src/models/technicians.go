@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Technician - a mechanic appointments can be assigned to.
+// WorkingHours is keyed by lower-case weekday; a technician without working hours is available whenever the shop is open.
+type Technician struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name           string             `json:"name" bson:"name"`
+	Email          string             `json:"email,omitempty" bson:"email,omitempty"`
+	Skills         []string           `json:"skills,omitempty" bson:"skills,omitempty"`
+	Certifications []Certification    `json:"certifications,omitempty" bson:"certifications,omitempty"`
+	WorkingHours   map[string]Shift   `json:"working_hours,omitempty" bson:"working_hours,omitempty"`
+}
+
+// Certification - a qualification held by a technician, such as an ASE certification
+type Certification struct {
+	Name    string     `json:"name" bson:"name"`
+	Number  string     `json:"number,omitempty" bson:"number,omitempty"`
+	Expires *time.Time `json:"expires,omitempty" bson:"expires,omitempty"`
+}
+
+// Shift - start and end of a technician's working day in 24-hour "15:04" form
+type Shift struct {
+	Start string `json:"start" bson:"start"`
+	End   string `json:"end" bson:"end"`
+}
+
+// TechnicianFilter - narrows a technician listing; empty fields match every technician
+type TechnicianFilter struct {
+	// Skill - one of the technician's skills, matched case-insensitively
+	Skill string
+}
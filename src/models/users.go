@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User roles, from the most to the least privileged
+const (
+	RoleAdmin      = "admin"
+	RoleAdvisor    = "advisor"
+	RoleTechnician = "technician"
+	RoleReadOnly   = "read_only"
+)
+
+// Roles - every valid user role
+func Roles() []string {
+	return []string{RoleAdmin, RoleAdvisor, RoleTechnician, RoleReadOnly}
+}
+
+// ValidRole - reports whether role is one a user may have
+func ValidRole(role string) bool {
+	for _, valid := range Roles() {
+		if role == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// User - a staff account that can sign in to the API. Usernames are unique and stored in lower case;
+// only the bcrypt hash of the password is kept, and it is never written to responses.
+type User struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Username     string             `json:"username" bson:"username"`
+	Name         string             `json:"name" bson:"name"`
+	PasswordHash string             `json:"-" bson:"password_hash"`
+	// Role - decides which routes the user may call; see auth.Require
+	Role string `json:"role" bson:"role"`
+	// Disabled - disabled users can't sign in or refresh their sessions
+	Disabled bool `json:"disabled" bson:"disabled"`
+	// TokenVersion - goes up whenever the password changes, ending the sessions issued before
+	TokenVersion int       `json:"-" bson:"token_version"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+}
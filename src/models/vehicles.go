@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Vehicle - a car owned by a customer
+type Vehicle struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CustomerID primitive.ObjectID `json:"customer_id" bson:"customer_id"`
+	VIN        string             `json:"vin" bson:"vin"`
+	Make       string             `json:"make" bson:"make"`
+	Model      string             `json:"model" bson:"model"`
+	Year       int                `json:"year" bson:"year"`
+	Plate      string             `json:"plate,omitempty" bson:"plate,omitempty"`
+	Mileage    int                `json:"mileage,omitempty" bson:"mileage,omitempty"`
+	Color      string             `json:"color,omitempty" bson:"color,omitempty"`
+}
+
+// VehicleFilter - narrows a vehicle listing; empty fields match every vehicle.
+// VIN and Plate are matched exactly, so they should be normalized the same way as stored vehicles.
+type VehicleFilter struct {
+	CustomerID *primitive.ObjectID
+	VIN        string
+	Plate      string
+}
+
+// NormalizeVIN - upper-cases vin and drops surrounding whitespace
+func NormalizeVIN(vin string) string {
+	return strings.ToUpper(strings.TrimSpace(vin))
+}
+
+// NormalizePlate - upper-cases plate and drops spaces and dashes, so "abc-123" and "ABC 123" are the same plate
+func NormalizePlate(plate string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(plate))
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"CarServiceCenter/src/money"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Work order line types
+const (
+	LineLabor = "labor"
+	LinePart  = "part"
+)
+
+// WorkOrder - the work done on a checked-in appointment. Its status follows the appointment's, and StatusHistory records every change.
+// Version is incremented by every update so concurrent changes can't overwrite each other.
+type WorkOrder struct {
+	ID            primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	AppointmentID primitive.ObjectID  `json:"appointment_id" bson:"appointment_id"`
+	CustomerID    *primitive.ObjectID `json:"customer_id,omitempty" bson:"customer_id,omitempty"`
+	VehicleID     *primitive.ObjectID `json:"vehicle_id,omitempty" bson:"vehicle_id,omitempty"`
+	Status        string              `json:"status" bson:"status"`
+	StatusHistory []StatusChange      `json:"status_history" bson:"status_history"`
+	Lines         []WorkOrderLine     `json:"lines" bson:"lines"`
+	TimeEntries   []TimeEntry         `json:"time_entries" bson:"time_entries"`
+	CreatedAt     time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at" bson:"updated_at"`
+	Version       int                 `json:"version" bson:"version"`
+}
+
+// StatusChange - a status a work order moved to and when
+type StatusChange struct {
+	Status string    `json:"status" bson:"status"`
+	At     time.Time `json:"at" bson:"at"`
+}
+
+// WorkOrderLine - a labor or part charge on a work order. Labor is charged per minute at the hourly Rate,
+// parts per unit at UnitPrice; CatalogItemID is set on lines that came from the service catalog.
+type WorkOrderLine struct {
+	ID            primitive.ObjectID  `json:"id" bson:"id"`
+	Type          string              `json:"type" bson:"type"`
+	CatalogItemID *primitive.ObjectID `json:"catalog_item_id,omitempty" bson:"catalog_item_id,omitempty"`
+	Description   string              `json:"description" bson:"description"`
+	Minutes       int                 `json:"minutes,omitempty" bson:"minutes,omitempty"`
+	Rate          money.Amount        `json:"rate,omitempty" bson:"rate,omitempty"`
+	SKU           string              `json:"sku,omitempty" bson:"sku,omitempty"`
+	Quantity      int                 `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	UnitPrice     money.Amount        `json:"unit_price,omitempty" bson:"unit_price,omitempty"`
+	// Reserved - whether Quantity units of the SKU are currently reserved in the parts inventory for this line
+	Reserved bool `json:"reserved,omitempty" bson:"reserved,omitempty"`
+}
+
+// TimeEntry - time a technician actually spent on a work order
+type TimeEntry struct {
+	ID           primitive.ObjectID `json:"id" bson:"id"`
+	TechnicianID primitive.ObjectID `json:"technician_id" bson:"technician_id"`
+	Start        time.Time          `json:"start" bson:"start"`
+	End          time.Time          `json:"end" bson:"end"`
+	Notes        string             `json:"notes,omitempty" bson:"notes,omitempty"`
+}
+
+// WorkOrderFilter - narrows a work order listing; empty fields match every work order
+type WorkOrderFilter struct {
+	AppointmentID *primitive.ObjectID
+	Status        string
+}
+
+// Total - the price of the line
+func (l WorkOrderLine) Total() money.Amount {
+	if l.Type == LineLabor {
+		return l.Rate.MulRatio(int64(l.Minutes), 60)
+	}
+	return l.UnitPrice.Mul(int64(l.Quantity))
+}
+
+// Duration - how long the technician worked
+func (t TimeEntry) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// Total - the price of every line of the given type, or of every line when lineType is empty
+func (w WorkOrder) Total(lineType string) money.Amount {
+	var total money.Amount
+	for _, line := range w.Lines {
+		if lineType == "" || line.Type == lineType {
+			total += line.Total()
+		}
+	}
+	return total
+}
+
+// Closed - reports whether the work is finished or abandoned, after which lines and time entries can no longer change
+func (w WorkOrder) Closed() bool {
+	return w.Status == StatusCompleted || w.Status == StatusPickedUp || w.Status == StatusCancelled
+}
@@ -0,0 +1,135 @@
+package money
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Amount - a sum of money in cents. Arithmetic is done on integers so amounts never pick up floating point error.
+type Amount int64
+
+// ErrInvalidAmount - the text isn't a decimal amount with at most two decimal places
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Cents - returns the amount for the given number of cents
+func Cents(cents int64) Amount {
+	return Amount(cents)
+}
+
+// Parse - reads a decimal amount such as "12", "12.5" or "-0.75"
+func Parse(value string) (Amount, error) {
+	cents, err := parseFixed(value, 2)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalidAmount, value)
+	}
+	return Amount(cents), nil
+}
+
+// parseFixed - reads a decimal number with at most places decimal places as an integer count of its smallest unit
+func parseFixed(value string, places int) (int64, error) {
+	text := strings.TrimSpace(value)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+
+	whole, fraction := text, ""
+	if dot := strings.IndexByte(text, '.'); dot >= 0 {
+		whole, fraction = text[:dot], text[dot+1:]
+	}
+	if whole == "" || len(fraction) > places || strings.ContainsAny(whole+fraction, "+-") {
+		return 0, errors.New("not a fixed point number")
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	parts := int64(0)
+	if fraction != "" {
+		if parts, err = strconv.ParseInt(fraction+strings.Repeat("0", places-len(fraction)), 10, 64); err != nil {
+			return 0, err
+		}
+	}
+
+	scale := int64(1)
+	for i := 0; i < places; i++ {
+		scale *= 10
+	}
+	result := units*scale + parts
+	if negative {
+		result = -result
+	}
+	return result, nil
+}
+
+// String - the amount with exactly two decimal places, such as "12.50"
+func (a Amount) String() string {
+	sign := ""
+	cents := int64(a)
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Mul - the amount multiplied by quantity
+func (a Amount) Mul(quantity int64) Amount {
+	return a * Amount(quantity)
+}
+
+// MulRatio - the amount multiplied by numerator/denominator, rounded half away from zero to the nearest cent
+func (a Amount) MulRatio(numerator, denominator int64) Amount {
+	product := int64(a) * numerator
+	quotient, remainder := product/denominator, product%denominator
+	if remainder < 0 {
+		remainder = -remainder
+	}
+	if 2*remainder >= abs(denominator) {
+		if (product < 0) != (denominator < 0) {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return Amount(quotient)
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// MarshalJSON - writes the amount as a decimal string such as "12.50" so clients don't read it into a float
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.String() + `"`), nil
+}
+
+// UnmarshalJSON - accepts a decimal string such as "12.50" or a JSON number such as 12.5; null leaves the amount unchanged
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	parsed, err := Parse(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// UnmarshalYAML - accepts a decimal amount such as "12.50" or 12.5 in config files
+func (a *Amount) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	parsed, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
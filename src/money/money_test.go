@@ -0,0 +1,91 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	valid := map[string]Amount{
+		"12":      1200,
+		"12.5":    1250,
+		"12.05":   1205,
+		"0.99":    99,
+		"-0.75":   -75,
+		" 100.00": 10000,
+	}
+	for text, expected := range valid {
+		if amount, err := Parse(text); err != nil || amount != expected {
+			t.Errorf("%q: got %v, %v want %v", text, amount, err, expected)
+		}
+	}
+	for _, text := range []string{"", "1.234", "abc", ".5", "1.-5", "--1", "1e3"} {
+		if _, err := Parse(text); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("%q: got %v want %v", text, err, ErrInvalidAmount)
+		}
+	}
+}
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		amount                 Amount
+		numerator, denominator int64
+		expected               Amount
+	}{
+		{9500, 45, 60, 7125},
+		{100, 1, 3, 33},
+		{200, 1, 3, 67},
+		{-200, 1, 3, -67},
+		{1, 1, 2, 1},
+		{-1, 1, 2, -1},
+	}
+	for _, test := range tests {
+		if result := test.amount.MulRatio(test.numerator, test.denominator); result != test.expected {
+			t.Errorf("%v * %d/%d: got %v want %v", test.amount, test.numerator, test.denominator, result, test.expected)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var decoded struct{ A, B Amount }
+	if err := json.Unmarshal([]byte(`{"A":"19.99","B":20.5}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.A != 1999 || decoded.B != 2050 {
+		t.Errorf("got %+v", decoded)
+	}
+	encoded, _ := json.Marshal(Amount(-5))
+	if string(encoded) != `"-0.05"` {
+		t.Errorf("got %s", encoded)
+	}
+	if err := json.Unmarshal([]byte(`{"A":"19.999"}`), &decoded); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("got %v want %v", err, ErrInvalidAmount)
+	}
+}
+
+func TestRate(t *testing.T) {
+	tests := []struct {
+		text     string
+		rate     Rate
+		amount   Amount
+		expected Amount
+	}{
+		{"8.875", 88750, 10000, 888},
+		{"10", Percent(10), 1999, 200},
+		{"0.5", 5000, 101, 1},
+		{"7.25", 72500, -1000, -73},
+	}
+	for _, test := range tests {
+		rate, err := ParseRate(test.text)
+		if err != nil || rate != test.rate || rate.String() != test.text {
+			t.Errorf("%q: got %v (%v), %v want %v", test.text, int64(rate), rate, err, int64(test.rate))
+		}
+		if result := rate.Of(test.amount); result != test.expected {
+			t.Errorf("%v%% of %v: got %v want %v", rate, test.amount, result, test.expected)
+		}
+	}
+	if _, err := ParseRate("8.87501"); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("got %v want %v", err, ErrInvalidRate)
+	}
+}
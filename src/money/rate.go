@@ -0,0 +1,85 @@
+package money
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ratePlaces - decimal places a Rate keeps, enough for tax rates such as 8.875%
+const ratePlaces = 4
+
+// rateScale - the Rate of one percent
+const rateScale = 10000
+
+// Rate - a percentage in ten-thousandths of a percent, so 8.875% is 88750
+type Rate int64
+
+// ErrInvalidRate - the text isn't a decimal percentage with at most four decimal places
+var ErrInvalidRate = errors.New("invalid rate")
+
+// Percent - returns the rate for a whole number of percent
+func Percent(percent int64) Rate {
+	return Rate(percent * rateScale)
+}
+
+// ParseRate - reads a decimal percentage such as "8", "8.875" or "-5"
+func ParseRate(value string) (Rate, error) {
+	rate, err := parseFixed(value, ratePlaces)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalidRate, value)
+	}
+	return Rate(rate), nil
+}
+
+// String - the percentage without trailing zeros, such as "8.875" or "10"
+func (r Rate) String() string {
+	sign := ""
+	value := int64(r)
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	fraction := strings.TrimRight(fmt.Sprintf("%04d", value%rateScale), "0")
+	if fraction == "" {
+		return fmt.Sprintf("%s%d", sign, value/rateScale)
+	}
+	return fmt.Sprintf("%s%d.%s", sign, value/rateScale, fraction)
+}
+
+// Of - rate percent of the amount, rounded half away from zero to the nearest cent
+func (r Rate) Of(amount Amount) Amount {
+	return amount.MulRatio(int64(r), 100*rateScale)
+}
+
+// MarshalJSON - writes the rate as a decimal string such as "8.875"
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + r.String() + `"`), nil
+}
+
+// UnmarshalJSON - accepts a decimal string such as "8.875" or a JSON number such as 8.875; null leaves the rate unchanged
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	parsed, err := ParseRate(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// UnmarshalYAML - accepts a decimal percentage such as "8.875" or 8.875 in config files
+func (r *Rate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	parsed, err := ParseRate(value)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
@@ -1,8 +1,13 @@
 package router
 
 import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/billing"
+	"CarServiceCenter/src/config"
 	"CarServiceCenter/src/controller"
 	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"CarServiceCenter/src/scheduling"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -10,16 +15,31 @@ import (
 	"github.com/rs/cors"
 )
 
-// Initialize chi mux router
-func Initialize() *chi.Mux {
-	mongoStruct := &db.MongoStruct{}
-	appointmentsController := controller.AppointmentsController{DB: mongoStruct}
+// Initialize chi mux router backed by the given database; scheduler may be nil to accept appointments at any time.
+// Staff routes require an access token signed by tokens or an API key: any role may read, each route that changes
+// something names the roles allowed to call it, and API keys are limited to the resources their scopes cover.
+// The customer portal only accepts portal tokens, and signing in and refreshing a session need neither.
+func Initialize(cfg *config.Config, database db.Database, scheduler *scheduling.Scheduler, tokens *auth.Tokens) *chi.Mux {
+	apiKeysController := &controller.APIKeysController{DB: database}
+	appointmentsController := &controller.AppointmentsController{DB: database, Scheduler: scheduler, Customers: database, Vehicles: database, Catalog: database, Technicians: database, WorkOrders: database, Parts: database, Estimates: database, Audit: database}
+	catalogController := &controller.CatalogController{DB: database}
+	customersController := &controller.CustomersController{DB: database, Appointments: database, Vehicles: database}
+	estimatesController := &controller.EstimatesController{DB: database, Appointments: database, Catalog: database}
+	invoicesController := &controller.InvoicesController{DB: database, WorkOrders: database, Billing: billing.New(cfg.Billing)}
+	partsController := &controller.PartsController{DB: database}
+	portalController := &controller.PortalController{Appointments: appointmentsController, Customers: database, Tokens: tokens,
+		LinkTTL: time.Duration(cfg.Portal.LinkTTL), CancellationCutoff: time.Duration(cfg.Portal.CancellationCutoff), Now: time.Now}
+	techniciansController := &controller.TechniciansController{DB: database, Appointments: database}
+	usersController := &controller.UsersController{DB: database, Tokens: tokens}
+	vehiclesController := &controller.VehiclesController{DB: database, Customers: database, Appointments: database}
+	workOrdersController := &controller.WorkOrdersController{DB: database, Appointments: database, Catalog: database, Technicians: database, Parts: database}
 	muxRouter := chi.NewRouter()
 
+	// sessions are bearer tokens rather than cookies, so browsers are never asked to send credentials cross-origin
 	cors := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET"},
-		AllowCredentials: true,
+		AllowedOrigins: cfg.Server.AllowedOrigins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Authorization", "Content-Type", auth.APIKeyHeader},
 	})
 
 	muxRouter.Use(cors.Handler)
@@ -27,13 +47,145 @@ func Initialize() *chi.Mux {
 	muxRouter.Use(middleware.RealIP)
 	muxRouter.Use(middleware.Logger)
 	muxRouter.Use(middleware.Recoverer)
-	muxRouter.Use(middleware.Timeout(200 * time.Second))
+	muxRouter.Use(middleware.Timeout(time.Duration(cfg.Server.RequestTimeout)))
 
-	muxRouter.Get("/appointment/{id}", appointmentsController.GetAppointment)
-	muxRouter.Post("/appointment/", appointmentsController.CreateAppointment)
-	muxRouter.Patch("/appointment/{id}", appointmentsController.UpdateAppointmentStatus)
-	muxRouter.Delete("/appointment/{id}", appointmentsController.DeleteAppointment)
-	muxRouter.Get("/appointments/range/", appointmentsController.GetAppointmentsWithinDateRange)
+	muxRouter.Post("/auth/login", usersController.Login)
+	muxRouter.Post("/auth/refresh", usersController.Refresh)
+
+	// customers use the portal with the tokens in their portal links, which no other route accepts
+	muxRouter.Route("/portal", func(r chi.Router) {
+		r.Use(tokens.AuthenticateCustomer)
+
+		r.Get("/appointments", portalController.ListAppointments)
+		r.Post("/appointments", portalController.BookAppointment)
+		r.Get("/appointments/{id}", portalController.GetAppointment)
+		r.Post("/appointments/{id}/cancel", portalController.CancelAppointment)
+		r.Get("/availability", appointmentsController.GetAvailability)
+	})
+
+	apiKeys := &auth.APIKeys{DB: database, Now: time.Now}
+	admin := auth.Require(models.RoleAdmin)
+	advisors := auth.Require(models.RoleAdmin, models.RoleAdvisor)
+	// technicians' status changes are limited further by the appointments controller
+	staff := auth.Require(models.RoleAdmin, models.RoleAdvisor, models.RoleTechnician)
+
+	muxRouter.Group(func(r chi.Router) {
+		r.Use(apiKeys.Authenticate)
+		r.Use(tokens.Authenticate)
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("appointments"))
+			r.Get("/appointment/{id}", appointmentsController.GetAppointment)
+			r.Get("/appointment/{id}/history", appointmentsController.GetAppointmentHistory)
+			r.With(advisors).Post("/appointment/", appointmentsController.CreateAppointment)
+			r.With(staff).Patch("/appointment/{id}", appointmentsController.PatchAppointment)
+			r.With(advisors).Put("/appointment/{id}", appointmentsController.UpdateAppointment)
+			r.With(advisors).Delete("/appointment/{id}", appointmentsController.DeleteAppointment)
+			r.With(advisors).Put("/appointment/{id}/technicians/{technicianID}", appointmentsController.AssignTechnician)
+			r.With(advisors).Delete("/appointment/{id}/technicians/{technicianID}", appointmentsController.UnassignTechnician)
+			r.Get("/appointments", appointmentsController.ListAppointments)
+			r.Get("/appointments/range/", appointmentsController.GetAppointmentsWithinDateRange)
+			r.Get("/availability", appointmentsController.GetAvailability)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("catalog"))
+			r.Get("/catalog", catalogController.ListCatalogItems)
+			r.With(advisors).Post("/catalog", catalogController.CreateCatalogItem)
+			r.Get("/catalog/{id}", catalogController.GetCatalogItem)
+			r.With(advisors).Put("/catalog/{id}", catalogController.UpdateCatalogItem)
+			r.With(advisors).Patch("/catalog/{id}", catalogController.UpdateCatalogItem)
+			r.With(advisors).Delete("/catalog/{id}", catalogController.DeleteCatalogItem)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("customers"))
+			r.Get("/customers", customersController.ListCustomers)
+			r.With(advisors).Post("/customers", customersController.CreateCustomer)
+			r.Get("/customers/{id}", customersController.GetCustomer)
+			r.With(advisors).Put("/customers/{id}", customersController.UpdateCustomer)
+			r.With(advisors).Patch("/customers/{id}", customersController.UpdateCustomer)
+			r.With(advisors).Delete("/customers/{id}", customersController.DeleteCustomer)
+			r.With(advisors).Post("/customers/{id}/portal", portalController.CreatePortalLink)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("estimates"))
+			r.Get("/estimates", estimatesController.ListEstimates)
+			r.With(advisors).Post("/estimates", estimatesController.CreateEstimate)
+			r.Get("/estimates/{id}", estimatesController.GetEstimate)
+			r.With(advisors).Post("/estimates/{id}/approval", estimatesController.ApproveEstimate)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("invoices"))
+			r.Get("/invoices", invoicesController.ListInvoices)
+			r.With(advisors).Post("/invoices", invoicesController.CreateInvoice)
+			r.Get("/invoices/{id}", invoicesController.GetInvoice)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("parts"))
+			r.Get("/parts", partsController.ListParts)
+			r.With(advisors).Post("/parts", partsController.CreatePart)
+			r.Get("/parts/{id}", partsController.GetPart)
+			r.With(advisors).Put("/parts/{id}", partsController.UpdatePart)
+			r.With(advisors).Patch("/parts/{id}", partsController.UpdatePart)
+			r.With(advisors).Delete("/parts/{id}", partsController.DeletePart)
+			r.With(advisors).Post("/parts/{id}/stock", partsController.AdjustStock)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("technicians"))
+			r.Get("/technicians", techniciansController.ListTechnicians)
+			r.With(advisors).Post("/technicians", techniciansController.CreateTechnician)
+			r.Get("/technicians/{id}", techniciansController.GetTechnician)
+			r.With(advisors).Put("/technicians/{id}", techniciansController.UpdateTechnician)
+			r.With(advisors).Patch("/technicians/{id}", techniciansController.UpdateTechnician)
+			r.With(advisors).Delete("/technicians/{id}", techniciansController.DeleteTechnician)
+			r.Get("/technicians/{id}/schedule", techniciansController.GetTechnicianSchedule)
+		})
+
+		// no API key scope covers users or API keys, so only signed in admins can manage them
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("users"))
+			r.With(admin).Get("/users", usersController.ListUsers)
+			r.With(admin).Post("/users", usersController.CreateUser)
+			r.With(admin).Get("/users/{id}", usersController.GetUser)
+			r.With(admin).Put("/users/{id}", usersController.UpdateUser)
+			r.With(admin).Patch("/users/{id}", usersController.UpdateUser)
+			r.With(admin).Delete("/users/{id}", usersController.DeleteUser)
+			r.Put("/users/{id}/password", usersController.SetPassword)
+
+			r.With(admin).Get("/apikeys", apiKeysController.ListAPIKeys)
+			r.With(admin).Post("/apikeys", apiKeysController.CreateAPIKey)
+			r.With(admin).Get("/apikeys/{id}", apiKeysController.GetAPIKey)
+			r.With(admin).Delete("/apikeys/{id}", apiKeysController.RevokeAPIKey)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("vehicles"))
+			r.Get("/vehicles", vehiclesController.ListVehicles)
+			r.With(advisors).Post("/vehicles", vehiclesController.CreateVehicle)
+			r.Get("/vehicles/{id}", vehiclesController.GetVehicle)
+			r.With(advisors).Put("/vehicles/{id}", vehiclesController.UpdateVehicle)
+			r.With(advisors).Patch("/vehicles/{id}", vehiclesController.UpdateVehicle)
+			r.With(advisors).Delete("/vehicles/{id}", vehiclesController.DeleteVehicle)
+			r.Get("/vehicles/{id}/history", vehiclesController.GetVehicleHistory)
+			r.Get("/vin/{vin}", vehiclesController.DecodeVIN)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Scope("workorders"))
+			r.Get("/workorders", workOrdersController.ListWorkOrders)
+			r.With(advisors).Post("/workorders", workOrdersController.CreateWorkOrder)
+			r.Get("/workorders/{id}", workOrdersController.GetWorkOrder)
+			r.With(staff).Post("/workorders/{id}/lines", workOrdersController.AddWorkOrderLine)
+			r.With(staff).Delete("/workorders/{id}/lines/{lineID}", workOrdersController.RemoveWorkOrderLine)
+			r.With(staff).Post("/workorders/{id}/time", workOrdersController.AddTimeEntry)
+			r.With(staff).Delete("/workorders/{id}/time/{entryID}", workOrdersController.RemoveTimeEntry)
+		})
+	})
 
 	return muxRouter
 }
@@ -0,0 +1,367 @@
+package router
+
+import (
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestServer - returns a server backed by a memory database, and the database
+func newTestServer(t *testing.T) (*httptest.Server, db.Database) {
+	cfg := config.Default()
+	cfg.Storage.Backend = config.BackendMemory
+	database, err := db.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokens(cfg.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(Initialize(cfg, database, nil, tokens))
+	t.Cleanup(server.Close)
+	return server, database
+}
+
+// signIn - creates a user with role, named after it, and returns the access token the user signs in with
+func signIn(t *testing.T, server *httptest.Server, database db.Database, role string) string {
+	hash, err := auth.HashPassword(role + "-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.CreateUser(context.Background(), models.User{Username: role, Name: role, Role: role, PasswordHash: hash, CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	resp := doRequest(t, "POST", server.URL+"/auth/login", "", map[string]string{"username": role, "password": role + "-password"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login returned %v", resp.StatusCode)
+	}
+	var session auth.TokenPair
+	json.NewDecoder(resp.Body).Decode(&session)
+	return session.AccessToken
+}
+
+// doRequest - sends body as JSON, signed in with the access token unless it is empty
+func doRequest(t *testing.T, method, url, token string, body interface{}) *http.Response {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return sendRequest(t, method, url, header, body)
+}
+
+// doAPIKeyRequest - sends body as JSON, authenticated with an API key
+func doAPIKeyRequest(t *testing.T, method, url, key string, body interface{}) *http.Response {
+	header := http.Header{}
+	header.Set(auth.APIKeyHeader, key)
+	return sendRequest(t, method, url, header, body)
+}
+
+func sendRequest(t *testing.T, method, url string, header http.Header, body interface{}) *http.Response {
+	var encoded []byte
+	if body != nil {
+		var err error
+		encoded, err = json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAppointmentLifecycle(t *testing.T) {
+	server, database := newTestServer(t)
+	token := signIn(t, server, database, models.RoleAdvisor)
+
+	resp := doRequest(t, "POST", server.URL+"/appointment/", token, map[string]string{
+		"name":        "Ultimate Car Appointment",
+		"description": "even newer engine appointment",
+		"date":        "2019-08-28T09:00:01+00:00",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create returned %v", resp.StatusCode)
+	}
+	var created models.Appointment
+	json.NewDecoder(resp.Body).Decode(&created)
+	id := created.ID.Hex()
+
+	resp = doRequest(t, "PATCH", server.URL+"/appointment/"+id, token, map[string]string{"status": "confirmed"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update status returned %v", resp.StatusCode)
+	}
+
+	resp = doRequest(t, "GET", server.URL+"/appointments/range/?start=2019-08-01T00:00:00Z&end=2019-08-31T00:00:00Z", token, nil)
+	var results []models.Appointment
+	json.NewDecoder(resp.Body).Decode(&results)
+	if len(results) != 1 || results[0].ID != created.ID || results[0].Status != "confirmed" {
+		t.Fatalf("unexpected range results %+v", results)
+	}
+
+	resp = doRequest(t, "DELETE", server.URL+"/appointment/"+id, token, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete returned %v", resp.StatusCode)
+	}
+
+	resp = doRequest(t, "GET", server.URL+"/appointment/"+id, token, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("get after delete returned %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestRoutesRequireSession(t *testing.T) {
+	server, database := newTestServer(t)
+	token := signIn(t, server, database, models.RoleAdvisor)
+
+	resp := doRequest(t, "GET", server.URL+"/appointments", "", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request without token returned %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if resp.Header.Get("WWW-Authenticate") == "" {
+		t.Error("401 response has no WWW-Authenticate header")
+	}
+
+	resp = doRequest(t, "GET", server.URL+"/appointments", token+"x", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request with tampered token returned %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp = doRequest(t, "GET", server.URL+"/appointments", token, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("request with token returned %v want %v", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	server, database := newTestServer(t)
+	advisor := signIn(t, server, database, models.RoleAdvisor)
+	technician := signIn(t, server, database, models.RoleTechnician)
+	readOnly := signIn(t, server, database, models.RoleReadOnly)
+
+	appointment, err := database.CreateAppointment(context.Background(), models.Appointment{Name: "Brakes", Description: "squeal",
+		Date: time.Date(2019, 8, 28, 9, 0, 0, 0, time.UTC), Status: models.StatusInProgress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := server.URL + "/appointment/" + appointment.ID.Hex()
+
+	// in order, since the status changes build on each other
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"read-only may read", "GET", url, readOnly, nil, http.StatusOK},
+		{"read-only may not create", "POST", server.URL + "/appointment/", readOnly,
+			map[string]string{"name": "Oil", "description": "change", "date": "2019-08-28T09:00:00Z"}, http.StatusForbidden},
+		{"read-only may not change status", "PATCH", url, readOnly, map[string]string{"status": models.StatusWaitingParts}, http.StatusForbidden},
+		{"technician may wait for parts", "PATCH", url, technician, map[string]string{"status": models.StatusWaitingParts}, http.StatusOK},
+		{"technician may not cancel", "PATCH", url, technician, map[string]string{"status": models.StatusCancelled}, http.StatusForbidden},
+		{"technician may not delete", "DELETE", url, technician, nil, http.StatusForbidden},
+		{"technician may not list users", "GET", server.URL + "/users", technician, nil, http.StatusForbidden},
+		{"advisor may not list users", "GET", server.URL + "/users", advisor, nil, http.StatusForbidden},
+		{"advisor may delete", "DELETE", url, advisor, nil, http.StatusOK},
+	}
+	for _, test := range tests {
+		resp := doRequest(t, test.method, test.url, test.token, test.body)
+		if resp.StatusCode != test.status {
+			t.Errorf("%v: got %v want %v", test.name, resp.StatusCode, test.status)
+		}
+	}
+
+	resp := doRequest(t, "DELETE", url, readOnly, nil)
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	if expected := `role "read_only" may not DELETE /appointment/` + appointment.ID.Hex(); body["error"] != expected {
+		t.Errorf("got %v want %v", body, expected)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	server, database := newTestServer(t)
+	admin := signIn(t, server, database, models.RoleAdmin)
+
+	resp := doRequest(t, "POST", server.URL+"/apikeys", admin, map[string]interface{}{"name": "kiosk", "scopes": []string{"appointments:write", "appointments:read"}})
+	var created struct {
+		models.APIKey
+		Key string `json:"key"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	if resp.StatusCode != http.StatusOK || created.Key == "" || created.Prefix == "" || created.CreatedBy != models.RoleAdmin {
+		t.Fatalf("create returned %v %+v", resp.StatusCode, created)
+	}
+	resp = doRequest(t, "POST", server.URL+"/apikeys", admin, map[string]interface{}{"name": "bad", "scopes": []string{"users:write"}})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("create with unknown scope returned %v want %v", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		body   interface{}
+		status int
+	}{
+		{"read in scope", "GET", "/appointments", created.Key, nil, http.StatusOK},
+		{"write in scope", "POST", "/appointment/", created.Key,
+			map[string]string{"name": "Oil", "description": "change", "date": "2019-08-28T09:00:00Z"}, http.StatusOK},
+		{"read out of scope", "GET", "/parts", created.Key, nil, http.StatusForbidden},
+		{"manage keys", "GET", "/apikeys", created.Key, nil, http.StatusForbidden},
+		{"unknown key", "GET", "/appointments", "csc_not-a-key", nil, http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		resp := doAPIKeyRequest(t, test.method, server.URL+test.path, test.key, test.body)
+		if resp.StatusCode != test.status {
+			t.Errorf("%v: got %v want %v", test.name, resp.StatusCode, test.status)
+		}
+	}
+
+	resp = doRequest(t, "GET", server.URL+"/apikeys/"+created.ID.Hex(), admin, nil)
+	var listed models.APIKey
+	json.NewDecoder(resp.Body).Decode(&listed)
+	if listed.LastUsedAt == nil {
+		t.Errorf("got %+v want the key's last use recorded", listed)
+	}
+
+	resp = doRequest(t, "DELETE", server.URL+"/apikeys/"+created.ID.Hex(), admin, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke returned %v", resp.StatusCode)
+	}
+	resp = doAPIKeyRequest(t, "GET", server.URL+"/appointments", created.Key, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request with revoked key returned %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestCustomerPortal(t *testing.T) {
+	server, database := newTestServer(t)
+	advisor := signIn(t, server, database, models.RoleAdvisor)
+	ctx := context.Background()
+	jane, _ := database.CreateCustomer(ctx, models.Customer{Name: "Jane Doe"})
+	john, _ := database.CreateCustomer(ctx, models.Customer{Name: "John Roe"})
+	johnsCar, err := database.CreateVehicle(ctx, models.Vehicle{CustomerID: john.ID, VIN: "1HGCM82633A004352", Make: "Honda"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	johns, _ := database.CreateAppointment(ctx, models.Appointment{Name: "Oil", Description: "change", Status: models.StatusOpen,
+		Date: time.Now().Add(72 * time.Hour), CustomerID: &john.ID})
+
+	resp := doRequest(t, "POST", server.URL+"/customers/"+jane.ID.Hex()+"/portal", advisor, nil)
+	var link struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&link)
+	if resp.StatusCode != http.StatusOK || link.Token == "" {
+		t.Fatalf("portal link returned %v %+v", resp.StatusCode, link)
+	}
+
+	soon := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	later := time.Now().Add(72 * time.Hour).UTC().Format(time.RFC3339)
+	resp = doRequest(t, "POST", server.URL+"/portal/appointments", link.Token, map[string]string{
+		"name": "Brakes", "description": "squeal", "date": later, "customer_id": john.ID.Hex(), "status": models.StatusCompleted})
+	var booked models.Appointment
+	json.NewDecoder(resp.Body).Decode(&booked)
+	if resp.StatusCode != http.StatusOK || booked.CustomerID == nil || *booked.CustomerID != jane.ID || booked.Status != models.StatusOpen {
+		t.Fatalf("booking returned %v %+v want an open appointment for the signed in customer", resp.StatusCode, booked)
+	}
+	resp = doRequest(t, "POST", server.URL+"/portal/appointments", link.Token, map[string]string{
+		"name": "Tires", "description": "rotate", "date": soon})
+	var soonBooked models.Appointment
+	json.NewDecoder(resp.Body).Decode(&soonBooked)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"staff routes refuse portal tokens", "GET", "/appointments", link.Token, nil, http.StatusUnauthorized},
+		{"portal refuses staff tokens", "GET", "/portal/appointments", advisor, nil, http.StatusUnauthorized},
+		{"another customer's vehicle", "POST", "/portal/appointments", link.Token,
+			map[string]string{"name": "Brakes", "description": "squeal", "date": later, "vehicle_id": johnsCar.ID.Hex()}, http.StatusBadRequest},
+		{"another customer's appointment", "GET", "/portal/appointments/" + johns.ID.Hex(), link.Token, nil, http.StatusNotFound},
+		{"cancel another customer's appointment", "POST", "/portal/appointments/" + johns.ID.Hex() + "/cancel", link.Token, nil, http.StatusNotFound},
+		{"cancel within the cutoff", "POST", "/portal/appointments/" + soonBooked.ID.Hex() + "/cancel", link.Token, nil, http.StatusConflict},
+		{"cancel", "POST", "/portal/appointments/" + booked.ID.Hex() + "/cancel", link.Token, nil, http.StatusOK},
+		{"cancel twice", "POST", "/portal/appointments/" + booked.ID.Hex() + "/cancel", link.Token, nil, http.StatusConflict},
+	}
+	for _, test := range tests {
+		resp := doRequest(t, test.method, server.URL+test.path, test.token, test.body)
+		if resp.StatusCode != test.status {
+			t.Errorf("%v: got %v want %v", test.name, resp.StatusCode, test.status)
+		}
+	}
+
+	resp = doRequest(t, "GET", server.URL+"/portal/appointments?sort=date", link.Token, nil)
+	var page models.AppointmentPage
+	json.NewDecoder(resp.Body).Decode(&page)
+	if len(page.Appointments) != 2 || page.Appointments[0].ID != soonBooked.ID || page.Appointments[1].Status != models.StatusCancelled {
+		t.Errorf("got %+v want only the signed in customer's two appointments", page.Appointments)
+	}
+}
+
+func TestAppointmentHistory(t *testing.T) {
+	server, database := newTestServer(t)
+	advisor := signIn(t, server, database, models.RoleAdvisor)
+
+	resp := doRequest(t, "POST", server.URL+"/appointment/", advisor, map[string]string{
+		"name": "Oil", "description": "change", "date": "2019-08-28T09:00:00Z"})
+	var created models.Appointment
+	json.NewDecoder(resp.Body).Decode(&created)
+	id := created.ID.Hex()
+	doRequest(t, "PATCH", server.URL+"/appointment/"+id, advisor, map[string]string{"status": models.StatusConfirmed})
+	doRequest(t, "PATCH", server.URL+"/appointment/"+id, advisor, map[string]string{"status": "bogus"})
+	resp = doRequest(t, "DELETE", server.URL+"/appointment/"+id, advisor, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete returned %v", resp.StatusCode)
+	}
+
+	resp = doRequest(t, "GET", server.URL+"/appointment/"+id+"/history", advisor, nil)
+	var history []models.AuditEntry
+	json.NewDecoder(resp.Body).Decode(&history)
+	if resp.StatusCode != http.StatusOK || len(history) != 3 {
+		t.Fatalf("history returned %v %+v want the create, status change and delete", resp.StatusCode, history)
+	}
+	for i, action := range []string{models.AuditCreated, models.AuditStatusChanged, models.AuditDeleted} {
+		entry := history[i]
+		if entry.Action != action || entry.Actor.Type != models.ActorUser || entry.Actor.Name != models.RoleAdvisor || entry.RequestID == "" {
+			t.Errorf("entry %v: got %+v want %v by the advisor with a request id", i, entry, action)
+		}
+	}
+	statusChange := models.FieldChange{Field: "status", Before: models.StatusOpen, After: models.StatusConfirmed}
+	if changes := history[1].Changes; len(changes) != 1 || changes[0] != statusChange {
+		t.Errorf("got changes %+v want %+v", changes, statusChange)
+	}
+	if history[2].Changes[0].After != "" {
+		t.Errorf("got %+v want only before values for a delete", history[2].Changes)
+	}
+
+	resp = doRequest(t, "GET", server.URL+"/appointment/000000000000000000000000/history", advisor, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("history of an unknown appointment returned %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+}
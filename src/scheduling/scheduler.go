@@ -0,0 +1,346 @@
+package scheduling
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by Scheduler.Check
+var (
+	// ErrOutsideBusinessHours - the appointment does not start and finish within a single business day
+	ErrOutsideBusinessHours = errors.New("appointment is outside business hours")
+	// ErrNoCapacity - every service bay is already booked for part of the appointment
+	ErrNoCapacity = errors.New("no service bay is available")
+	// ErrUnknownService - the appointment's service has no configured duration
+	ErrUnknownService = errors.New("unknown service")
+	// ErrTechnicianOffDuty - the appointment does not fall within the technician's working hours
+	ErrTechnicianOffDuty = errors.New("technician is not working then")
+	// ErrTechnicianBusy - the technician is assigned to another appointment at the same time
+	ErrTechnicianBusy = errors.New("technician is assigned to another appointment at that time")
+)
+
+// clock - a time of day as hours and minutes
+type clock struct {
+	hour, minute int
+}
+
+// businessDay - opening and closing time of one weekday
+type businessDay struct {
+	open, close clock
+}
+
+// Scheduler - decides whether appointments fit in the shop's service bays and business hours
+type Scheduler struct {
+	bays            int
+	location        *time.Location
+	hours           map[time.Weekday]businessDay
+	slot            time.Duration
+	defaultDuration time.Duration
+	services        map[string]time.Duration
+	suggestions     int
+	searchDays      int
+
+	// Now - current time, replaceable in tests; no slot before it is ever suggested
+	Now func() time.Time
+}
+
+// interval - the time a booked appointment occupies a bay
+type interval struct {
+	start, end time.Time
+}
+
+// New - builds a Scheduler from cfg, returning nil when scheduling is disabled because no bays are configured
+func New(cfg config.SchedulingConfig) (*Scheduler, error) {
+	if cfg.Bays == 0 {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	scheduler := &Scheduler{
+		bays:            cfg.Bays,
+		location:        location,
+		hours:           make(map[time.Weekday]businessDay),
+		slot:            time.Duration(cfg.SlotDuration),
+		defaultDuration: time.Duration(cfg.DefaultDuration),
+		services:        make(map[string]time.Duration),
+		suggestions:     cfg.Suggestions,
+		searchDays:      cfg.SearchDays,
+		Now:             time.Now,
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		hours, ok := cfg.BusinessHours[strings.ToLower(weekday.String())]
+		if !ok {
+			continue
+		}
+		open, err := parseClock(hours.Open)
+		if err != nil {
+			return nil, err
+		}
+		close, err := parseClock(hours.Close)
+		if err != nil {
+			return nil, err
+		}
+		scheduler.hours[weekday] = businessDay{open: open, close: close}
+	}
+	for service, duration := range cfg.ServiceDurations {
+		scheduler.services[service] = time.Duration(duration)
+	}
+	return scheduler, nil
+}
+
+func parseClock(value string) (clock, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return clock{}, fmt.Errorf("invalid time of day %q", value)
+	}
+	return clock{hour: parsed.Hour(), minute: parsed.Minute()}, nil
+}
+
+// Duration - how long an appointment for service occupies a bay; an empty service takes the default duration
+func (s *Scheduler) Duration(service string) (time.Duration, error) {
+	if service == "" {
+		return s.defaultDuration, nil
+	}
+	duration, ok := s.services[service]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownService, service)
+	}
+	return duration, nil
+}
+
+// AppointmentDuration - how long appointment occupies a bay: the labor time of its catalog items when it has any,
+// otherwise the duration of its service
+func (s *Scheduler) AppointmentDuration(appointment models.Appointment) (time.Duration, error) {
+	if appointment.DurationMinutes > 0 {
+		return time.Duration(appointment.DurationMinutes) * time.Minute, nil
+	}
+	return s.Duration(appointment.Service)
+}
+
+// maxDuration - the longest any appointment can occupy a bay; catalog items can add up to any length, but an appointment never outlasts a business day
+func (s *Scheduler) maxDuration() time.Duration {
+	longest := s.defaultDuration
+	for _, duration := range s.services {
+		if duration > longest {
+			longest = duration
+		}
+	}
+	for _, hours := range s.hours {
+		open := time.Duration(hours.open.hour)*time.Hour + time.Duration(hours.open.minute)*time.Minute
+		close := time.Duration(hours.close.hour)*time.Hour + time.Duration(hours.close.minute)*time.Minute
+		if close-open > longest {
+			longest = close - open
+		}
+	}
+	return longest
+}
+
+// Window - the range of appointment dates Check and Suggest need to see for an appointment starting at date
+func (s *Scheduler) Window(date time.Time) (time.Time, time.Time) {
+	return date.Add(-s.maxDuration()), date.AddDate(0, 0, s.searchDays+1)
+}
+
+// Check - returns ErrOutsideBusinessHours, ErrNoCapacity or ErrUnknownService if appointment can't be booked
+// alongside existing, which must include every appointment within Window(appointment.Date).
+// An existing appointment with the same ID as appointment is ignored so reschedules don't conflict with themselves.
+func (s *Scheduler) Check(appointment models.Appointment, existing []models.Appointment) error {
+	duration, err := s.AppointmentDuration(appointment)
+	if err != nil {
+		return err
+	}
+	return s.fits(appointment.Date, duration, s.booked(existing, appointment.ID))
+}
+
+// Suggest - returns up to the configured number of start times, after appointment.Date and within the
+// configured search days, at which appointment could be booked instead
+func (s *Scheduler) Suggest(appointment models.Appointment, existing []models.Appointment) []time.Time {
+	duration, err := s.AppointmentDuration(appointment)
+	if err != nil {
+		return nil
+	}
+	booked := s.booked(existing, appointment.ID)
+	suggestions := []time.Time{}
+	notBefore := appointment.Date
+	if now := s.Now(); now.After(notBefore) {
+		notBefore = now
+	}
+
+	local := appointment.Date.In(s.location)
+	for day := 0; day <= s.searchDays && len(suggestions) < s.suggestions; day++ {
+		for _, start := range s.slots(local.AddDate(0, 0, day), duration) {
+			if len(suggestions) == s.suggestions {
+				break
+			}
+			if start.After(notBefore) && s.fits(start, duration, booked) == nil {
+				suggestions = append(suggestions, start)
+			}
+		}
+	}
+	return suggestions
+}
+
+// slots - every slot-aligned start time on day at which an appointment lasting duration ends before closing
+func (s *Scheduler) slots(day time.Time, duration time.Duration) []time.Time {
+	open, close, ok := s.businessHours(day)
+	if !ok {
+		return nil
+	}
+	var starts []time.Time
+	for start := open; !start.Add(duration).After(close); start = start.Add(s.slot) {
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// businessHours - opening and closing time on the local day containing date
+func (s *Scheduler) businessHours(date time.Time) (time.Time, time.Time, bool) {
+	local := date.In(s.location)
+	hours, ok := s.hours[local.Weekday()]
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return s.at(local, hours.open), s.at(local, hours.close), true
+}
+
+// booked - the intervals occupied by existing appointments that still need a bay, leaving out exclude
+func (s *Scheduler) booked(existing []models.Appointment, exclude primitive.ObjectID) []interval {
+	intervals := make([]interval, 0, len(existing))
+	for _, appointment := range existing {
+		if (!exclude.IsZero() && appointment.ID == exclude) ||
+			appointment.Status == models.StatusCancelled || appointment.Status == models.StatusNoShow {
+			continue
+		}
+		duration, err := s.AppointmentDuration(appointment)
+		if err != nil {
+			// services removed from the configuration keep occupying a bay for the default duration
+			duration = s.defaultDuration
+		}
+		intervals = append(intervals, interval{start: appointment.Date, end: appointment.Date.Add(duration)})
+	}
+	return intervals
+}
+
+// fits - checks that [start, start+duration) is within business hours and that fewer than all bays are busy throughout it
+func (s *Scheduler) fits(start time.Time, duration time.Duration, booked []interval) error {
+	end := start.Add(duration)
+	open, close, ok := s.businessHours(start)
+	if !ok || start.Before(open) || end.After(close) {
+		return ErrOutsideBusinessHours
+	}
+	if busiest(interval{start: start, end: end}, booked) >= s.bays {
+		return ErrNoCapacity
+	}
+	return nil
+}
+
+// busiest - the largest number of booked intervals in progress at any one moment of window
+func busiest(window interval, booked []interval) int {
+	// concurrency only increases when an interval starts, so it's enough to count at the window start and at every start inside it
+	points := []time.Time{window.start}
+	for _, b := range booked {
+		if b.start.After(window.start) && b.start.Before(window.end) {
+			points = append(points, b.start)
+		}
+	}
+	most := 0
+	for _, point := range points {
+		count := 0
+		for _, b := range booked {
+			if !b.start.After(point) && b.end.After(point) {
+				count++
+			}
+		}
+		if count > most {
+			most = count
+		}
+	}
+	return most
+}
+
+// CheckTechnician - returns ErrTechnicianOffDuty or ErrTechnicianBusy if technician can't work on appointment, given the
+// other appointments in existing, which must include every appointment within Window(appointment.Date).
+// A technician can only work on one appointment at a time; cancelled and no-show appointments don't count.
+func (s *Scheduler) CheckTechnician(appointment models.Appointment, technician models.Technician, existing []models.Appointment) error {
+	duration, err := s.AppointmentDuration(appointment)
+	if err != nil {
+		return err
+	}
+	start, end := appointment.Date, appointment.Date.Add(duration)
+	if len(technician.WorkingHours) != 0 {
+		local := start.In(s.location)
+		shift, ok := technician.WorkingHours[strings.ToLower(local.Weekday().String())]
+		if !ok {
+			return ErrTechnicianOffDuty
+		}
+		from, fromErr := parseClock(shift.Start)
+		until, untilErr := parseClock(shift.End)
+		if fromErr != nil || untilErr != nil || start.Before(s.at(local, from)) || end.After(s.at(local, until)) {
+			return ErrTechnicianOffDuty
+		}
+	}
+
+	assigned := []models.Appointment{}
+	for _, other := range existing {
+		for _, id := range other.TechnicianIDs {
+			if id == technician.ID {
+				assigned = append(assigned, other)
+				break
+			}
+		}
+	}
+	if busiest(interval{start: start, end: end}, s.booked(assigned, appointment.ID)) > 0 {
+		return ErrTechnicianBusy
+	}
+	return nil
+}
+
+// at - the time of day c on the local day containing date
+func (s *Scheduler) at(date time.Time, c clock) time.Time {
+	year, month, day := date.In(s.location).Date()
+	return time.Date(year, month, day, c.hour, c.minute, 0, 0, s.location)
+}
+
+// Slot - a bookable start time and the number of bays free for the whole appointment starting then
+type Slot struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	AvailableBays int       `json:"available_bays"`
+}
+
+// ParseDay - parses a "2006-01-02" date as the start of that day in the shop's timezone
+func (s *Scheduler) ParseDay(value string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, s.location)
+}
+
+// DayWindow - the range of appointment dates Availability needs to see for day
+func (s *Scheduler) DayWindow(day time.Time) (time.Time, time.Time) {
+	return day.Add(-s.maxDuration()), day.AddDate(0, 0, 1)
+}
+
+// Availability - every slot on day at which an appointment lasting duration could still be booked alongside existing,
+// which must include every appointment within DayWindow(day). Slots that have already started are left out.
+func (s *Scheduler) Availability(day time.Time, duration time.Duration, existing []models.Appointment) []Slot {
+	booked := s.booked(existing, primitive.NilObjectID)
+	now := s.Now()
+
+	slots := []Slot{}
+	for _, start := range s.slots(day, duration) {
+		if start.Before(now) {
+			continue
+		}
+		end := start.Add(duration)
+		free := s.bays - busiest(interval{start: start, end: end}, booked)
+		if free > 0 {
+			slots = append(slots, Slot{Start: start, End: end, AvailableBays: free})
+		}
+	}
+	return slots
+}
@@ -0,0 +1,208 @@
+package scheduling
+
+import (
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/models"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestScheduler(t *testing.T) *Scheduler {
+	cfg := config.Default().Scheduling
+	cfg.Bays = 2
+	cfg.ServiceDurations = map[string]config.Duration{
+		"oil_change": config.Duration(30 * time.Minute),
+		"brake_job":  config.Duration(2 * time.Hour),
+	}
+	cfg.Suggestions = 3
+	scheduler, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler.Now = func() time.Time { return mustParseTime(t, "2019-08-01T00:00:00Z") }
+	return scheduler
+}
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func booking(t *testing.T, date, service string) models.Appointment {
+	return models.Appointment{ID: primitive.NewObjectID(), Date: mustParseTime(t, date), Service: service, Status: models.StatusOpen}
+}
+
+func TestNewDisabledWithoutBays(t *testing.T) {
+	scheduler, err := New(config.Default().Scheduling)
+	if err != nil || scheduler != nil {
+		t.Errorf("got %v, %v want a nil scheduler", scheduler, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	scheduler := newTestScheduler(t)
+	// 2019-08-28 is a Wednesday, 2019-08-31 a Saturday and 2019-09-01 a Sunday
+	existing := []models.Appointment{
+		booking(t, "2019-08-28T09:00:00Z", "brake_job"),
+		booking(t, "2019-08-28T10:00:00Z", "oil_change"),
+	}
+	cancelled := booking(t, "2019-08-28T13:00:00Z", "brake_job")
+	cancelled.Status = models.StatusCancelled
+	existing = append(existing, cancelled, booking(t, "2019-08-28T13:00:00Z", "brake_job"))
+
+	tests := map[string]struct {
+		appointment models.Appointment
+		expected    error
+	}{
+		"free bay":              {booking(t, "2019-08-28T09:00:00Z", "oil_change"), nil},
+		"both bays busy":        {booking(t, "2019-08-28T10:00:00Z", "oil_change"), ErrNoCapacity},
+		"overlaps busy period":  {booking(t, "2019-08-28T08:30:00Z", "brake_job"), ErrNoCapacity},
+		"ends as another ends":  {booking(t, "2019-08-28T10:30:00Z", "oil_change"), nil},
+		"cancelled frees a bay": {booking(t, "2019-08-28T13:00:00Z", ""), nil},
+		"before opening":        {booking(t, "2019-08-28T07:30:00Z", "oil_change"), ErrOutsideBusinessHours},
+		"runs past closing":     {booking(t, "2019-08-28T17:00:00Z", "brake_job"), ErrOutsideBusinessHours},
+		"closed on sunday":      {booking(t, "2019-09-01T10:00:00Z", "oil_change"), ErrOutsideBusinessHours},
+		"short saturday":        {booking(t, "2019-08-31T12:00:00Z", "brake_job"), ErrOutsideBusinessHours},
+		"unknown service":       {booking(t, "2019-08-28T14:00:00Z", "paint"), ErrUnknownService},
+	}
+	for name, test := range tests {
+		if err := scheduler.Check(test.appointment, existing); !errors.Is(err, test.expected) {
+			t.Errorf("%v: got %v want %v", name, err, test.expected)
+		}
+	}
+
+	rescheduled := existing[1]
+	if err := scheduler.Check(rescheduled, existing); err != nil {
+		t.Errorf("an appointment should not conflict with itself: %v", err)
+	}
+}
+
+func TestCheckCatalogDuration(t *testing.T) {
+	scheduler := newTestScheduler(t)
+	long := booking(t, "2019-08-28T09:00:00Z", "oil_change")
+	long.DurationMinutes = 240
+	existing := []models.Appointment{long, booking(t, "2019-08-28T12:00:00Z", "oil_change")}
+
+	tests := map[string]struct {
+		date     string
+		minutes  int
+		expected error
+	}{
+		"inside the long booking": {"2019-08-28T12:00:00Z", 0, ErrNoCapacity},
+		"after the short booking": {"2019-08-28T12:30:00Z", 0, nil},
+		"catalog labor time":      {"2019-08-28T11:00:00Z", 90, ErrNoCapacity},
+		"runs past closing":       {"2019-08-28T16:00:00Z", 180, ErrOutsideBusinessHours},
+	}
+	for name, test := range tests {
+		appointment := booking(t, test.date, "oil_change")
+		appointment.DurationMinutes = test.minutes
+		if err := scheduler.Check(appointment, existing); !errors.Is(err, test.expected) {
+			t.Errorf("%v: got %v want %v", name, err, test.expected)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	scheduler := newTestScheduler(t)
+	existing := []models.Appointment{
+		booking(t, "2019-08-30T16:00:00Z", "brake_job"),
+		booking(t, "2019-08-30T16:00:00Z", "brake_job"),
+	}
+
+	suggestions := scheduler.Suggest(booking(t, "2019-08-30T16:00:00Z", "oil_change"), existing)
+	expected := []string{"2019-08-31T09:00:00Z", "2019-08-31T09:30:00Z", "2019-08-31T10:00:00Z"}
+	if len(suggestions) != len(expected) {
+		t.Fatalf("got %v want %v", suggestions, expected)
+	}
+	for i := range expected {
+		if !suggestions[i].Equal(mustParseTime(t, expected[i])) {
+			t.Errorf("got %v want %v", suggestions, expected)
+		}
+	}
+}
+
+func TestAvailability(t *testing.T) {
+	scheduler := newTestScheduler(t)
+	day, err := scheduler.ParseDay("2019-08-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := []models.Appointment{
+		booking(t, "2019-08-31T09:00:00Z", "brake_job"),
+		booking(t, "2019-08-31T10:00:00Z", "oil_change"),
+	}
+
+	expected := map[string]int{
+		"2019-08-31T09:00:00Z": 1,
+		"2019-08-31T09:30:00Z": 1,
+		"2019-08-31T10:30:00Z": 1,
+		"2019-08-31T11:00:00Z": 2,
+		"2019-08-31T11:30:00Z": 2,
+		"2019-08-31T12:00:00Z": 2,
+		"2019-08-31T12:30:00Z": 2,
+	}
+	slots := scheduler.Availability(day, 30*time.Minute, existing)
+	if len(slots) != len(expected) {
+		t.Fatalf("got %v want %v", slots, expected)
+	}
+	for _, slot := range slots {
+		if bays, ok := expected[slot.Start.Format(time.RFC3339)]; !ok || bays != slot.AvailableBays {
+			t.Errorf("got slot %v with %v bays want %v", slot.Start, slot.AvailableBays, bays)
+		}
+		if slot.End.Sub(slot.Start) != 30*time.Minute {
+			t.Errorf("slot %v should last 30m, got %v", slot.Start, slot.End.Sub(slot.Start))
+		}
+	}
+
+	scheduler.Now = func() time.Time { return mustParseTime(t, "2019-08-31T11:15:00Z") }
+	slots = scheduler.Availability(day, 30*time.Minute, existing)
+	if len(slots) != 3 {
+		t.Errorf("slots that already started should be left out, got %v", slots)
+	}
+}
+
+func TestCheckTechnician(t *testing.T) {
+	scheduler := newTestScheduler(t)
+	technician := models.Technician{
+		ID:           primitive.NewObjectID(),
+		WorkingHours: map[string]models.Shift{"wednesday": {Start: "08:00", End: "12:00"}},
+	}
+	assigned := booking(t, "2019-08-28T09:00:00Z", "brake_job")
+	assigned.TechnicianIDs = []primitive.ObjectID{technician.ID}
+	cancelled := booking(t, "2019-08-28T08:00:00Z", "oil_change")
+	cancelled.TechnicianIDs = []primitive.ObjectID{technician.ID}
+	cancelled.Status = models.StatusCancelled
+	// booked for another technician, so it doesn't keep this one busy
+	other := booking(t, "2019-08-28T11:00:00Z", "oil_change")
+	other.TechnicianIDs = []primitive.ObjectID{primitive.NewObjectID()}
+	existing := []models.Appointment{assigned, cancelled, other}
+
+	tests := map[string]struct {
+		appointment models.Appointment
+		expected    error
+	}{
+		"free":                {booking(t, "2019-08-28T08:00:00Z", "oil_change"), nil},
+		"overlaps assignment": {booking(t, "2019-08-28T10:30:00Z", "oil_change"), ErrTechnicianBusy},
+		"after assignment":    {booking(t, "2019-08-28T11:00:00Z", "oil_change"), nil},
+		"runs past shift":     {booking(t, "2019-08-28T11:30:00Z", "brake_job"), ErrTechnicianOffDuty},
+		"day off":             {booking(t, "2019-08-29T09:00:00Z", "oil_change"), ErrTechnicianOffDuty},
+	}
+	for name, test := range tests {
+		if err := scheduler.CheckTechnician(test.appointment, technician, existing); !errors.Is(err, test.expected) {
+			t.Errorf("%v: got %v want %v", name, err, test.expected)
+		}
+	}
+	if err := scheduler.CheckTechnician(assigned, technician, existing); err != nil {
+		t.Errorf("an assignment should not conflict with itself: %v", err)
+	}
+	technician.WorkingHours = nil
+	if err := scheduler.CheckTechnician(booking(t, "2019-08-29T09:00:00Z", "oil_change"), technician, existing); err != nil {
+		t.Errorf("technicians without working hours should work whenever the shop is open: %v", err)
+	}
+}
@@ -1,24 +1,124 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"CarServiceCenter/src/auth"
+	"CarServiceCenter/src/config"
+	"CarServiceCenter/src/db"
+	"CarServiceCenter/src/models"
 	"CarServiceCenter/src/router"
+	"CarServiceCenter/src/scheduling"
 )
 
-// Start the http server
+// Start the http server using the config file named by CONFIG_FILE, if any, and the environment
 func Start() {
-	r := router.Initialize()
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	database, err := db.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	scheduler, err := scheduling.New(cfg.Scheduling)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if scheduler != nil && cfg.Storage.Backend == config.BackendMongo {
+		log.Println("scheduling only prevents double-booked bays within this instance, run no other instance against the same database")
+	}
+
+	tokens, err := auth.NewTokens(cfg.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Auth.Secret == "" {
+		log.Println("AUTH_SECRET is not set, sessions will end when the server restarts")
+	}
+
+	if err := bootstrapUser(database, cfg.Auth); err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: router.Initialize(cfg, database, scheduler, tokens),
+	}
 
-	var port string
-	port = os.Getenv("PORT")
-	if port == "" {
-		log.Println("No port set. Setting port to 8080.")
-		port = "8080"
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+
+		log.Println("Shutting down server...")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Println("error shutting down server:", err)
+		}
+	}()
+
+	log.Printf("Starting server on port %s\n", cfg.Server.Port)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
 	}
+	<-done
 
-	log.Printf("Starting server on port %s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	if err := database.Close(); err != nil {
+		log.Println("error closing database:", err)
+	}
+}
+
+// bootstrapUser - makes sure an admin can sign in: when no user is an admin, the configured bootstrap account is
+// created as one, or promoted if it already exists, so that admin can give everyone else their roles
+func bootstrapUser(users db.UserStore, cfg config.AuthConfig) error {
+	if cfg.BootstrapUsername == "" {
+		return nil
+	}
+	ctx := context.Background()
+	existing, err := users.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, user := range *existing {
+		if user.Role == models.RoleAdmin {
+			return nil
+		}
+	}
+
+	username := strings.ToLower(strings.TrimSpace(cfg.BootstrapUsername))
+	if user, err := users.GetUserByUsername(ctx, username); err == nil {
+		user.Role = models.RoleAdmin
+		if _, err := users.UpdateUser(ctx, *user); err != nil {
+			return fmt.Errorf("bootstrap user: %w", err)
+		}
+		log.Printf("Made bootstrap user %s an admin\n", username)
+		return nil
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("bootstrap user: %w", err)
+	}
+
+	hash, err := auth.HashPassword(cfg.BootstrapPassword)
+	if err != nil {
+		return fmt.Errorf("bootstrap user: %w", err)
+	}
+	if _, err := users.CreateUser(ctx, models.User{Username: username, Name: username, PasswordHash: hash, Role: models.RoleAdmin, CreatedAt: time.Now().UTC()}); err != nil {
+		return fmt.Errorf("bootstrap user: %w", err)
+	}
+	log.Printf("Created bootstrap user %s\n", username)
+	return nil
 }
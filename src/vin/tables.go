@@ -0,0 +1,73 @@
+package vin
+
+// Tables used by Decode. They cover the manufacturers most often seen in the shop and are not exhaustive.
+
+// yearCodes - model year codes in order, starting from 1980 and again from 2010
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// manufacturers - make of the vehicles built under each world manufacturer identifier
+var manufacturers = map[string]string{
+	"19U": "Acura", "19X": "Honda",
+	"1C3": "Chrysler", "1C4": "Chrysler", "1C6": "Ram", "1D7": "Dodge",
+	"1FA": "Ford", "1FD": "Ford", "1FM": "Ford", "1FT": "Ford",
+	"1G1": "Chevrolet", "1GC": "Chevrolet", "1GN": "Chevrolet", "1G4": "Buick",
+	"1G6": "Cadillac", "1GY": "Cadillac", "1GT": "GMC",
+	"1HG": "Honda", "1J4": "Jeep", "1LN": "Lincoln", "1ME": "Mercury",
+	"1N4": "Nissan", "1N6": "Nissan", "1VW": "Volkswagen", "1YV": "Mazda",
+	"2FA": "Ford", "2G1": "Chevrolet", "2HG": "Honda", "2HK": "Honda", "2T1": "Toyota", "2T3": "Toyota",
+	"3FA": "Ford", "3G1": "Chevrolet", "3N1": "Nissan", "3VW": "Volkswagen",
+	"4JG": "Mercedes-Benz", "4S3": "Subaru", "4S4": "Subaru", "4T1": "Toyota", "4T3": "Toyota",
+	"5FN": "Honda", "5J6": "Honda", "5N1": "Nissan", "5NP": "Hyundai", "5TD": "Toyota",
+	"5UX": "BMW", "5XY": "Kia", "5YJ": "Tesla", "7SA": "Tesla",
+	"JA3": "Mitsubishi", "JA4": "Mitsubishi", "JF1": "Subaru", "JF2": "Subaru",
+	"JH4": "Acura", "JHM": "Honda", "JM1": "Mazda", "JN1": "Nissan", "JN8": "Nissan", "JS2": "Suzuki",
+	"JT2": "Toyota", "JTD": "Toyota", "JTE": "Toyota", "JTH": "Lexus", "JTJ": "Lexus",
+	"KMH": "Hyundai", "KNA": "Kia", "KND": "Kia", "LRW": "Tesla",
+	"SAJ": "Jaguar", "SAL": "Land Rover", "SCC": "Lotus", "SCF": "Aston Martin",
+	"TMB": "Skoda", "TRU": "Audi",
+	"VF1": "Renault", "VF3": "Peugeot", "VF7": "Citroen", "VSS": "SEAT",
+	"W0L": "Opel", "WAU": "Audi", "WBA": "BMW", "WBS": "BMW", "WDB": "Mercedes-Benz", "WDD": "Mercedes-Benz",
+	"WF0": "Ford", "WP0": "Porsche", "WP1": "Porsche", "WV2": "Volkswagen", "WVW": "Volkswagen",
+	"YS3": "Saab", "YV1": "Volvo",
+	"ZAR": "Alfa Romeo", "ZFA": "Fiat", "ZFF": "Ferrari", "ZHW": "Lamborghini",
+}
+
+// countries - country of manufacture by the first character of the VIN
+var countries = map[byte]string{
+	'1': "United States", '4': "United States", '5': "United States", '7': "United States",
+	'2': "Canada", '3': "Mexico", '6': "Australia", '9': "Brazil",
+	'J': "Japan", 'K': "South Korea", 'L': "China", 'M': "India",
+	'S': "United Kingdom", 'W': "Germany", 'X': "Russia", 'Z': "Italy",
+}
+
+// countriesByPrefix - countries that share a first character with others, by the first two characters
+var countriesByPrefix = map[string]string{
+	"TM": "Czech Republic", "TR": "Hungary", "TS": "Hungary",
+	"VA": "Austria", "VF": "France", "VR": "France", "VS": "Spain", "VW": "Spain",
+	"YS": "Sweden", "YV": "Sweden", "YX": "Finland",
+}
+
+// plants - assembly plant by make and the plant code at position 11
+var plants = map[string]map[string]string{
+	"Ford": {
+		"E": "Louisville, Kentucky",
+		"F": "Dearborn, Michigan",
+		"G": "Chicago, Illinois",
+		"K": "Kansas City, Missouri",
+		"R": "Flat Rock, Michigan",
+	},
+	"Honda": {
+		"A": "Marysville, Ohio",
+		"H": "Alliston, Ontario",
+		"L": "East Liberty, Ohio",
+		"S": "Suzuka, Japan",
+	},
+	"Tesla": {
+		"A": "Austin, Texas",
+		"F": "Fremont, California",
+	},
+	"Toyota": {
+		"U": "Georgetown, Kentucky",
+		"Z": "Fremont, California",
+	},
+}
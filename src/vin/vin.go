@@ -0,0 +1,122 @@
+package vin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Length - number of characters in every VIN since 1981
+const Length = 17
+
+// ErrInvalidVIN - the VIN is malformed or its check digit doesn't match; returned wrapped with the reason
+var ErrInvalidVIN = errors.New("invalid VIN")
+
+// Info - what can be read from a VIN without contacting anyone
+type Info struct {
+	VIN string `json:"vin"`
+	// WMI - world manufacturer identifier, the first three characters
+	WMI          string `json:"wmi"`
+	Make         string `json:"make,omitempty"`
+	Country      string `json:"country,omitempty"`
+	ModelYear    int    `json:"model_year,omitempty"`
+	PlantCode    string `json:"plant_code"`
+	Plant        string `json:"plant,omitempty"`
+	SerialNumber string `json:"serial_number"`
+}
+
+// checkDigitPosition - index of the check digit within a VIN
+const checkDigitPosition = 8
+
+// weights - ISO 3779 weight of each position when computing the check digit
+var weights = [Length]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// value - the number a VIN character stands for in the check digit sum; I, O and Q are never used
+func value(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'H':
+		return int(c-'A') + 1, true
+	case c >= 'J' && c <= 'N':
+		return int(c-'J') + 1, true
+	case c == 'P':
+		return 7, true
+	case c == 'R':
+		return 9, true
+	case c >= 'S' && c <= 'Z':
+		return int(c-'S') + 2, true
+	}
+	return 0, false
+}
+
+// CheckDigit - the check digit ISO 3779 expects at position 9 of vin, which must already be 17 valid characters
+func CheckDigit(vin string) byte {
+	sum := 0
+	for i := 0; i < Length; i++ {
+		v, _ := value(vin[i])
+		sum += v * weights[i]
+	}
+	if sum%11 == 10 {
+		return 'X'
+	}
+	return byte('0' + sum%11)
+}
+
+// Validate - returns an error wrapping ErrInvalidVIN unless vin is 17 valid characters with a matching check digit.
+// vin must already be upper-case.
+func Validate(vin string) error {
+	if len(vin) != Length {
+		return fmt.Errorf("%w %q: must be %d characters long", ErrInvalidVIN, vin, Length)
+	}
+	for i := 0; i < Length; i++ {
+		if _, ok := value(vin[i]); !ok {
+			return fmt.Errorf("%w %q: %q is not allowed", ErrInvalidVIN, vin, vin[i])
+		}
+	}
+	if expected := CheckDigit(vin); vin[checkDigitPosition] != expected {
+		return fmt.Errorf("%w %q: check digit is %c but should be %c", ErrInvalidVIN, vin, vin[checkDigitPosition], expected)
+	}
+	return nil
+}
+
+// Decode - validates vin and reads its manufacturer, country, model year and plant from the bundled tables.
+// Fields the tables don't cover are left empty.
+func Decode(vin string) (*Info, error) {
+	vin = strings.ToUpper(strings.TrimSpace(vin))
+	if err := Validate(vin); err != nil {
+		return nil, err
+	}
+	info := &Info{
+		VIN:          vin,
+		WMI:          vin[:3],
+		Make:         manufacturers[vin[:3]],
+		Country:      country(vin),
+		ModelYear:    modelYear(vin),
+		PlantCode:    vin[10:11],
+		SerialNumber: vin[11:],
+	}
+	info.Plant = plants[info.Make][info.PlantCode]
+	return info, nil
+}
+
+// country - where the vehicle was built, from the first one or two characters
+func country(vin string) string {
+	if name, ok := countriesByPrefix[vin[:2]]; ok {
+		return name
+	}
+	return countries[vin[0]]
+}
+
+// modelYear - decodes position 10. The codes repeat every 30 years, so as in North America a letter at
+// position 7 selects the cycle starting in 2010 and a digit the one starting in 1980.
+func modelYear(vin string) int {
+	offset := strings.IndexByte(yearCodes, vin[9])
+	if offset < 0 {
+		return 0
+	}
+	if vin[6] >= 'A' && vin[6] <= 'Z' {
+		return 2010 + offset
+	}
+	return 1980 + offset
+}
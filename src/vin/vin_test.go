@@ -0,0 +1,57 @@
+package vin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := map[string]bool{
+		"1HGCM82633A004352": true,
+		"JH4KA7561PC008269": true,
+		"5YJSA1E18HF000337": true,
+		"1M8GDM9AXKP042788": true,
+		"1HGCM82633A004353": false,
+		"1HGCM82633A00435":  false,
+		"1HGCM8263OA004352": false,
+		"1hgcm82633a004352": false,
+	}
+	for vin, valid := range tests {
+		err := Validate(vin)
+		if valid && err != nil || !valid && !errors.Is(err, ErrInvalidVIN) {
+			t.Errorf("%v: got %v want valid %v", vin, err, valid)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	info, err := Decode(" 1hgcm82633a004352")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Info{
+		VIN:          "1HGCM82633A004352",
+		WMI:          "1HG",
+		Make:         "Honda",
+		Country:      "United States",
+		ModelYear:    2003,
+		PlantCode:    "A",
+		Plant:        "Marysville, Ohio",
+		SerialNumber: "004352",
+	}
+	if *info != expected {
+		t.Errorf("got %+v want %+v", *info, expected)
+	}
+
+	info, err = Decode("5YJSA1E18HF000337")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Make != "Tesla" || info.ModelYear != 2017 || info.Plant != "Fremont, California" {
+		t.Errorf("got %+v", *info)
+	}
+
+	if _, err := Decode("1HGCM82633A004353"); !errors.Is(err, ErrInvalidVIN) {
+		t.Errorf("got %v want %v", err, ErrInvalidVIN)
+	}
+}